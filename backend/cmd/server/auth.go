@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+)
+
+// newAuthenticator builds the API authenticator from the OIDC and API key
+// settings. The JWKS comes from OIDC_JWKS_FILE, OIDC_JWKS_URL, or the
+// issuer's discovery document, in that order. API keys may only be bound to
+// tenants in the registry.
+func newAuthenticator(ctx context.Context, cfg *config.Config, tenants *tenant.Registry) (*auth.Authenticator, error) {
+	keys, err := auth.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if _, ok := tenants.Get(k.Tenant); k.Tenant != "" && !ok {
+			return nil, fmt.Errorf("API key %q is bound to unknown tenant %q", k.Name, k.Tenant)
+		}
+	}
+	var verifier *auth.JWTVerifier
+	if cfg.OIDCIssuer != "" {
+		var keySet auth.KeySet
+		switch {
+		case cfg.OIDCJWKSFile != "":
+			if keySet, err = auth.LoadJWKSFile(cfg.OIDCJWKSFile); err != nil {
+				return nil, err
+			}
+		case cfg.OIDCJWKSURL != "":
+			keySet = auth.NewRemoteKeySet(cfg.OIDCJWKSURL)
+		default:
+			jwksURL, err := auth.DiscoverJWKSURL(ctx, &http.Client{Timeout: 10 * time.Second}, cfg.OIDCIssuer)
+			if err != nil {
+				return nil, err
+			}
+			keySet = auth.NewRemoteKeySet(jwksURL)
+		}
+		verifier = auth.NewJWTVerifier(keySet, cfg.OIDCIssuer, cfg.OIDCAudience, cfg.OIDCTenantClaim)
+	}
+	return auth.NewAuthenticator(verifier, keys), nil
+}
+
+// loadPolicy reads POLICY_FILE. Without one, permissions are not enforced and
+// every authenticated caller has full access, as before roles existed.
+func loadPolicy(cfg *config.Config) (*authz.Policy, error) {
+	if cfg.PolicyFile == "" {
+		slog.Warn("No POLICY_FILE: every authenticated caller has full access")
+		return nil, nil
+	}
+	p, err := authz.LoadPolicy(cfg.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	p.AllowAnonymous = !cfg.AuthRequired
+	slog.Info("Permission policy loaded", "path", cfg.PolicyFile, "roles", len(p.Roles), "subjects", len(p.Subjects))
+	return p, nil
+}
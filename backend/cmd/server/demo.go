@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// seedDemo fills the demo store with a customer and a few loads so the UI
+// has something to show.
+func seedDemo(store *memstore.Store, mapper *turvo.Mapper) {
+	store.AddCustomer(turvo.MinimalCustomer{ID: 1, Name: "Demo Foods Inc."})
+	store.AddCustomer(turvo.MinimalCustomer{ID: 2, Name: "Sample Retail Co."})
+	loads := []domain.Load{
+		{
+			ExternalTMSLoadID: "DEMO-1001",
+			Status:            "Tendered",
+			Customer:          domain.Party{TurvoID: 1, Name: "Demo Foods Inc."},
+			Pickup:            domain.Stop{Name: "Demo Foods DC", City: "Chicago", State: "IL", Zipcode: "60601"},
+			Consignee:         domain.Stop{Name: "Sample Retail #12", City: "Dallas", State: "TX", Zipcode: "75201"},
+		},
+		{
+			ExternalTMSLoadID: "DEMO-1002",
+			Status:            "Covered",
+			Customer:          domain.Party{TurvoID: 2, Name: "Sample Retail Co."},
+			Pickup:            domain.Stop{Name: "Port Warehouse", City: "Long Beach", State: "CA", Zipcode: "90802"},
+			Consignee:         domain.Stop{Name: "Sample Retail #40", City: "Phoenix", State: "AZ", Zipcode: "85004"},
+		},
+	}
+	for i := range loads {
+		shipment, err := mapper.ToTurvoShipment(&loads[i])
+		if err != nil {
+			slog.Warn("Demo seed skipped", "external_id", loads[i].ExternalTMSLoadID, "error", err)
+			continue
+		}
+		store.CreateShipment(context.Background(), shipment)
+	}
+}
@@ -4,14 +4,24 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"slices"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	chcors "github.com/go-chi/cors"
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
 	"github.com/maceo-kwik/drumkit/backend/internal/config"
 	"github.com/maceo-kwik/drumkit/backend/internal/http/handlers"
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/jobs"
+	"github.com/maceo-kwik/drumkit/backend/internal/logging"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
 	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
 )
 
@@ -22,27 +32,65 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
 	}
+	// JSON logs when deployed; readable text locally
+	logger := logging.New(os.Stderr, cfg.LogLevel, cfg.AppEnv != "local")
+	slog.SetDefault(logger)
+	slog.Info("Configuration loaded", "config", cfg)
 
-	// Create a new Turvo client
-	turvoClient, err := turvo.NewClient(cfg)
+	// One Turvo connection per tenant; without TENANTS_FILE there is one
+	tenantConfigs, defaultTenant, err := cfg.Tenants(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to create Turvo client: %v", err)
+		fatal("Failed to load tenants", err)
+	}
+	policy, err := loadPolicy(cfg)
+	if err != nil {
+		fatal("Failed to load policy", err)
+	}
+	idem, err := newIdempotencyStore(cfg)
+	if err != nil {
+		fatal("Failed to create idempotency store", err)
+	}
+	jobStore, err := newJobStore(cfg)
+	if err != nil {
+		fatal("Failed to create job store", err)
 	}
 
-	// Create a new mapper
-	turvoMapper := turvo.NewMapper(cfg)
+	if cfg.CursorSecret == "" {
+		slog.Warn("CURSOR_SECRET is not set; list cursors only work on the instance that issued them, until it restarts")
+	}
+
+	tenants := tenant.NewRegistry(defaultTenant)
+	for _, tc := range tenantConfigs {
+		t, err := newTenant(tc, policy, idem, jobStore)
+		if err != nil {
+			fatal("Failed to create tenant "+tc.ID, err)
+		}
+		tenants.Add(t)
+	}
+	slog.Info("Tenants loaded", "tenants", tenants.IDs(), "default", defaultTenant)
+
+	authenticator, err := newAuthenticator(context.Background(), cfg, tenants)
+	if err != nil {
+		fatal("Failed to configure authentication", err)
+	}
+	if !cfg.AuthRequired {
+		slog.Warn("API authentication is optional (AUTH_REQUIRED=false)")
+	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestID)
+	r.Use(logging.Middleware(logger))
 	r.Use(middleware.Recoverer)
+	// callers send bearer tokens or API keys, not cookies, so credentials are
+	// only allowed for an explicit origin list, never with *
 	r.Use(chcors.Handler(chcors.Options{
 		AllowedOrigins:   cfg.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", auth.APIKeyHeader, cfg.TenantHeader, idempotency.Header},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", "Content-Disposition"},
+		AllowCredentials: !slices.Contains(cfg.AllowedOrigins, "*"),
 		MaxAge:           300,
 	}))
 
@@ -56,12 +104,135 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
-	// API routes
-	loadHandler := handlers.NewLoadHandler(turvoClient, turvoMapper)
-	loadHandler.RegisterRoutes(r)
+	// API and admin routes require an authenticated caller and are served
+	// by the caller's tenant
+	tenantRouter := handlers.NewTenantRouter(tenants, cfg.TenantHeader)
+	tenantRouter.Resolve = func(r *http.Request) (string, bool) {
+		id, ok := auth.FromContext(r.Context())
+		return id.Tenant, ok
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(handlers.Authenticate(authenticator, cfg.AuthRequired))
+		r.Handle("/api/*", tenantRouter)
+		r.Handle("/admin/*", tenantRouter)
+	})
+
+	// Turvo webhooks go to the tenant's dispatcher; event ids are kept in the
+	// idempotency store so replays are caught across instances when it is
+	// DynamoDB
+	webhookHandler := handlers.NewWebhookHandler(cfg.WebhookSecret, tenants, idem)
+	webhookHandler.RegisterRoutes(r)
 
-	log.Println("Server starting on port 8080...")
+	slog.Info("Server starting", "addr", ":8080")
 	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		fatal("Server failed to start", err)
+	}
+}
+
+// newTenant builds the Turvo client, mapper and routes for one tenant. In
+// demo mode each tenant gets its own in-memory store instead of a client.
+func newTenant(tc config.Tenant, policy *authz.Policy, idem idempotency.Store, jobStore jobs.Store) (*tenant.Tenant, error) {
+	cfg := tc.Config
+	client, err := turvo.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t := &tenant.Tenant{
+		ID:     tc.ID,
+		Config: cfg,
+		Client: client,
+		Mapper: turvo.NewMapper(cfg),
 	}
+
+	var (
+		shipments handlers.ShipmentStore     = client
+		customers handlers.CustomerDirectory = client
+		orders    handlers.OrderStore        = client
+		locations turvo.LocationDirectory    = client
+	)
+	if cfg.DemoMode {
+		slog.Info("Demo mode: serving loads from an in-memory store", "tenant", tc.ID)
+		store := memstore.New()
+		seedDemo(store, t.Mapper)
+		shipments, customers, orders, locations = store, store, store, store
+	} else if p := cfg.SecretProvider(); p != nil {
+		// Rotate Turvo credentials from Secrets Manager (or a local file)
+		// without a restart; a credential rejection triggers an early refresh
+		t.Secrets = config.NewSecretRefresher(p, cfg, client)
+		client.SetOnAuthRejected(t.Secrets.Trigger)
+		go t.Secrets.Run(context.Background())
+	}
+
+	// webhook events are pushed to the grid's open event streams
+	events := handlers.NewLoadEvents()
+	t.Events = turvo.NewDispatcher()
+	t.Events.Subscribe("", func(ctx context.Context, ev turvo.Event) {
+		slog.InfoContext(ctx, "Turvo webhook received", "tenant", tc.ID, "event_type", ev.EventType(), "event_id", ev.EventID())
+	})
+	t.Events.Subscribe("", events.Publish)
+
+	r := chi.NewRouter()
+	loads := handlers.NewLoadHandler(shipments, customers, t.Mapper, turvo.NewLocationResolver(locations))
+	loads.Policy = policy
+	loads.Events = events
+	loads.Idempotency = idem
+	loads.Jobs = jobStore
+	if cfg.CursorSecret != "" {
+		loads.CursorKey = []byte(cfg.CursorSecret)
+	}
+	loads.RegisterRoutes(r)
+	orderHandler := handlers.NewOrderHandler(orders, t.Mapper)
+	orderHandler.Policy = policy
+	orderHandler.RegisterRoutes(r)
+	var secrets handlers.SecretStatusReporter
+	if t.Secrets != nil {
+		secrets = t.Secrets
+	}
+	admin := handlers.NewAdminHandler(secrets)
+	admin.Policy = policy
+	admin.RegisterRoutes(r)
+	t.Handler = r
+	return t, nil
+}
+
+// newIdempotencyStore returns the DynamoDB store when IDEMPOTENCY_TABLE is
+// set, so every instance shares keys, and an in-memory store otherwise.
+func newIdempotencyStore(cfg *config.Config) (idempotency.Store, error) {
+	if cfg.IdempotencyTable == "" {
+		s := idempotency.NewMemoryStore()
+		if cfg.IdempotencyTTL > 0 {
+			s.TTL = cfg.IdempotencyTTL
+		}
+		return s, nil
+	}
+	s, err := idempotency.NewDynamoStore(cfg.AWSRegion, cfg.IdempotencyTable)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.IdempotencyTTL > 0 {
+		s.TTL = cfg.IdempotencyTTL
+	}
+	slog.Info("Idempotency keys stored in DynamoDB", "table", cfg.IdempotencyTable)
+	return s, nil
+}
+
+// newJobStore keeps bulk upload jobs in the idempotency table when
+// IDEMPOTENCY_TABLE is set, so a job can be polled through any instance, and
+// in memory otherwise.
+func newJobStore(cfg *config.Config) (jobs.Store, error) {
+	if cfg.IdempotencyTable == "" {
+		return jobs.NewMemoryStore(), nil
+	}
+	s, err := jobs.NewDynamoStore(cfg.AWSRegion, cfg.IdempotencyTable)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Bulk jobs stored in DynamoDB", "table", cfg.IdempotencyTable)
+	return s, nil
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,37 @@
+// Turvofake serves the in-memory fake Turvo API from package turvotest so the
+// backend can run locally without Turvo credentials. Point the server at it
+// with TURVO_BASE_URL and the fake's credentials.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo/turvotest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8089", "listen address")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		slog.Error("Failed to listen", "error", err)
+		os.Exit(1)
+	}
+	srv := turvotest.NewUnstartedServer()
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.AddCustomer(turvo.MinimalCustomer{ID: 500, Name: "Demo Customer"})
+	srv.Start()
+
+	slog.Info("Fake Turvo listening", "url", srv.URL)
+	// the fake's credentials are public; print them for copy-paste into .env
+	fmt.Printf("TURVO_BASE_URL=%s TURVO_AUTH_MODE=oauth_password TURVO_CLIENT_ID=%s TURVO_CLIENT_SECRET=%s TURVO_USERNAME=%s TURVO_PASSWORD=%s TURVO_DEFAULT_CUSTOMER_ID=500\n",
+		srv.URL, turvotest.ClientID, turvotest.ClientSecret, turvotest.Username, turvotest.Password)
+	select {}
+}
@@ -0,0 +1,153 @@
+// Package auth authenticates callers of the Drumkit API, either with a JWT
+// from an OIDC issuer or with a static API key for service callers, and
+// carries the resulting Identity in the request context.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries a static API key.
+const APIKeyHeader = "X-API-Key"
+
+// Authentication methods recorded in Identity.Method.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+var (
+	// ErrNoCredentials means the request carried neither a bearer token nor
+	// an API key.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials means a token or key was present but rejected.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is the authenticated caller.
+type Identity struct {
+	// Subject is the JWT sub claim or the API key's name.
+	Subject string
+	Method  string
+	// Tenant is the tenant the caller is bound to, from the tenant claim or
+	// the API key entry; empty when the caller is not bound to one.
+	Tenant string
+	// Claims holds the verified JWT claims; nil for API keys.
+	Claims map[string]any
+}
+
+type ctxKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the identity stored by WithIdentity.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
+
+// APIKey is a named static key for a service caller.
+type APIKey struct {
+	Name string
+	Key  string
+	// Tenant binds the key to one tenant; empty leaves it unbound.
+	Tenant string
+}
+
+// ParseAPIKeys parses API_KEYS entries of the form name:key or
+// name:key:tenant. Keys must be at least 16 characters and names unique.
+func ParseAPIKeys(entries []string) ([]APIKey, error) {
+	var keys []APIKey
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		parts := strings.Split(e, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("API key entry must be name:key or name:key:tenant")
+		}
+		name, key := parts[0], parts[1]
+		var ten string
+		if len(parts) == 3 {
+			if ten = parts[2]; ten == "" {
+				return nil, fmt.Errorf("API key %q has an empty tenant", name)
+			}
+		}
+		if len(key) < 16 {
+			return nil, fmt.Errorf("API key %q must be at least 16 characters", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("API key %q is listed twice", name)
+		}
+		seen[name] = true
+		keys = append(keys, APIKey{Name: name, Key: key, Tenant: ten})
+	}
+	return keys, nil
+}
+
+// Authenticator checks a request's bearer JWT or API key.
+type Authenticator struct {
+	// JWT verifies bearer tokens; nil disables JWT authentication.
+	JWT *JWTVerifier
+
+	apiKeys []hashedKey
+}
+
+type hashedKey struct {
+	name   string
+	tenant string
+	sum    [sha256.Size]byte
+}
+
+// NewAuthenticator returns an Authenticator accepting tokens from jwt (may
+// be nil) and the given API keys.
+func NewAuthenticator(jwt *JWTVerifier, keys []APIKey) *Authenticator {
+	a := &Authenticator{JWT: jwt}
+	for _, k := range keys {
+		a.apiKeys = append(a.apiKeys, hashedKey{name: k.Name, tenant: k.Tenant, sum: sha256.Sum256([]byte(k.Key))})
+	}
+	return a
+}
+
+// Authenticate returns the caller's identity. An API key in X-API-Key takes
+// precedence over an Authorization bearer token.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.checkAPIKey(key)
+	}
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	if a.JWT == nil {
+		return Identity{}, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+	return a.JWT.Verify(r.Context(), strings.TrimSpace(token))
+}
+
+// checkAPIKey compares hashes in constant time against every key so timing
+// does not reveal which key, if any, matched.
+func (a *Authenticator) checkAPIKey(key string) (Identity, error) {
+	sum := sha256.Sum256([]byte(key))
+	var match *hashedKey
+	for i, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(sum[:], k.sum[:]) == 1 {
+			match = &a.apiKeys[i]
+		}
+	}
+	if match == nil {
+		return Identity{}, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	return Identity{Subject: match.name, Method: MethodAPIKey, Tenant: match.tenant}, nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func jwksJSON(keys map[string]*rsa.PrivateKey) []byte {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, k := range keys {
+		doc.Keys = append(doc.Keys, jwk{
+			Kid: kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	b, _ := json.Marshal(doc)
+	return b
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    testIssuer,
+		"aud":    "drumkit",
+		"sub":    "user-1",
+		"tenant": "east",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	key := newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	os.WriteFile(path, jwksJSON(map[string]*rsa.PrivateKey{"k1": key}), 0o600)
+	keys, err := LoadJWKSFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewJWTVerifier(keys, testIssuer, "drumkit", "tenant")
+
+	id, err := v.Verify(context.Background(), sign(t, key, "k1", validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "user-1" || id.Tenant != "east" || id.Method != MethodJWT {
+		t.Fatalf("identity = %+v", id)
+	}
+
+	other := newRSAKey(t)
+	for name, tok := range map[string]string{
+		"expired":      sign(t, key, "k1", with(validClaims(), "exp", time.Now().Add(-time.Hour).Unix())),
+		"no exp":       sign(t, key, "k1", without(validClaims(), "exp")),
+		"wrong issuer": sign(t, key, "k1", with(validClaims(), "iss", "https://evil.example.com")),
+		"wrong aud":    sign(t, key, "k1", with(validClaims(), "aud", "other")),
+		"no subject":   sign(t, key, "k1", without(validClaims(), "sub")),
+		"unknown key":  sign(t, other, "k2", validClaims()),
+		"wrong key":    sign(t, other, "k1", validClaims()),
+		"hmac":         hmacToken(t, validClaims()),
+	} {
+		if _, err := v.Verify(context.Background(), tok); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: err = %v, want ErrInvalidCredentials", name, err)
+		}
+	}
+}
+
+func with(c jwt.MapClaims, k string, v any) jwt.MapClaims {
+	c[k] = v
+	return c
+}
+
+func without(c jwt.MapClaims, k string) jwt.MapClaims {
+	delete(c, k)
+	return c
+}
+
+func hmacToken(t *testing.T, c jwt.MapClaims) string {
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRemoteKeySetPicksUpRotatedKeys(t *testing.T) {
+	k1, k2 := newRSAKey(t), newRSAKey(t)
+	current := map[string]*rsa.PrivateKey{"k1": k1}
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(jwksJSON(current))
+	}))
+	defer srv.Close()
+
+	keys := NewRemoteKeySet(srv.URL)
+	keys.MinRefresh = 0
+	v := NewJWTVerifier(keys, testIssuer, "", "")
+	if _, err := v.Verify(context.Background(), sign(t, k1, "k1", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	current = map[string]*rsa.PrivateKey{"k1": k1, "k2": k2}
+	if _, err := v.Verify(context.Background(), sign(t, k2, "k2", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, k1, "k1", validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+}
+
+func TestAuthenticatorAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"billing:0123456789abcdef:east", " reports:fedcba9876543210 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAuthenticator(nil, keys)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/loads", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("no credentials: err = %v", err)
+	}
+	req.Header.Set(APIKeyHeader, "fedcba9876543210")
+	if id, err := a.Authenticate(req); err != nil || id.Subject != "reports" || id.Method != MethodAPIKey || id.Tenant != "" {
+		t.Fatalf("id = %+v, err = %v", id, err)
+	}
+	req.Header.Set(APIKeyHeader, "0123456789abcdef")
+	if id, err := a.Authenticate(req); err != nil || id.Subject != "billing" || id.Tenant != "east" {
+		t.Fatalf("bound key: id = %+v, err = %v", id, err)
+	}
+	req.Header.Set(APIKeyHeader, "not-a-key-at-all")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("bad key: err = %v", err)
+	}
+	req.Header.Del(APIKeyHeader)
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("bearer without OIDC: err = %v", err)
+	}
+}
+
+func TestParseAPIKeysRejectsBadEntries(t *testing.T) {
+	for _, entries := range [][]string{
+		{"nokey"},
+		{":0123456789abcdef"},
+		{"short:abc"},
+		{"dup:0123456789abcdef", "dup:fedcba9876543210"},
+		{"notenant:0123456789abcdef:"},
+		{"extra:0123456789abcdef:east:west"},
+	} {
+		if _, err := ParseAPIKeys(entries); err == nil {
+			t.Errorf("ParseAPIKeys(%q) succeeded", entries)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeySet returns the public key that signed a token, by key id.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// jwk is one entry of a JSON Web Key Set (RFC 7517).
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS decodes the signing keys in a JWKS document. Encryption keys and
+// unsupported key types are skipped.
+func parseJWKS(b []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		if pub != nil {
+			keys[k.Kid] = pub
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks has no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64Int(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64Int(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64Int(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64Int(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// lookup finds kid in keys. A token without a kid matches when the set holds
+// a single key.
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if k, ok := keys[kid]; ok {
+		return k, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// StaticKeySet is a fixed set of keys, typically loaded from a file for local
+// testing.
+type StaticKeySet struct {
+	keys map[string]crypto.PublicKey
+}
+
+// LoadJWKSFile reads a JWKS document from path.
+func LoadJWKSFile(path string) (*StaticKeySet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks file: %w", err)
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticKeySet{keys: keys}, nil
+}
+
+// Key implements KeySet.
+func (s *StaticKeySet) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if k, ok := lookup(s.keys, kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// RemoteKeySet fetches keys from a JWKS URL. Keys are cached for TTL, and an
+// unknown key id triggers a refetch at most once per MinRefresh so rotated
+// issuer keys are picked up without hammering the issuer.
+type RemoteKeySet struct {
+	URL        string
+	HTTPClient *http.Client
+	TTL        time.Duration
+	MinRefresh time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewRemoteKeySet returns a RemoteKeySet for url with a one hour cache.
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		URL:        url,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		TTL:        time.Hour,
+		MinRefresh: time.Minute,
+	}
+}
+
+// Key implements KeySet.
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := lookup(s.keys, kid); ok && time.Since(s.fetched) < s.TTL {
+		return k, nil
+	}
+	if s.keys == nil || time.Since(s.fetched) >= s.MinRefresh {
+		if err := s.fetch(ctx); err != nil {
+			// keep serving cached keys if the issuer is briefly unreachable
+			if k, ok := lookup(s.keys, kid); ok {
+				return k, nil
+			}
+			return nil, err
+		}
+	}
+	if k, ok := lookup(s.keys, kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) error {
+	b, err := getJSON(ctx, s.HTTPClient, s.URL)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	s.fetched = time.Now()
+	return nil
+}
+
+// DiscoverJWKSURL reads the issuer's OpenID configuration and returns its
+// jwks_uri.
+func DiscoverJWKSURL(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	b, err := getJSON(ctx, client, strings.TrimRight(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", fmt.Errorf("oidc discovery: %w", err)
+	}
+	var doc struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil || doc.JWKSURI == "" {
+		return "", fmt.Errorf("oidc discovery: no jwks_uri for %s", issuer)
+	}
+	return doc.JWKSURI, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return b, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods are the asymmetric algorithms accepted from the issuer.
+// HMAC and "none" are never accepted.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// JWTVerifier validates bearer tokens issued by one OIDC issuer.
+type JWTVerifier struct {
+	Keys     KeySet
+	Issuer   string
+	Audience string
+	// TenantClaim names the claim that binds a caller to a tenant.
+	TenantClaim string
+	// Leeway tolerates clock skew in exp and nbf.
+	Leeway time.Duration
+}
+
+// NewJWTVerifier returns a verifier for tokens from issuer signed by keys.
+// An empty audience skips the aud check.
+func NewJWTVerifier(keys KeySet, issuer, audience, tenantClaim string) *JWTVerifier {
+	return &JWTVerifier{
+		Keys:        keys,
+		Issuer:      issuer,
+		Audience:    audience,
+		TenantClaim: tenantClaim,
+		Leeway:      30 * time.Second,
+	}
+}
+
+// Verify checks the token's signature, issuer, audience and expiry and
+// returns the caller's identity.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Identity, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.Leeway),
+	}
+	if v.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.Audience))
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Identity{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	id := Identity{Subject: sub, Method: MethodJWT, Claims: claims}
+	if v.TenantClaim != "" {
+		id.Tenant, _ = claims[v.TenantClaim].(string)
+	}
+	return id, nil
+}
@@ -0,0 +1,283 @@
+// Package authz decides what an authenticated caller may do. Roles grant
+// permissions on API operations and may restrict the caller to assigned
+// customers; a Policy maps callers to roles by subject or token claim.
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+)
+
+// Permission names one API operation.
+type Permission string
+
+// Permissions checked by the handlers. A role may also list "*" for every
+// permission or "loads:*" for every permission on a resource.
+const (
+	LoadsRead          Permission = "loads:read"
+	LoadsCreate        Permission = "loads:create"
+	LoadsUpdate        Permission = "loads:update"
+	LoadsAssignCarrier Permission = "loads:assign_carrier"
+	CustomersRead      Permission = "customers:read"
+	OrdersRead         Permission = "orders:read"
+	OrdersCreate       Permission = "orders:create"
+	Admin              Permission = "admin"
+)
+
+var allPermissions = []Permission{LoadsRead, LoadsCreate, LoadsUpdate, LoadsAssignCarrier, CustomersRead, OrdersRead, OrdersCreate, Admin}
+
+// Role is a named set of permissions.
+type Role struct {
+	Permissions []Permission `json:"permissions"`
+	// RestrictCustomers limits the role's load and customer operations to
+	// the caller's assigned customers.
+	RestrictCustomers bool `json:"restrictCustomers,omitempty"`
+}
+
+// Subject assigns roles and customers to one caller by identity subject
+// (JWT sub or API key name).
+type Subject struct {
+	Roles     []string `json:"roles"`
+	Customers []int    `json:"customers,omitempty"`
+}
+
+// Policy maps callers to roles. Roles come from Subjects, from the token's
+// RolesClaim, and from DefaultRoles; customers from Subjects and the token's
+// CustomersClaim.
+type Policy struct {
+	Roles          map[string]Role    `json:"roles"`
+	Subjects       map[string]Subject `json:"subjects"`
+	RolesClaim     string             `json:"rolesClaim"`
+	CustomersClaim string             `json:"customersClaim"`
+	DefaultRoles   []string           `json:"defaultRoles"`
+	// AllowAnonymous grants every permission to requests without an
+	// identity, for running with AUTH_REQUIRED=false.
+	AllowAnonymous bool `json:"-"`
+}
+
+// DefaultPolicy returns the built-in viewer, rep, dispatcher and admin roles
+// with no subject assignments.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Roles: map[string]Role{
+			"viewer": {Permissions: []Permission{LoadsRead, CustomersRead, OrdersRead}},
+			"rep":    {Permissions: []Permission{LoadsRead, LoadsCreate, CustomersRead}, RestrictCustomers: true},
+			"dispatcher": {Permissions: []Permission{
+				LoadsRead, LoadsCreate, LoadsUpdate, LoadsAssignCarrier, CustomersRead, OrdersRead, OrdersCreate,
+			}},
+			"admin": {Permissions: []Permission{"*"}},
+		},
+		Subjects:       map[string]Subject{},
+		RolesClaim:     "roles",
+		CustomersClaim: "customers",
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file. Roles defined in the file
+// replace the built-in role of the same name; the built-in roles stay
+// available otherwise.
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+	var f Policy
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	p := DefaultPolicy()
+	for name, r := range f.Roles {
+		p.Roles[name] = r
+	}
+	for name, s := range f.Subjects {
+		p.Subjects[name] = s
+	}
+	if f.RolesClaim != "" {
+		p.RolesClaim = f.RolesClaim
+	}
+	if f.CustomersClaim != "" {
+		p.CustomersClaim = f.CustomersClaim
+	}
+	p.DefaultRoles = f.DefaultRoles
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate reports unknown permissions and references to undefined roles.
+func (p *Policy) Validate() error {
+	var problems []string
+	for name, r := range p.Roles {
+		for _, perm := range r.Permissions {
+			if !knownPermission(perm) {
+				problems = append(problems, fmt.Sprintf("role %q has unknown permission %q", name, perm))
+			}
+		}
+	}
+	check := func(where string, roles []string) {
+		for _, r := range roles {
+			if _, ok := p.Roles[r]; !ok {
+				problems = append(problems, fmt.Sprintf("%s uses undefined role %q", where, r))
+			}
+		}
+	}
+	for name, s := range p.Subjects {
+		check(fmt.Sprintf("subject %q", name), s.Roles)
+	}
+	check("defaultRoles", p.DefaultRoles)
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid policy: %s", strings.Join(problems, "; "))
+}
+
+func knownPermission(perm Permission) bool {
+	if perm == "*" {
+		return true
+	}
+	for _, k := range allPermissions {
+		if perm == k || (strings.HasSuffix(string(perm), ":*") && strings.HasPrefix(string(k), strings.TrimSuffix(string(perm), "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(granted, perm Permission) bool {
+	if granted == "*" || granted == perm {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(string(granted), "*")
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(string(perm), prefix)
+}
+
+// Grant is what one caller may do.
+type Grant struct {
+	Subject string
+	Roles   []string
+	// perms maps each allowed permission to whether it is customer
+	// restricted; a permission is unrestricted if any role granting it is.
+	perms     map[Permission]bool
+	all       bool
+	customers map[int]bool
+}
+
+// Grant resolves the roles and customers for id. ok is false for anonymous
+// requests.
+func (p *Policy) Grant(id auth.Identity, ok bool) Grant {
+	if !ok {
+		return Grant{all: p.AllowAnonymous}
+	}
+	g := Grant{Subject: id.Subject, perms: make(map[Permission]bool), customers: make(map[int]bool)}
+	roles := append([]string(nil), p.DefaultRoles...)
+	if s, ok := p.Subjects[id.Subject]; ok {
+		roles = append(roles, s.Roles...)
+		for _, c := range s.Customers {
+			g.customers[c] = true
+		}
+	}
+	roles = append(roles, claimStrings(id.Claims[p.RolesClaim])...)
+	for _, c := range claimStrings(id.Claims[p.CustomersClaim]) {
+		if n, err := strconv.Atoi(c); err == nil {
+			g.customers[n] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range roles {
+		role, ok := p.Roles[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		g.Roles = append(g.Roles, name)
+		for _, perm := range allPermissions {
+			for _, granted := range role.Permissions {
+				if !matches(granted, perm) {
+					continue
+				}
+				if restricted, had := g.perms[perm]; !had || restricted {
+					g.perms[perm] = role.RestrictCustomers
+				}
+			}
+		}
+	}
+	sort.Strings(g.Roles)
+	return g
+}
+
+// claimStrings reads a claim holding a list or a space-separated string.
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var out []string
+		for _, e := range v {
+			switch e := e.(type) {
+			case string:
+				out = append(out, e)
+			case float64:
+				out = append(out, strconv.FormatFloat(e, 'f', -1, 64))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Allows reports whether perm is granted at all.
+func (g Grant) Allows(perm Permission) bool {
+	if g.all {
+		return true
+	}
+	_, ok := g.perms[perm]
+	return ok
+}
+
+// Customers returns the customer ids perm is limited to, and false when perm
+// is not customer restricted.
+func (g Grant) Customers(perm Permission) ([]int, bool) {
+	if g.all || !g.perms[perm] {
+		return nil, false
+	}
+	ids := make([]int, 0, len(g.customers))
+	for id := range g.customers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, true
+}
+
+// AllowsCustomer reports whether perm may be used on customerID.
+func (g Grant) AllowsCustomer(perm Permission, customerID int) bool {
+	if !g.Allows(perm) {
+		return false
+	}
+	if g.all || !g.perms[perm] {
+		return true
+	}
+	return g.customers[customerID]
+}
+
+type ctxKey struct{}
+
+// WithGrant returns a copy of ctx carrying g.
+func WithGrant(ctx context.Context, g Grant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, g)
+}
+
+// FromContext returns the grant stored by WithGrant.
+func FromContext(ctx context.Context) (Grant, bool) {
+	g, ok := ctx.Value(ctxKey{}).(Grant)
+	return g, ok
+}
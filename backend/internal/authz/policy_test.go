@@ -0,0 +1,91 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+)
+
+func TestGrantFromClaimsAndSubjects(t *testing.T) {
+	p := DefaultPolicy()
+	p.Subjects["svc-import"] = Subject{Roles: []string{"dispatcher"}}
+	p.Subjects["alice"] = Subject{Customers: []int{7}}
+
+	viewer := p.Grant(auth.Identity{Subject: "bob", Claims: map[string]any{"roles": []any{"viewer"}}}, true)
+	if !viewer.Allows(LoadsRead) || viewer.Allows(LoadsCreate) {
+		t.Errorf("viewer grant = %+v", viewer)
+	}
+
+	rep := p.Grant(auth.Identity{Subject: "alice", Claims: map[string]any{"roles": "rep", "customers": []any{float64(9)}}}, true)
+	ids, restricted := rep.Customers(LoadsCreate)
+	if !restricted || !slices.Equal(ids, []int{7, 9}) {
+		t.Errorf("rep customers = %v, %v", ids, restricted)
+	}
+	if !rep.AllowsCustomer(LoadsCreate, 9) || rep.AllowsCustomer(LoadsCreate, 8) {
+		t.Error("rep customer checks wrong")
+	}
+
+	svc := p.Grant(auth.Identity{Subject: "svc-import", Method: auth.MethodAPIKey}, true)
+	if !svc.AllowsCustomer(LoadsAssignCarrier, 123) || svc.Allows(Admin) {
+		t.Errorf("service grant = %+v", svc)
+	}
+
+	if anon := p.Grant(auth.Identity{}, false); anon.Allows(LoadsRead) {
+		t.Error("anonymous caller allowed without AllowAnonymous")
+	}
+	p.AllowAnonymous = true
+	if anon := p.Grant(auth.Identity{}, false); !anon.AllowsCustomer(Admin, 1) {
+		t.Error("anonymous caller denied with AllowAnonymous")
+	}
+}
+
+func TestUnrestrictedRoleWins(t *testing.T) {
+	p := DefaultPolicy()
+	g := p.Grant(auth.Identity{Subject: "x", Claims: map[string]any{"roles": []any{"rep", "viewer"}}}, true)
+	if _, restricted := g.Customers(LoadsRead); restricted {
+		t.Error("loads:read restricted although viewer grants it unrestricted")
+	}
+	if _, restricted := g.Customers(LoadsCreate); !restricted {
+		t.Error("loads:create unrestricted although only rep grants it")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		path := filepath.Join(dir, "policy.json")
+		os.WriteFile(path, []byte(body), 0o600)
+		return path
+	}
+
+	p, err := LoadPolicy(write(`{
+		"roles": {"auditor": {"permissions": ["loads:*", "customers:read"]}},
+		"subjects": {"reporting": {"roles": ["auditor"]}},
+		"defaultRoles": ["viewer"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.Roles["rep"]; !ok {
+		t.Error("built-in roles dropped")
+	}
+	g := p.Grant(auth.Identity{Subject: "reporting"}, true)
+	if !g.Allows(LoadsUpdate) || g.Allows(OrdersCreate) {
+		t.Errorf("auditor grant = %+v", g)
+	}
+	if g := p.Grant(auth.Identity{Subject: "anyone"}, true); !g.Allows(OrdersRead) {
+		t.Error("defaultRoles not applied")
+	}
+
+	_, err = LoadPolicy(write(`{
+		"roles": {"broken": {"permissions": ["loads:delete"]}},
+		"subjects": {"x": {"roles": ["missing"]}}
+	}`))
+	if err == nil || !strings.Contains(err.Error(), `"loads:delete"`) || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
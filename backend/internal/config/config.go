@@ -1,8 +1,10 @@
 package config
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -13,25 +15,67 @@ import (
 type Config struct {
 	AppEnv         string `envconfig:"APP_ENV" default:"local"`
 	TurvoBaseURL   string `envconfig:"TURVO_BASE_URL" default:"https://app.turvo.com"`
-	TurvoAPIPrefix string `envconfig:"TURVO_API_PREFIX" default:"/v1"`
+	TurvoAPIPrefix string `envconfig:"TURVO_API_PREFIX"` // defaults by auth mode, see DefaultAPIPrefix
+	TurvoAuthMode  string `envconfig:"TURVO_AUTH_MODE" default:"oauth_password"`
 	// OAuth (preferred)
-	TurvoClientID                     string   `envconfig:"TURVO_CLIENT_ID"`
-	TurvoClientSecret                 string   `envconfig:"TURVO_CLIENT_SECRET"`
-	TurvoAPIKey                       string   `envconfig:"TURVO_API_KEY"`
-	TurvoOAuthUsername                string   `envconfig:"TURVO_USERNAME"`
-	TurvoOAuthPassword                string   `envconfig:"TURVO_PASSWORD"`
-	TurvoOAuthScope                   string   `envconfig:"TURVO_SCOPE" default:"read+trust+write"`
-	TurvoOAuthUserType                string   `envconfig:"TURVO_USER_TYPE" default:"business"`
-	TurvoTenant                       string   `envconfig:"TURVO_TENANT"`
-	TurvoUseAWSSigV4                  bool     `envconfig:"TURVO_USE_AWS_SIGV4" default:"false"`
-	WebhookSecret                     string   `envconfig:"WEBHOOK_SECRET"`
-	AllowedOrigins                    []string `envconfig:"ALLOWED_ORIGINS" default:"*"`
-	LogLevel                          string   `envconfig:"LOG_LEVEL" default:"info"`
-	TurvoDefaultCustomerID            int      `envconfig:"TURVO_DEFAULT_CUSTOMER_ID" default:"0"`
-	TurvoDefaultOriginLocationID      int      `envconfig:"TURVO_DEFAULT_ORIGIN_LOCATION_ID" default:"0"`
-	TurvoDefaultDestinationLocationID int      `envconfig:"TURVO_DEFAULT_DESTINATION_LOCATION_ID" default:"0"`
-	AWSRegion                         string   `envconfig:"AWS_REGION" default:"us-east-1"`
-	SecretsManagerTurvoSecretName     string   `envconfig:"SECRETS_MANAGER_TURVO_SECRET_NAME"`
+	TurvoClientID                     string        `envconfig:"TURVO_CLIENT_ID"`
+	TurvoClientSecret                 string        `envconfig:"TURVO_CLIENT_SECRET"`
+	TurvoAPIKey                       string        `envconfig:"TURVO_API_KEY"`
+	TurvoOAuthUsername                string        `envconfig:"TURVO_USERNAME"`
+	TurvoOAuthPassword                string        `envconfig:"TURVO_PASSWORD"`
+	TurvoOAuthScope                   string        `envconfig:"TURVO_SCOPE" default:"read+trust+write"`
+	TurvoOAuthUserType                string        `envconfig:"TURVO_USER_TYPE" default:"business"`
+	TurvoTenant                       string        `envconfig:"TURVO_TENANT"`
+	TurvoUseAWSSigV4                  bool          `envconfig:"TURVO_USE_AWS_SIGV4" default:"false"`
+	WebhookSecret                     string        `envconfig:"WEBHOOK_SECRET"`
+	AllowedOrigins                    []string      `envconfig:"ALLOWED_ORIGINS" default:"*"`
+	LogLevel                          string        `envconfig:"LOG_LEVEL" default:"info"`
+	DemoMode                          bool          `envconfig:"DEMO_MODE" default:"false"` // in-memory store instead of Turvo
+	TurvoDefaultCustomerID            int           `envconfig:"TURVO_DEFAULT_CUSTOMER_ID" default:"0"`
+	TurvoDefaultOriginLocationID      int           `envconfig:"TURVO_DEFAULT_ORIGIN_LOCATION_ID" default:"0"`
+	TurvoDefaultDestinationLocationID int           `envconfig:"TURVO_DEFAULT_DESTINATION_LOCATION_ID" default:"0"`
+	AWSRegion                         string        `envconfig:"AWS_REGION" default:"us-east-1"`
+	SecretsManagerTurvoSecretName     string        `envconfig:"SECRETS_MANAGER_TURVO_SECRET_NAME"`
+	TurvoSecretsFile                  string        `envconfig:"TURVO_SECRETS_FILE"` // JSON file instead of Secrets Manager
+	SecretsRefreshInterval            time.Duration `envconfig:"SECRETS_REFRESH_INTERVAL" default:"5m"`
+	TenantsFile                       string        `envconfig:"TENANTS_FILE"` // per-tenant Turvo settings, see Tenants
+	TenantHeader                      string        `envconfig:"TENANT_HEADER" default:"X-Tenant-ID"`
+	PolicyFile                        string        `envconfig:"POLICY_FILE"`       // roles and subject assignments, see authz.LoadPolicy
+	IdempotencyTable                  string        `envconfig:"IDEMPOTENCY_TABLE"` // DynamoDB table; in memory when empty
+	IdempotencyTTL                    time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
+	CursorSecret                      string        `envconfig:"CURSOR_SECRET"` // signs list cursors; random per process when empty
+	// Authentication of Drumkit API callers
+	AuthRequired    bool     `envconfig:"AUTH_REQUIRED" default:"true"`
+	OIDCIssuer      string   `envconfig:"OIDC_ISSUER"`
+	OIDCAudience    string   `envconfig:"OIDC_AUDIENCE"`
+	OIDCJWKSURL     string   `envconfig:"OIDC_JWKS_URL"`  // default: discovered from the issuer
+	OIDCJWKSFile    string   `envconfig:"OIDC_JWKS_FILE"` // local testing
+	OIDCTenantClaim string   `envconfig:"OIDC_TENANT_CLAIM" default:"tenant"`
+	APIKeys         []string `envconfig:"API_KEYS"` // name:key pairs for service callers
+
+	// prefixDefaulted records that TurvoAPIPrefix came from DefaultAPIPrefix,
+	// so a tenant that changes the auth mode gets its own default.
+	prefixDefaulted bool
+}
+
+// Turvo auth modes selected with TURVO_AUTH_MODE.
+const (
+	// AuthOAuthPassword obtains bearer tokens with the OAuth password grant.
+	AuthOAuthPassword = "oauth_password"
+	// AuthOAuthClientCredentials obtains bearer tokens with the OAuth
+	// client_credentials grant.
+	AuthOAuthClientCredentials = "oauth_client_credentials"
+	// AuthAPIKey sends only the x-api-key header; no OAuth is performed.
+	AuthAPIKey = "api_key"
+)
+
+// DefaultAPIPrefix returns the data API path prefix used when
+// TURVO_API_PREFIX is unset: /v1 for OAuth modes and /public/v1 for api_key.
+func DefaultAPIPrefix(mode string) string {
+	if mode == AuthAPIKey {
+		return "/public/v1"
+	}
+	return "/v1"
 }
 
 // Load reads environment variables (optionally from .env when APP_ENV=local),
@@ -42,56 +86,122 @@ func Load() (*Config, error) {
 
 	// In local, load .env first
 	if err := godotenv.Load(); err == nil {
-		log.Printf("Loaded .env file")
+		slog.Info("Loaded .env file")
 	}
 
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, err
 	}
 
-	// If deployed (any env other than local), fetch secrets from Secrets Manager (optional)
-	if cfg.AppEnv != "local" && cfg.SecretsManagerTurvoSecretName != "" {
-		secretJSON, err := FetchSecret(cfg.AWSRegion, cfg.SecretsManagerTurvoSecretName)
-		if err != nil {
-			log.Printf("warning: failed to fetch secrets: %v", err)
-		} else {
-			// Expected JSON keys include all envs above
-			var m map[string]string
-			if err := json.Unmarshal([]byte(secretJSON), &m); err == nil {
-				if v := m["TURVO_CLIENT_ID"]; v != "" {
-					cfg.TurvoClientID = v
-				}
-				if v := m["TURVO_CLIENT_SECRET"]; v != "" {
-					cfg.TurvoClientSecret = v
-				}
-				if v := m["TURVO_API_KEY"]; v != "" {
-					cfg.TurvoAPIKey = v
-				}
-				if v := m["TURVO_USERNAME"]; v != "" {
-					cfg.TurvoOAuthUsername = v
-				}
-				if v := m["TURVO_PASSWORD"]; v != "" {
-					cfg.TurvoOAuthPassword = v
-				}
-				if v := m["TURVO_SCOPE"]; v != "" {
-					cfg.TurvoOAuthScope = v
-				}
-				if v := m["TURVO_USER_TYPE"]; v != "" {
-					cfg.TurvoOAuthUserType = v
-				}
-				if v := m["TURVO_BASE_URL"]; v != "" {
-					cfg.TurvoBaseURL = v
-				}
-				if v := m["TURVO_TENANT"]; v != "" {
-					cfg.TurvoTenant = v
-				}
-				if v := m["TURVO_API_PREFIX"]; v != "" {
-					cfg.TurvoAPIPrefix = v
-				}
-			}
+	if p := cfg.SecretProvider(); p != nil {
+		cfg.loadSecret(context.Background(), p)
+	}
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadSecret applies the secret from p. Secrets Manager when deployed, or a
+// local secrets file, overrides the environment. Refresh failures later keep
+// the last good values, so a failure here only warns.
+func (c *Config) loadSecret(ctx context.Context, p SecretProvider) {
+	m, err := p.Fetch(ctx)
+	if err != nil {
+		slog.Warn("Failed to fetch secrets", "source", p.Name(), "error", err)
+		return
+	}
+	c.ApplySecret(m)
+}
+
+// normalize canonicalizes the auth mode and fills in the API prefix default.
+func (c *Config) normalize() {
+	c.TurvoAuthMode = strings.ToLower(strings.TrimSpace(c.TurvoAuthMode))
+	if c.TurvoAPIPrefix == "" {
+		c.TurvoAPIPrefix = DefaultAPIPrefix(c.TurvoAuthMode)
+		c.prefixDefaulted = true
+	}
+}
+
+// ApplySecret overrides settings with the non-empty values in m, keyed by
+// environment variable name as stored in Secrets Manager.
+func (c *Config) ApplySecret(m map[string]string) {
+	for key, dst := range c.secretFields() {
+		if v := m[key]; v != "" {
+			*dst = v
 		}
 	}
+}
+
+// secretFields maps the keys accepted by ApplySecret to the fields they set.
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"TURVO_CLIENT_ID":     &c.TurvoClientID,
+		"TURVO_CLIENT_SECRET": &c.TurvoClientSecret,
+		"TURVO_API_KEY":       &c.TurvoAPIKey,
+		"TURVO_USERNAME":      &c.TurvoOAuthUsername,
+		"TURVO_PASSWORD":      &c.TurvoOAuthPassword,
+		"TURVO_SCOPE":         &c.TurvoOAuthScope,
+		"TURVO_USER_TYPE":     &c.TurvoOAuthUserType,
+		"TURVO_BASE_URL":      &c.TurvoBaseURL,
+		"TURVO_TENANT":        &c.TurvoTenant,
+		"TURVO_API_PREFIX":    &c.TurvoAPIPrefix,
+		"TURVO_AUTH_MODE":     &c.TurvoAuthMode,
+	}
+}
 
-	log.Printf("Configuration loaded: %+v", cfg)
-	return &cfg, nil
+// Credentials are the Turvo secrets that may rotate while the service runs.
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	APIKey       string
+	Username     string
+	Password     string
+}
+
+// Credentials returns the Turvo credentials currently in c.
+func (c *Config) Credentials() Credentials {
+	return Credentials{
+		ClientID:     c.TurvoClientID,
+		ClientSecret: c.TurvoClientSecret,
+		APIKey:       c.TurvoAPIKey,
+		Username:     c.TurvoOAuthUsername,
+		Password:     c.TurvoOAuthPassword,
+	}
+}
+
+// LogValue implements slog.LogValuer. Credentials are listed by name only
+// when set, so the configuration can be logged safely.
+func (c Config) LogValue() slog.Value {
+	var creds []string
+	for _, f := range []struct{ name, v string }{
+		{"client_id", c.TurvoClientID},
+		{"client_secret", c.TurvoClientSecret},
+		{"api_key", c.TurvoAPIKey},
+		{"username", c.TurvoOAuthUsername},
+		{"password", c.TurvoOAuthPassword},
+		{"webhook_secret", c.WebhookSecret},
+		{"cursor_secret", c.CursorSecret},
+		{"api_keys", strings.Join(c.APIKeys, ",")},
+	} {
+		if f.v != "" {
+			creds = append(creds, f.name)
+		}
+	}
+	return slog.GroupValue(
+		slog.String("app_env", c.AppEnv),
+		slog.String("turvo_base_url", c.TurvoBaseURL),
+		slog.String("turvo_api_prefix", c.TurvoAPIPrefix),
+		slog.String("turvo_auth_mode", c.TurvoAuthMode),
+		slog.String("turvo_tenant", c.TurvoTenant),
+		slog.String("credentials", strings.Join(creds, ",")),
+		slog.Any("allowed_origins", c.AllowedOrigins),
+		slog.String("log_level", c.LogLevel),
+		slog.Bool("demo_mode", c.DemoMode),
+		slog.Bool("auth_required", c.AuthRequired),
+		slog.String("oidc_issuer", c.OIDCIssuer),
+		slog.Int("turvo_default_customer_id", c.TurvoDefaultCustomerID),
+		slog.String("aws_region", c.AWSRegion),
+	)
 }
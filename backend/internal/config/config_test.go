@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func validConfig() *Config {
+	return &Config{
+		TurvoBaseURL:       "https://publicapi.turvo.com",
+		TurvoAPIPrefix:     "/v1",
+		TurvoAuthMode:      AuthOAuthPassword,
+		TurvoClientID:      "id",
+		TurvoClientSecret:  "secret",
+		TurvoOAuthUsername: "user",
+		TurvoOAuthPassword: "pass",
+		AllowedOrigins:     []string{"http://localhost:5173", "https://*.vercel.app"},
+		LogLevel:           "info",
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRequiredFieldsPerMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		want []string
+	}{
+		{AuthOAuthPassword, []string{"TURVO_CLIENT_ID", "TURVO_CLIENT_SECRET", "TURVO_USERNAME", "TURVO_PASSWORD"}},
+		{AuthOAuthClientCredentials, []string{"TURVO_CLIENT_ID", "TURVO_CLIENT_SECRET"}},
+		{AuthAPIKey, []string{"TURVO_API_KEY"}},
+	} {
+		cfg := validConfig()
+		cfg.TurvoAuthMode = tc.mode
+		cfg.TurvoClientID, cfg.TurvoClientSecret, cfg.TurvoOAuthUsername, cfg.TurvoOAuthPassword = "", "", "", ""
+		var verr *ValidationError
+		if !errors.As(cfg.Validate(), &verr) {
+			t.Fatalf("%s: want *ValidationError", tc.mode)
+		}
+		if len(verr.Problems) != len(tc.want) {
+			t.Errorf("%s: problems = %q", tc.mode, verr.Problems)
+			continue
+		}
+		for i, name := range tc.want {
+			if !strings.HasPrefix(verr.Problems[i], name+" ") {
+				t.Errorf("%s: problem %d = %q, want %s", tc.mode, i, verr.Problems[i], name)
+			}
+		}
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.TurvoAuthMode = "basic"
+	cfg.TurvoBaseURL = "publicapi.turvo.com"
+	cfg.AllowedOrigins = []string{"https://app.example.com/"}
+	cfg.LogLevel = "verbose"
+	cfg.TurvoDefaultCustomerID = -1
+	cfg.OIDCJWKSFile = "jwks.json"
+	cfg.APIKeys = []string{"svc:short"}
+	cfg.CursorSecret = "short"
+
+	var verr *ValidationError
+	if !errors.As(cfg.Validate(), &verr) {
+		t.Fatal("want *ValidationError")
+	}
+	for _, want := range []string{"TURVO_AUTH_MODE", "TURVO_BASE_URL", "ALLOWED_ORIGINS", "LOG_LEVEL", "TURVO_DEFAULT_CUSTOMER_ID", "require OIDC_ISSUER", "API_KEYS", "CURSOR_SECRET"} {
+		if !strings.Contains(verr.Error(), want) {
+			t.Errorf("error %q does not mention %s", verr, want)
+		}
+	}
+}
+
+func TestValidateDemoModeSkipsTurvo(t *testing.T) {
+	cfg := validConfig()
+	cfg.DemoMode = true
+	cfg.TurvoBaseURL, cfg.TurvoClientID, cfg.TurvoClientSecret = "", "", ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultAPIPrefix(t *testing.T) {
+	if got := DefaultAPIPrefix(AuthAPIKey); got != "/public/v1" {
+		t.Errorf("api_key prefix = %q", got)
+	}
+	if got := DefaultAPIPrefix(AuthOAuthPassword); got != "/v1" {
+		t.Errorf("oauth_password prefix = %q", got)
+	}
+}
+
+func TestValidateRequiresAuthMethod(t *testing.T) {
+	cfg := validConfig()
+	cfg.AuthRequired = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "AUTH_REQUIRED") {
+		t.Fatalf("err = %v", err)
+	}
+	cfg.APIKeys = []string{"svc:0123456789abcdef"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRequiresOIDCAudience(t *testing.T) {
+	cfg := validConfig()
+	cfg.OIDCIssuer = "https://login.example.com"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "OIDC_AUDIENCE") {
+		t.Fatalf("err = %v", err)
+	}
+	cfg.OIDCAudience = "drumkit"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
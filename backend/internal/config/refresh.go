@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CredentialSetter receives rotated Turvo credentials. turvo.Client
+// implements it.
+type CredentialSetter interface {
+	SetCredentials(Credentials)
+}
+
+// SecretStatus describes the refresher's recent activity. It never contains
+// secret values.
+type SecretStatus struct {
+	Source      string     `json:"source"`
+	LastRefresh *time.Time `json:"lastRefresh,omitempty"`
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	// Rotations counts refreshes that changed the credentials.
+	Rotations int `json:"rotations"`
+}
+
+// SecretRefresher re-reads a SecretProvider on an interval, or sooner when
+// Trigger is called, and pushes changed credentials to a CredentialSetter.
+// A failed fetch keeps the last good credentials in place.
+type SecretRefresher struct {
+	Provider SecretProvider
+	Target   CredentialSetter
+	Interval time.Duration
+	// MinInterval throttles Trigger so a burst of rejected requests causes
+	// one fetch.
+	MinInterval time.Duration
+
+	trigger chan struct{}
+
+	mu      sync.Mutex
+	base    Config
+	current Credentials
+	status  SecretStatus
+}
+
+// NewSecretRefresher returns a refresher that starts from the credentials in
+// cfg and refreshes every cfg.SecretsRefreshInterval.
+func NewSecretRefresher(provider SecretProvider, cfg *Config, target CredentialSetter) *SecretRefresher {
+	return &SecretRefresher{
+		Provider:    provider,
+		Target:      target,
+		Interval:    cfg.SecretsRefreshInterval,
+		MinInterval: 30 * time.Second,
+		trigger:     make(chan struct{}, 1),
+		base:        *cfg,
+		current:     cfg.Credentials(),
+		status:      SecretStatus{Source: provider.Name()},
+	}
+}
+
+// Refresh fetches the secret once and applies the credentials if they
+// changed.
+func (r *SecretRefresher) Refresh(ctx context.Context) error {
+	now := time.Now()
+	m, err := r.Provider.Fetch(ctx)
+
+	r.mu.Lock()
+	r.status.LastAttempt = &now
+	if err != nil {
+		r.status.LastError = err.Error()
+		r.mu.Unlock()
+		slog.WarnContext(ctx, "Secret refresh failed", "source", r.Provider.Name(), "error", err)
+		return err
+	}
+	next := r.base
+	next.ApplySecret(m)
+	creds := next.Credentials()
+	changed := creds != r.current
+	r.current = creds
+	r.status.LastRefresh = &now
+	r.status.LastError = ""
+	if changed {
+		r.status.Rotations++
+	}
+	r.mu.Unlock()
+
+	if changed {
+		slog.InfoContext(ctx, "Turvo credentials rotated", "source", r.Provider.Name())
+		r.Target.SetCredentials(creds)
+	}
+	return nil
+}
+
+// Trigger asks Run to refresh as soon as MinInterval allows. It never
+// blocks, so it is safe to call from request paths.
+func (r *SecretRefresher) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes on the interval and on Trigger until ctx is done. A
+// non-positive Interval disables periodic refreshes.
+func (r *SecretRefresher) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if r.Interval > 0 {
+		t := time.NewTicker(r.Interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-r.trigger:
+			if time.Since(last) < r.MinInterval {
+				continue
+			}
+			slog.InfoContext(ctx, "Turvo rejected credentials; refreshing secret", "source", r.Provider.Name())
+		}
+		last = time.Now()
+		r.Refresh(ctx)
+	}
+}
+
+// Status returns a snapshot of the refresher's state.
+func (r *SecretRefresher) Status() SecretStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSetter struct {
+	mu  sync.Mutex
+	got []Credentials
+}
+
+func (s *recordingSetter) SetCredentials(c Credentials) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.got = append(s.got, c)
+}
+
+func (s *recordingSetter) calls() []Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Credentials(nil), s.got...)
+}
+
+func writeSecret(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretRefresherRotatesChangedCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turvo.json")
+	writeSecret(t, path, `{"TURVO_USERNAME":"user","TURVO_PASSWORD":"old"}`)
+	cfg := validConfig()
+	cfg.TurvoOAuthPassword = "old"
+	target := &recordingSetter{}
+	r := NewSecretRefresher(FileSecretProvider{Path: path}, cfg, target)
+
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(target.calls()); n != 0 {
+		t.Fatalf("unchanged secret pushed %d times", n)
+	}
+
+	writeSecret(t, path, `{"TURVO_USERNAME":"user","TURVO_PASSWORD":"new"}`)
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := target.calls()
+	if len(got) != 1 || got[0].Password != "new" || got[0].ClientID != "id" {
+		t.Fatalf("pushed %+v", got)
+	}
+	st := r.Status()
+	if st.Rotations != 1 || st.LastRefresh == nil || st.LastError != "" || st.Source != "file:"+path {
+		t.Fatalf("status = %+v", st)
+	}
+}
+
+func TestSecretRefresherKeepsCredentialsOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turvo.json")
+	writeSecret(t, path, `not json`)
+	target := &recordingSetter{}
+	r := NewSecretRefresher(FileSecretProvider{Path: path}, validConfig(), target)
+
+	if err := r.Refresh(context.Background()); err == nil {
+		t.Fatal("want error for malformed secret")
+	}
+	st := r.Status()
+	if len(target.calls()) != 0 || st.LastRefresh != nil || st.LastAttempt == nil || st.LastError == "" {
+		t.Fatalf("status = %+v, pushed %+v", st, target.calls())
+	}
+}
+
+func TestSecretRefresherTrigger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "turvo.json")
+	writeSecret(t, path, `{"TURVO_PASSWORD":"rotated"}`)
+	target := &recordingSetter{}
+	r := NewSecretRefresher(FileSecretProvider{Path: path}, validConfig(), target)
+	r.Interval = 0
+	r.MinInterval = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+	r.Trigger()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(target.calls()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Trigger did not refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
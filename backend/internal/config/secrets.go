@@ -1,22 +1,92 @@
 package config
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 )
 
+// SecretProvider returns the Turvo secret as a map of environment variable
+// names (TURVO_CLIENT_ID, TURVO_PASSWORD, ...) to values.
+type SecretProvider interface {
+	// Name identifies the source in logs and status output; it never
+	// contains secret values.
+	Name() string
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// SecretsManagerProvider reads a JSON secret from AWS Secrets Manager.
+type SecretsManagerProvider struct {
+	Region     string
+	SecretName string
+}
+
+// Name implements SecretProvider.
+func (p SecretsManagerProvider) Name() string { return "secretsmanager:" + p.SecretName }
+
+// Fetch implements SecretProvider.
+func (p SecretsManagerProvider) Fetch(ctx context.Context) (map[string]string, error) {
+	s, err := FetchSecret(ctx, p.Region, p.SecretName)
+	if err != nil {
+		return nil, err
+	}
+	return parseSecret([]byte(s))
+}
+
+// FileSecretProvider reads the same JSON object from a local file. It is
+// meant for local development and tests; edit the file to rotate.
+type FileSecretProvider struct {
+	Path string
+}
+
+// Name implements SecretProvider.
+func (p FileSecretProvider) Name() string { return "file:" + p.Path }
+
+// Fetch implements SecretProvider.
+func (p FileSecretProvider) Fetch(ctx context.Context) (map[string]string, error) {
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read secret file: %w", err)
+	}
+	return parseSecret(b)
+}
+
+func parseSecret(b []byte) (map[string]string, error) {
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parse secret: %w", err)
+	}
+	return m, nil
+}
+
+// SecretProvider returns the provider selected by the configuration:
+// TURVO_SECRETS_FILE when set, otherwise Secrets Manager when a secret name is
+// set outside local. It returns nil when secrets come only from the
+// environment.
+func (c *Config) SecretProvider() SecretProvider {
+	switch {
+	case c.TurvoSecretsFile != "":
+		return FileSecretProvider{Path: c.TurvoSecretsFile}
+	case c.AppEnv != "local" && c.SecretsManagerTurvoSecretName != "":
+		return SecretsManagerProvider{Region: c.AWSRegion, SecretName: c.SecretsManagerTurvoSecretName}
+	}
+	return nil
+}
+
 // FetchSecret retrieves a secret string from AWS Secrets Manager in the given
 // region by name. The caller is responsible for parsing the returned JSON.
-func FetchSecret(region string, name string) (string, error) {
+func FetchSecret(ctx context.Context, region string, name string) (string, error) {
 	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
 	if err != nil {
 		return "", fmt.Errorf("create aws session: %w", err)
 	}
 	sm := secretsmanager.New(sess)
-	out, err := sm.GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	out, err := sm.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
 	if err != nil {
 		return "", fmt.Errorf("get secret: %w", err)
 	}
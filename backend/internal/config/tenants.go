@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTenantID names the single tenant when TENANTS_FILE is unset and
+// TURVO_TENANT is empty.
+const DefaultTenantID = "default"
+
+// Tenant is the resolved configuration of one Turvo tenant.
+type Tenant struct {
+	ID     string
+	Config *Config
+}
+
+// tenantsFile is the TENANTS_FILE format. Settings are keyed by environment
+// variable name and override the service-wide configuration:
+//
+//	{
+//	  "default": "east",
+//	  "tenants": [
+//	    {"id": "east", "settings": {"TURVO_TENANT": "east", "TURVO_DEFAULT_CUSTOMER_ID": "500"}},
+//	    {"id": "west", "settings": {"SECRETS_MANAGER_TURVO_SECRET_NAME": "drumkit/turvo-west"}}
+//	  ]
+//	}
+type tenantsFile struct {
+	Default string `json:"default"`
+	Tenants []struct {
+		ID       string            `json:"id"`
+		Settings map[string]string `json:"settings"`
+	} `json:"tenants"`
+}
+
+// Tenants returns one configuration per tenant and the id of the default
+// tenant. Without TENANTS_FILE there is a single tenant, named after
+// TURVO_TENANT, using c itself. Each tenant's own secret source, if it differs
+// from the service-wide one, is read once here. All problems across tenants
+// are reported together as a *ValidationError.
+func (c *Config) Tenants(ctx context.Context) ([]Tenant, string, error) {
+	if c.TenantsFile == "" {
+		id := c.TurvoTenant
+		if id == "" {
+			id = DefaultTenantID
+		}
+		return []Tenant{{ID: id, Config: c}}, id, nil
+	}
+
+	b, err := os.ReadFile(c.TenantsFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("read tenants file: %w", err)
+	}
+	var f tenantsFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, "", fmt.Errorf("parse tenants file: %w", err)
+	}
+
+	var (
+		tenants  []Tenant
+		problems []string
+		seen     = make(map[string]bool)
+	)
+	baseSecret := ""
+	if p := c.SecretProvider(); p != nil {
+		baseSecret = p.Name()
+	}
+	for i, t := range f.Tenants {
+		if t.ID == "" {
+			problems = append(problems, fmt.Sprintf("tenant #%d has no id", i+1))
+			continue
+		}
+		if seen[t.ID] {
+			problems = append(problems, fmt.Sprintf("tenant %q is listed twice", t.ID))
+			continue
+		}
+		seen[t.ID] = true
+
+		tc := *c
+		if tc.prefixDefaulted {
+			tc.TurvoAPIPrefix = ""
+		}
+		tc.TurvoTenant = t.ID
+		if err := tc.applyTenantSettings(t.Settings); err != nil {
+			problems = append(problems, fmt.Sprintf("tenant %q: %v", t.ID, err))
+			continue
+		}
+		if p := tc.SecretProvider(); p != nil && p.Name() != baseSecret {
+			tc.loadSecret(ctx, p)
+		}
+		tc.normalize()
+		if err := tc.Validate(); err != nil {
+			for _, msg := range err.(*ValidationError).Problems {
+				problems = append(problems, fmt.Sprintf("tenant %q: %s", t.ID, msg))
+			}
+		}
+		tenants = append(tenants, Tenant{ID: t.ID, Config: &tc})
+	}
+
+	def := f.Default
+	switch {
+	case len(f.Tenants) == 0:
+		problems = append(problems, "TENANTS_FILE lists no tenants")
+	case def == "":
+		def = f.Tenants[0].ID
+	case !seen[def]:
+		problems = append(problems, fmt.Sprintf("default tenant %q is not listed", def))
+	}
+	if len(problems) > 0 {
+		return nil, "", &ValidationError{Problems: problems}
+	}
+	return tenants, def, nil
+}
+
+// applyTenantSettings applies TENANTS_FILE settings. It accepts the secret
+// keys handled by ApplySecret plus the tenant's secret source and default ids,
+// and rejects anything else so typos do not silently fall back to the
+// service-wide value.
+func (c *Config) applyTenantSettings(m map[string]string) error {
+	strs := map[string]*string{
+		"SECRETS_MANAGER_TURVO_SECRET_NAME": &c.SecretsManagerTurvoSecretName,
+		"TURVO_SECRETS_FILE":                &c.TurvoSecretsFile,
+	}
+	ints := map[string]*int{
+		"TURVO_DEFAULT_CUSTOMER_ID":             &c.TurvoDefaultCustomerID,
+		"TURVO_DEFAULT_ORIGIN_LOCATION_ID":      &c.TurvoDefaultOriginLocationID,
+		"TURVO_DEFAULT_DESTINATION_LOCATION_ID": &c.TurvoDefaultDestinationLocationID,
+	}
+	var unknown []string
+	for k, v := range m {
+		switch {
+		case c.secretFields()[k] != nil:
+		case strs[k] != nil:
+			*strs[k] = v
+		case ints[k] != nil:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s %q is not a number", k, v)
+			}
+			*ints[k] = n
+		default:
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings %s", strings.Join(unknown, ", "))
+	}
+	c.ApplySecret(m)
+	return nil
+}
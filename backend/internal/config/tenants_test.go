@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tenantsConfig(t *testing.T, body string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := validConfig()
+	cfg.TurvoAPIPrefix = "" // unset, so each tenant gets its mode's default
+	cfg.normalize()
+	cfg.TenantsFile = path
+	return cfg
+}
+
+func TestTenantsWithoutFile(t *testing.T) {
+	cfg := validConfig()
+	tenants, def, err := cfg.Tenants(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != DefaultTenantID || len(tenants) != 1 || tenants[0].Config != cfg {
+		t.Fatalf("tenants = %+v, default %q", tenants, def)
+	}
+}
+
+func TestTenantsOverrideBase(t *testing.T) {
+	cfg := tenantsConfig(t, `{
+		"default": "west",
+		"tenants": [
+			{"id": "east", "settings": {"TURVO_CLIENT_ID": "east-id", "TURVO_DEFAULT_CUSTOMER_ID": "500"}},
+			{"id": "west", "settings": {"TURVO_AUTH_MODE": "api_key", "TURVO_API_KEY": "west-key"}}
+		]
+	}`)
+	tenants, def, err := cfg.Tenants(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != "west" || len(tenants) != 2 {
+		t.Fatalf("tenants = %+v, default %q", tenants, def)
+	}
+	east, west := tenants[0].Config, tenants[1].Config
+	if east.TurvoClientID != "east-id" || east.TurvoDefaultCustomerID != 500 || east.TurvoOAuthPassword != "pass" || east.TurvoTenant != "east" {
+		t.Errorf("east = %+v", east)
+	}
+	if west.TurvoAPIKey != "west-key" || west.TurvoAPIPrefix != "/public/v1" || west.TurvoClientID != "id" {
+		t.Errorf("west = %+v", west)
+	}
+	if cfg.TurvoClientID != "id" {
+		t.Error("tenant settings leaked into the base configuration")
+	}
+}
+
+func TestTenantsReportsAllProblems(t *testing.T) {
+	cfg := tenantsConfig(t, `{
+		"default": "north",
+		"tenants": [
+			{"id": "east", "settings": {"TURVO_CLEINT_ID": "typo"}},
+			{"id": "west", "settings": {"TURVO_AUTH_MODE": "api_key"}},
+			{"id": "west"}
+		]
+	}`)
+	_, _, err := cfg.Tenants(context.Background())
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want *ValidationError, got %v", err)
+	}
+	for _, want := range []string{"TURVO_CLEINT_ID", `tenant "west": TURVO_API_KEY is required`, "listed twice", `default tenant "north"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
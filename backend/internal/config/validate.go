@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+)
+
+// ValidationError lists every configuration problem found at startup.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// Validate checks that the settings are complete and well formed for the
+// selected auth mode. It reports all problems at once as a *ValidationError.
+// In demo mode Turvo settings are not required.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	require := func(name, value string) {
+		if strings.TrimSpace(value) == "" {
+			add("%s is required when TURVO_AUTH_MODE=%s", name, c.TurvoAuthMode)
+		}
+	}
+
+	if !c.DemoMode {
+		switch c.TurvoAuthMode {
+		case AuthOAuthPassword:
+			require("TURVO_CLIENT_ID", c.TurvoClientID)
+			require("TURVO_CLIENT_SECRET", c.TurvoClientSecret)
+			require("TURVO_USERNAME", c.TurvoOAuthUsername)
+			require("TURVO_PASSWORD", c.TurvoOAuthPassword)
+		case AuthOAuthClientCredentials:
+			require("TURVO_CLIENT_ID", c.TurvoClientID)
+			require("TURVO_CLIENT_SECRET", c.TurvoClientSecret)
+		case AuthAPIKey:
+			require("TURVO_API_KEY", c.TurvoAPIKey)
+		default:
+			add("TURVO_AUTH_MODE %q is not one of %s, %s, %s", c.TurvoAuthMode, AuthOAuthPassword, AuthOAuthClientCredentials, AuthAPIKey)
+		}
+		if err := checkHTTPURL(c.TurvoBaseURL); err != nil {
+			add("TURVO_BASE_URL %q %v", c.TurvoBaseURL, err)
+		}
+		if strings.ContainsAny(c.TurvoAPIPrefix, "?# ") {
+			add("TURVO_API_PREFIX %q must be a plain path such as /v1", c.TurvoAPIPrefix)
+		}
+	}
+
+	if len(c.AllowedOrigins) == 0 {
+		add("ALLOWED_ORIGINS must list at least one origin or *")
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		if err := checkOrigin(o); err != nil {
+			add("ALLOWED_ORIGINS entry %q %v", o, err)
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		add("LOG_LEVEL %q is not one of debug, info, warn, error", c.LogLevel)
+	}
+	for _, f := range []struct {
+		name string
+		v    int
+	}{
+		{"TURVO_DEFAULT_CUSTOMER_ID", c.TurvoDefaultCustomerID},
+		{"TURVO_DEFAULT_ORIGIN_LOCATION_ID", c.TurvoDefaultOriginLocationID},
+		{"TURVO_DEFAULT_DESTINATION_LOCATION_ID", c.TurvoDefaultDestinationLocationID},
+	} {
+		if f.v < 0 {
+			add("%s must not be negative", f.name)
+		}
+	}
+
+	if c.AuthRequired && c.OIDCIssuer == "" && len(c.APIKeys) == 0 {
+		add("AUTH_REQUIRED is set but neither OIDC_ISSUER nor API_KEYS is configured; set AUTH_REQUIRED=false to run without authentication")
+	}
+	if c.OIDCIssuer != "" {
+		if err := checkHTTPURL(c.OIDCIssuer); err != nil {
+			add("OIDC_ISSUER %q %v", c.OIDCIssuer, err)
+		}
+		// without an audience, tokens the issuer gives other clients pass
+		if c.OIDCAudience == "" {
+			add("OIDC_AUDIENCE is required with OIDC_ISSUER")
+		}
+	} else if c.OIDCJWKSURL != "" || c.OIDCJWKSFile != "" {
+		add("OIDC_JWKS_URL and OIDC_JWKS_FILE require OIDC_ISSUER")
+	}
+	if c.OIDCJWKSURL != "" && c.OIDCJWKSFile != "" {
+		add("set only one of OIDC_JWKS_URL and OIDC_JWKS_FILE")
+	}
+	if c.OIDCJWKSURL != "" {
+		if err := checkHTTPURL(c.OIDCJWKSURL); err != nil {
+			add("OIDC_JWKS_URL %q %v", c.OIDCJWKSURL, err)
+		}
+	}
+	if _, err := auth.ParseAPIKeys(c.APIKeys); err != nil {
+		// the error names the key but never its value
+		add("API_KEYS: %v", err)
+	}
+	if c.SecretsRefreshInterval < 0 {
+		add("SECRETS_REFRESH_INTERVAL must not be negative")
+	}
+	if c.IdempotencyTTL < 0 {
+		add("IDEMPOTENCY_TTL must not be negative")
+	}
+	if c.CursorSecret != "" && len(c.CursorSecret) < 32 {
+		add("CURSOR_SECRET must be at least 32 characters")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// checkHTTPURL requires an absolute http(s) URL with a host.
+func checkHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must start with http:// or https://")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("has no host")
+	}
+	return nil
+}
+
+// checkOrigin requires scheme://host[:port] with no path, allowing one
+// wildcard subdomain such as https://*.example.com.
+func checkOrigin(o string) error {
+	if err := checkHTTPURL(strings.Replace(o, "*.", "", 1)); err != nil {
+		return err
+	}
+	u, _ := url.Parse(strings.Replace(o, "*.", "", 1))
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("must be scheme://host[:port] without a path")
+	}
+	if strings.HasSuffix(o, "/") {
+		return fmt.Errorf("must not end with /")
+	}
+	return nil
+}
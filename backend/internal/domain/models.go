@@ -11,6 +11,7 @@ type Load struct {
 	BillTo            *Party          `json:"billTo,omitempty"`
 	Pickup            Stop            `json:"pickup"`
 	Consignee         Stop            `json:"consignee"`
+	Stops             []Stop          `json:"stops,omitempty"` // ordered route; overrides pickup/consignee when set
 	Carrier           *Carrier        `json:"carrier,omitempty"`
 	RateData          *RateData       `json:"rateData,omitempty"`
 	Specifications    *Specifications `json:"specifications,omitempty"`
@@ -61,9 +62,20 @@ type Stop struct {
 	ApptNote      string     `json:"apptNote,omitempty"`
 	Timezone      string     `json:"timezone,omitempty"`
 	WarehouseId   string     `json:"warehouseId,omitempty"`
+	// Multi-stop fields (used in Load.Stops)
+	StopType        string   `json:"stopType,omitempty"` // pickup | delivery
+	TurvoLocationID int      `json:"turvoLocationId,omitempty"`
+	PoNumbers       []string `json:"poNumbers,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
 }
 
+const (
+	StopTypePickup   = "pickup"
+	StopTypeDelivery = "delivery"
+)
+
 type Carrier struct {
+	TurvoID                  int        `json:"turvoId,omitempty"`
 	MCNumber                 string     `json:"mcNumber,omitempty"`
 	DOTNumber                string     `json:"dotNumber,omitempty"`
 	Name                     string     `json:"name,omitempty"`
@@ -107,7 +119,17 @@ type RateData struct {
 	ProfitPercent     float64 `json:"profitPercent,omitempty"`
 }
 
+// Rate types for CustomerRateType and CarrierRateType.
+const (
+	RateTypeFlat    = "flat"
+	RateTypePerMile = "per_mile"
+	RateTypeHourly  = "hourly"
+)
+
 type Specifications struct {
+	// Reefer marks a temperature-controlled load; the temperatures only
+	// apply to reefer loads, and 0°F is a valid set point.
+	Reefer            bool    `json:"reefer,omitempty"`
 	MinTempFahrenheit float64 `json:"minTempFahrenheit,omitempty"`
 	MaxTempFahrenheit float64 `json:"maxTempFahrenheit,omitempty"`
 	LiftgatePickup    bool    `json:"liftgatePickup,omitempty"`
@@ -132,3 +154,24 @@ type Specifications struct {
 	Operator          string  `json:"operator,omitempty"`
 	RouteMiles        float64 `json:"routeMiles,omitempty"`
 }
+
+type Order struct {
+	TurvoID        int         `json:"turvoId,omitempty"`
+	ExternalID     string      `json:"externalId"`
+	Status         string      `json:"status,omitempty"`
+	Customer       Party       `json:"customer"`
+	Origin         Stop        `json:"origin"`
+	Destination    Stop        `json:"destination"`
+	Items          []OrderItem `json:"items,omitempty"`
+	CarrierTurvoID int         `json:"carrierTurvoId,omitempty"`
+	CarrierName    string      `json:"carrierName,omitempty"`
+	ShipmentIDs    []int       `json:"shipmentIds,omitempty"` // linked Turvo shipments
+}
+
+type OrderItem struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Units    string  `json:"units,omitempty"`
+	Weight   float64 `json:"weight,omitempty"` // per unit, pounds
+	Notes    string  `json:"notes,omitempty"`
+}
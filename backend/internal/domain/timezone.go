@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the zone database so stop timezones resolve in minimal images.
+	_ "time/tzdata"
+)
+
+// stateZones holds the predominant IANA zone for each US state.
+var stateZones = map[string]string{
+	"AL": "America/Chicago", "AK": "America/Anchorage", "AZ": "America/Phoenix", "AR": "America/Chicago",
+	"CA": "America/Los_Angeles", "CO": "America/Denver", "CT": "America/New_York", "DC": "America/New_York",
+	"DE": "America/New_York", "FL": "America/New_York", "GA": "America/New_York", "HI": "Pacific/Honolulu",
+	"ID": "America/Boise", "IL": "America/Chicago", "IN": "America/Indiana/Indianapolis", "IA": "America/Chicago",
+	"KS": "America/Chicago", "KY": "America/New_York", "LA": "America/Chicago", "ME": "America/New_York",
+	"MD": "America/New_York", "MA": "America/New_York", "MI": "America/Detroit", "MN": "America/Chicago",
+	"MS": "America/Chicago", "MO": "America/Chicago", "MT": "America/Denver", "NE": "America/Chicago",
+	"NV": "America/Los_Angeles", "NH": "America/New_York", "NJ": "America/New_York", "NM": "America/Denver",
+	"NY": "America/New_York", "NC": "America/New_York", "ND": "America/Chicago", "OH": "America/New_York",
+	"OK": "America/Chicago", "OR": "America/Los_Angeles", "PA": "America/New_York", "PR": "America/Puerto_Rico",
+	"RI": "America/New_York", "SC": "America/New_York", "SD": "America/Chicago", "TN": "America/Chicago",
+	"TX": "America/Chicago", "UT": "America/Denver", "VT": "America/New_York", "VA": "America/New_York",
+	"WA": "America/Los_Angeles", "WV": "America/New_York", "WI": "America/Chicago", "WY": "America/Denver",
+}
+
+// zipRange maps an inclusive range of 3-digit zip prefixes to a value.
+type zipRange struct {
+	lo, hi int
+	value  string
+}
+
+// zipZones overrides the state zone for prefixes in states split across zones.
+var zipZones = []zipRange{
+	{324, 325, "America/Chicago"},     // Florida panhandle
+	{370, 374, "America/Chicago"},     // Middle Tennessee (Chattanooga 373-374 below)
+	{373, 374, "America/New_York"},    // Chattanooga
+	{376, 379, "America/New_York"},    // East Tennessee
+	{420, 424, "America/Chicago"},     // Western Kentucky
+	{463, 464, "America/Chicago"},     // Northwest Indiana
+	{476, 477, "America/Chicago"},     // Southwest Indiana
+	{498, 499, "America/Menominee"},   // Michigan Upper Peninsula (west)
+	{577, 577, "America/Denver"},      // Western South Dakota
+	{586, 588, "America/Denver"},      // Southwest North Dakota
+	{677, 679, "America/Denver"},      // Western Kansas
+	{690, 693, "America/Denver"},      // Western Nebraska
+	{798, 799, "America/Denver"},      // El Paso, Texas
+	{835, 838, "America/Los_Angeles"}, // North Idaho
+}
+
+// zipStates maps 3-digit zip prefixes to states for stops with no state.
+var zipStates = []zipRange{
+	{6, 9, "PR"}, {10, 27, "MA"}, {28, 29, "RI"}, {30, 38, "NH"}, {39, 49, "ME"}, {50, 59, "VT"},
+	{60, 69, "CT"}, {70, 89, "NJ"}, {100, 149, "NY"}, {150, 196, "PA"}, {197, 199, "DE"},
+	{200, 205, "DC"}, {206, 219, "MD"}, {220, 246, "VA"}, {247, 268, "WV"}, {270, 289, "NC"},
+	{290, 299, "SC"}, {300, 319, "GA"}, {320, 349, "FL"}, {350, 369, "AL"}, {370, 385, "TN"},
+	{386, 397, "MS"}, {398, 399, "GA"}, {400, 427, "KY"}, {430, 459, "OH"}, {460, 479, "IN"},
+	{480, 499, "MI"}, {500, 528, "IA"}, {530, 549, "WI"}, {550, 567, "MN"}, {570, 577, "SD"},
+	{580, 588, "ND"}, {590, 599, "MT"}, {600, 629, "IL"}, {630, 658, "MO"}, {660, 679, "KS"},
+	{680, 693, "NE"}, {700, 714, "LA"}, {716, 729, "AR"}, {730, 749, "OK"}, {750, 799, "TX"},
+	{800, 816, "CO"}, {820, 831, "WY"}, {832, 838, "ID"}, {840, 847, "UT"}, {850, 865, "AZ"},
+	{870, 884, "NM"}, {889, 898, "NV"}, {900, 961, "CA"}, {967, 968, "HI"}, {970, 979, "OR"},
+	{980, 994, "WA"}, {995, 999, "AK"},
+}
+
+// Location resolves the IANA zone of the stop from its Timezone field, then
+// its zip code, then its state. It falls back to UTC.
+func (st Stop) Location() *time.Location {
+	if tz := strings.TrimSpace(st.Timezone); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	state := strings.ToUpper(strings.TrimSpace(st.State))
+	if z, ok := zip3(st.Zipcode); ok {
+		if state == "" {
+			state = lookupZip(zipStates, z)
+		}
+		// later override entries are more specific, so the last match wins
+		if name := lookupZip(zipZones, z); name != "" && stateOfZip(z) == state {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc
+			}
+		}
+	}
+	if name, ok := stateZones[state]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+func zip3(zip string) (int, bool) {
+	zip = strings.TrimSpace(zip)
+	if len(zip) < 3 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(zip[:3])
+	return n, err == nil
+}
+
+func lookupZip(ranges []zipRange, z int) string {
+	found := ""
+	for _, r := range ranges {
+		if z >= r.lo && z <= r.hi {
+			found = r.value
+		}
+	}
+	return found
+}
+
+func stateOfZip(z int) string {
+	return lookupZip(zipStates, z)
+}
+
+// localLayouts are the accepted stop time forms without a UTC offset.
+var localLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", time.DateOnly}
+
+// ParseStopTime parses an RFC 3339 time, or a wall-clock time without an
+// offset ("2006-01-02T15:04", "2006-01-02 15:04" or a bare date), which is
+// taken as local to loc. An explicit offset is kept as given.
+func ParseStopTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time (use RFC 3339, 2006-01-02T15:04 or 2006-01-02)", s)
+}
+
+// UnmarshalJSON reads the stop's times with ParseStopTime, so a client can
+// send the wall-clock time at the facility without knowing its offset.
+func (st *Stop) UnmarshalJSON(b []byte) error {
+	type plain Stop
+	var raw struct {
+		*plain
+		ReadyTime   *string `json:"readyTime"`
+		MustDeliver *string `json:"mustDeliver"`
+		ApptTime    *string `json:"apptTime"`
+	}
+	raw.plain = (*plain)(st)
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	loc := st.Location()
+	for _, f := range []struct {
+		name string
+		raw  *string
+		dst  **time.Time
+	}{
+		{"readyTime", raw.ReadyTime, &st.ReadyTime},
+		{"mustDeliver", raw.MustDeliver, &st.MustDeliver},
+		{"apptTime", raw.ApptTime, &st.ApptTime},
+	} {
+		if f.raw == nil {
+			continue
+		}
+		if *f.raw == "" {
+			*f.dst = nil
+			continue
+		}
+		t, err := ParseStopTime(*f.raw, loc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.dst = &t
+	}
+	return nil
+}
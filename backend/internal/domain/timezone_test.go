@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStopLocation(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stop Stop
+		want string
+	}{
+		{"explicit zone wins", Stop{Timezone: "America/Denver", State: "IL"}, "America/Denver"},
+		{"unknown zone falls back to state", Stop{Timezone: "Mars/Base", State: "IL"}, "America/Chicago"},
+		{"state", Stop{State: "ny"}, "America/New_York"},
+		{"state from zip", Stop{Zipcode: "60601"}, "America/Chicago"},
+		{"Florida panhandle", Stop{State: "FL", Zipcode: "32501"}, "America/Chicago"},
+		{"Florida peninsula", Stop{State: "FL", Zipcode: "33101"}, "America/New_York"},
+		{"El Paso", Stop{State: "TX", Zipcode: "79901"}, "America/Denver"},
+		{"Dallas", Stop{State: "TX", Zipcode: "75201"}, "America/Chicago"},
+		{"Chattanooga", Stop{State: "TN", Zipcode: "37402"}, "America/New_York"},
+		{"Nashville", Stop{State: "TN", Zipcode: "37201"}, "America/Chicago"},
+		{"North Idaho", Stop{Zipcode: "83814"}, "America/Los_Angeles"},
+		{"zip of another state is ignored", Stop{State: "TX", Zipcode: "32501"}, "America/Chicago"},
+		{"nothing known", Stop{}, "UTC"},
+	} {
+		if got := tc.stop.Location().String(); got != tc.want {
+			t.Errorf("%s: Location() = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseStopTime(t *testing.T) {
+	chicago, _ := time.LoadLocation("America/Chicago")
+	for in, want := range map[string]time.Time{
+		"2026-03-02T08:00":          time.Date(2026, 3, 2, 8, 0, 0, 0, chicago),
+		"2026-03-02 08:00":          time.Date(2026, 3, 2, 8, 0, 0, 0, chicago),
+		"2026-03-02T08:00:30":       time.Date(2026, 3, 2, 8, 0, 30, 0, chicago),
+		"2026-03-02":                time.Date(2026, 3, 2, 0, 0, 0, 0, chicago),
+		"2026-03-02T13:00:00Z":      time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC),
+		"2026-03-02T08:00:00-05:00": time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseStopTime(in, chicago)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseStopTime(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseStopTime("tomorrow", chicago); err == nil {
+		t.Error("want an error for an unparseable time")
+	}
+}
+
+func TestStopUnmarshalUsesStopZone(t *testing.T) {
+	var st Stop
+	err := json.Unmarshal([]byte(`{"readyTime":"2026-03-02T08:00","apptTime":"2026-03-02T14:00:00Z","city":"Chicago","state":"IL"}`), &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ReadyTime == nil || st.ReadyTime.Format(time.RFC3339) != "2026-03-02T08:00:00-06:00" {
+		t.Errorf("readyTime = %v, want 8am Central", st.ReadyTime)
+	}
+	if st.ApptTime == nil || !st.ApptTime.Equal(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("apptTime = %v, want the instant as sent", st.ApptTime)
+	}
+	if st.City != "Chicago" || st.MustDeliver != nil {
+		t.Errorf("stop = %+v", st)
+	}
+	if err := json.Unmarshal([]byte(`{"readyTime":"soon"}`), &st); err == nil {
+		t.Error("want an error for a bad readyTime")
+	}
+}
@@ -0,0 +1,273 @@
+// Package dynamotest provides an in-memory DynamoDB table for testing the
+// stores that keep shared state in DynamoDB.
+package dynamotest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Table fakes the single-item calls of dynamodbiface.DynamoDBAPI for one
+// table with a string partition key. Condition expressions may use =, <>,
+// <, <=, >, >=, attribute_exists, attribute_not_exists, AND, OR, NOT and
+// parentheses; update expressions may use SET. Other calls panic.
+type Table struct {
+	dynamodbiface.DynamoDBAPI
+	// KeyName is the partition key attribute.
+	KeyName string
+
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+// New returns an empty Table keyed by keyName.
+func New(keyName string) *Table {
+	return &Table{KeyName: keyName, items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+// Item returns a copy of the item stored under key, or nil.
+func (t *Table) Item(key string) map[string]*dynamodb.AttributeValue {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return copyItem(t.items[key])
+}
+
+// PutItemWithContext implements dynamodbiface.DynamoDBAPI.
+func (t *Table) PutItemWithContext(_ aws.Context, in *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := aws.StringValue(in.Item[t.KeyName].S)
+	if err := t.check(key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	t.items[key] = copyItem(in.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// GetItemWithContext implements dynamodbiface.DynamoDBAPI.
+func (t *Table) GetItemWithContext(_ aws.Context, in *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: copyItem(t.items[aws.StringValue(in.Key[t.KeyName].S)])}, nil
+}
+
+// UpdateItemWithContext implements dynamodbiface.DynamoDBAPI. Like
+// DynamoDB, it creates the item when none exists and the condition allows.
+func (t *Table) UpdateItemWithContext(_ aws.Context, in *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := aws.StringValue(in.Key[t.KeyName].S)
+	if err := t.check(key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	item := copyItem(t.items[key])
+	if item == nil {
+		item = copyItem(in.Key)
+	}
+	expr := strings.TrimSpace(aws.StringValue(in.UpdateExpression))
+	if !strings.HasPrefix(expr, "SET ") {
+		panic("dynamotest: unsupported update expression " + expr)
+	}
+	for _, set := range strings.Split(strings.TrimPrefix(expr, "SET "), ",") {
+		name, value, ok := strings.Cut(set, "=")
+		if !ok {
+			panic("dynamotest: unsupported update expression " + expr)
+		}
+		item[resolveName(strings.TrimSpace(name), in.ExpressionAttributeNames)] = copyValue(in.ExpressionAttributeValues[strings.TrimSpace(value)])
+	}
+	t.items[key] = item
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// DeleteItemWithContext implements dynamodbiface.DynamoDBAPI.
+func (t *Table) DeleteItemWithContext(_ aws.Context, in *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := aws.StringValue(in.Key[t.KeyName].S)
+	if err := t.check(key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	delete(t.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// check evaluates a condition against the item under key and returns the
+// error DynamoDB gives when it fails.
+func (t *Table) check(key string, cond *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+	if cond == nil {
+		return nil
+	}
+	p := &parser{tokens: tokenRe.FindAllString(*cond, -1), names: names, values: values, item: t.items[key]}
+	ok := p.or()
+	if p.pos != len(p.tokens) {
+		panic("dynamotest: unsupported condition " + *cond)
+	}
+	if !ok {
+		return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	return nil
+}
+
+var tokenRe = regexp.MustCompile(`\(|\)|<>|<=|>=|=|<|>|[#:]?[A-Za-z0-9_]+`)
+
+// parser evaluates a condition expression as it parses it.
+type parser struct {
+	tokens []string
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+	item   map[string]*dynamodb.AttributeValue
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *parser) expect(tok string) {
+	if got := p.next(); got != tok {
+		panic(fmt.Sprintf("dynamotest: want %q in condition, got %q", tok, got))
+	}
+}
+
+func (p *parser) or() bool {
+	ok := p.and()
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		ok = p.and() || ok
+	}
+	return ok
+}
+
+func (p *parser) and() bool {
+	ok := p.unary()
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		ok = p.unary() && ok
+	}
+	return ok
+}
+
+func (p *parser) unary() bool {
+	switch tok := p.next(); {
+	case tok == "(":
+		ok := p.or()
+		p.expect(")")
+		return ok
+	case strings.EqualFold(tok, "NOT"):
+		return !p.unary()
+	case tok == "attribute_exists", tok == "attribute_not_exists":
+		p.expect("(")
+		_, exists := p.item[resolveName(p.next(), p.names)]
+		p.expect(")")
+		return exists == (tok == "attribute_exists")
+	default:
+		left := p.operand(tok)
+		op := p.next()
+		right := p.operand(p.next())
+		return compare(left, op, right)
+	}
+}
+
+func (p *parser) operand(tok string) *dynamodb.AttributeValue {
+	if strings.HasPrefix(tok, ":") {
+		v, ok := p.values[tok]
+		if !ok {
+			panic("dynamotest: no value for " + tok)
+		}
+		return v
+	}
+	return p.item[resolveName(tok, p.names)]
+}
+
+func resolveName(tok string, names map[string]*string) string {
+	if strings.HasPrefix(tok, "#") {
+		n, ok := names[tok]
+		if !ok {
+			panic("dynamotest: no name for " + tok)
+		}
+		return aws.StringValue(n)
+	}
+	return tok
+}
+
+// compare applies op to a and b. Like DynamoDB, a comparison involving a
+// missing attribute or values of different types is false.
+func compare(a *dynamodb.AttributeValue, op string, b *dynamodb.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	var c int
+	switch {
+	case a.N != nil && b.N != nil:
+		x, _ := strconv.ParseFloat(*a.N, 64)
+		y, _ := strconv.ParseFloat(*b.N, 64)
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	case a.S != nil && b.S != nil:
+		c = strings.Compare(*a.S, *b.S)
+	case a.B != nil && b.B != nil:
+		c = bytes.Compare(a.B, b.B)
+	default:
+		return false
+	}
+	switch op {
+	case "=":
+		return c == 0
+	case "<>":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	panic("dynamotest: unsupported comparison " + op)
+}
+
+// copyItem copies item so that, as with the real service, later changes on
+// either side of a call are not shared.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	out := make(map[string]*dynamodb.AttributeValue, len(item))
+	for k, v := range item {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	c.B = bytes.Clone(v.B)
+	return &c
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+)
+
+// SecretStatusReporter reports the state of live secret rotation.
+// config.SecretRefresher implements it.
+type SecretStatusReporter interface {
+	Status() config.SecretStatus
+}
+
+// AdminHandler exposes operational endpoints under /admin.
+type AdminHandler struct {
+	// Secrets is nil when credentials come only from the environment.
+	Secrets SecretStatusReporter
+	// Policy guards each route; nil allows everything.
+	Policy *authz.Policy
+}
+
+// NewAdminHandler returns an AdminHandler reporting on secrets.
+func NewAdminHandler(secrets SecretStatusReporter) *AdminHandler {
+	return &AdminHandler{Secrets: secrets}
+}
+
+// RegisterRoutes mounts GET /admin/secrets.
+func (h *AdminHandler) RegisterRoutes(r *chi.Mux) {
+	r.With(require(h.Policy, authz.Admin)).Get("/admin/secrets", h.SecretStatus)
+}
+
+// SecretStatus returns the secret source and the time of the last successful
+// refresh. Secret values are never included.
+func (h *AdminHandler) SecretStatus(w http.ResponseWriter, r *http.Request) {
+	if h.Secrets == nil {
+		writeError(w, http.StatusNotFound, codeNotFound, "no secret provider configured")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Secrets.Status())
+}
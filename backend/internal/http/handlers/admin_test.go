@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+)
+
+type fixedStatus config.SecretStatus
+
+func (s fixedStatus) Status() config.SecretStatus { return config.SecretStatus(s) }
+
+func TestSecretStatus(t *testing.T) {
+	r := chi.NewRouter()
+	NewAdminHandler(nil).RegisterRoutes(r)
+	if rec := serve(r, http.MethodGet, "/admin/secrets", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("without provider: status = %d", rec.Code)
+	}
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r = chi.NewRouter()
+	NewAdminHandler(fixedStatus{Source: "file:/tmp/turvo.json", LastRefresh: &at, Rotations: 2}).RegisterRoutes(r)
+	rec := serve(r, http.MethodGet, "/admin/secrets", nil)
+	var got config.SecretStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got.LastRefresh == nil || !got.LastRefresh.Equal(at) || got.Rotations != 2 {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/logging"
+)
+
+// Authenticate returns middleware that stores the caller's identity in the
+// request context and the access log. Invalid credentials get 401, as do
+// missing credentials when required is set; otherwise anonymous requests pass
+// through without an identity.
+func Authenticate(a *auth.Authenticator, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := a.Authenticate(r)
+			if errors.Is(err, auth.ErrNoCredentials) && !required {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				msg := "authentication required"
+				if !errors.Is(err, auth.ErrNoCredentials) {
+					msg = "invalid credentials"
+					slog.WarnContext(r.Context(), "Authentication failed", "error", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="drumkit"`)
+				writeError(w, http.StatusUnauthorized, codeUnauthorized, msg)
+				return
+			}
+			logging.AddRequestAttrs(r.Context(), slog.String("caller", id.Subject), slog.String("auth_method", id.Method))
+			next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), id)))
+		})
+	}
+}
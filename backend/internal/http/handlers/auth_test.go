@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+)
+
+func TestAuthenticateMiddleware(t *testing.T) {
+	keys, _ := auth.ParseAPIKeys([]string{"svc:0123456789abcdef"})
+	a := auth.NewAuthenticator(nil, keys)
+	var caller string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := auth.FromContext(r.Context())
+		caller = id.Subject
+	})
+
+	for _, tc := range []struct {
+		name     string
+		required bool
+		key      string
+		want     int
+		caller   string
+	}{
+		{"required, missing", true, "", http.StatusUnauthorized, ""},
+		{"required, valid", true, "0123456789abcdef", http.StatusOK, "svc"},
+		{"optional, missing", false, "", http.StatusOK, ""},
+		{"optional, invalid", false, "wrong-key-value!", http.StatusUnauthorized, ""},
+	} {
+		caller = ""
+		rec := serve(Authenticate(a, tc.required)(next), http.MethodGet, "/api/loads", nil, auth.APIKeyHeader, tc.key)
+		if rec.Code != tc.want || caller != tc.caller {
+			t.Errorf("%s: status = %d caller %q, want %d %q", tc.name, rec.Code, caller, tc.want, tc.caller)
+		}
+		if rec.Code == http.StatusUnauthorized && decodeError(t, rec).Code != codeUnauthorized {
+			t.Errorf("%s: body %s", tc.name, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// countingLocations counts the location lookups and creates that reach
+// Turvo.
+type countingLocations struct {
+	turvo.LocationDirectory
+	calls atomic.Int32
+}
+
+func (c *countingLocations) ListLocations(ctx context.Context, q url.Values) ([]turvo.LocationRecord, error) {
+	c.calls.Add(1)
+	return c.LocationDirectory.ListLocations(ctx, q)
+}
+
+func (c *countingLocations) CreateLocation(ctx context.Context, loc turvo.LocationRecord) (*turvo.LocationRecord, error) {
+	c.calls.Add(1)
+	return c.LocationDirectory.CreateLocation(ctx, loc)
+}
+
+func TestPolicyDeniesMissingPermission(t *testing.T) {
+	r := newLoadRouter(memstore.New(), withPolicy)
+	rec := serve(asCaller(r, "viewer"), http.MethodPost, "/api/loads", customerLoad("V-1", 7))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if e := decodeError(t, rec); e.Code != codeForbidden || e.Permission != string(authz.LoadsCreate) {
+		t.Errorf("error = %+v", e)
+	}
+	if rec := serve(asCaller(r, "viewer"), http.MethodGet, "/api/loads", nil); rec.Code != http.StatusOK {
+		t.Errorf("viewer list status = %d", rec.Code)
+	}
+	if rec := serve(r, http.MethodGet, "/api/loads", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("anonymous list status = %d", rec.Code)
+	}
+}
+
+func TestPolicyRestrictsRepToAssignedCustomers(t *testing.T) {
+	r := newLoadRouter(memstore.New(), withPolicy)
+	rep := asCaller(r, "rep")
+
+	if rec := serve(rep, http.MethodPost, "/api/loads", customerLoad("R-1", 7)); rec.Code != http.StatusCreated {
+		t.Fatalf("own customer status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec := serve(rep, http.MethodPost, "/api/loads", customerLoad("R-2", 8))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("other customer status = %d, body %s", rec.Code, rec.Body)
+	}
+	if e := decodeError(t, rec); e.CustomerID != 8 || e.Permission != string(authz.LoadsCreate) {
+		t.Errorf("error = %+v", e)
+	}
+	// default customer 500 applies when the payload names none
+	if rec := serve(rep, http.MethodPost, "/api/loads", testLoad("R-3")); rec.Code != http.StatusForbidden {
+		t.Errorf("default customer status = %d", rec.Code)
+	}
+
+	serve(asCaller(r, "dispatcher"), http.MethodPost, "/api/loads", customerLoad("D-1", 8))
+	if ids := listExternalIDs(t, serve(rep, http.MethodGet, "/api/loads", nil)); !slices.Equal(ids, []string{"R-1"}) {
+		t.Errorf("rep list = %v", ids)
+	}
+	if ids := listExternalIDs(t, serve(asCaller(r, "viewer"), http.MethodGet, "/api/loads", nil)); len(ids) != 2 {
+		t.Errorf("viewer list = %v", ids)
+	}
+	if rec := serve(rep, http.MethodGet, "/api/loads?customerId[eq]=8", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("filter on other customer status = %d", rec.Code)
+	}
+	if rec := serve(rep, http.MethodGet, "/api/loads/by-external/D-1", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("get other customer's load status = %d", rec.Code)
+	}
+}
+
+func TestPolicyRepWithoutCustomers(t *testing.T) {
+	r := newLoadRouter(memstore.New(), withPolicy)
+	req := httptest.NewRequest(http.MethodGet, "/api/customers", nil)
+	id := auth.Identity{Subject: "new-rep", Claims: map[string]any{"roles": "rep"}}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req.WithContext(auth.WithIdentity(req.Context(), id)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPolicyDeniesBeforeResolvingLocations(t *testing.T) {
+	store := memstore.New()
+	locations := &countingLocations{LocationDirectory: store}
+	r := newLoadRouter(store, withPolicy, func(h *LoadHandler) { h.Locations = turvo.NewLocationResolver(locations) })
+	rep, manager := asCaller(r, "rep"), asCaller(r, "manager")
+
+	if rec := serve(rep, http.MethodPost, "/api/loads", customerLoad("D-1", 8)); rec.Code != http.StatusForbidden {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	bulk := `[{"externalTMSLoadID":"D-2","customer":{"turvoId":8},"pickup":{"name":"Acme DC","addressLine1":"1 Main St","city":"Chicago","state":"IL"}}]`
+	rec := serve(rep, http.MethodPost, "/api/loads/bulk", bulk)
+	if resp := decodeBulk(t, rec); len(resp.Results) != 1 || resp.Results[0].StatusCode != http.StatusForbidden {
+		t.Fatalf("bulk status = %d, body %s", rec.Code, rec.Body)
+	}
+	if n := locations.calls.Load(); n != 0 {
+		t.Fatalf("denied creates made %d location calls", n)
+	}
+
+	if rec := serve(manager, http.MethodPost, "/api/loads", customerLoad("D-3", 7)); rec.Code != http.StatusCreated {
+		t.Fatalf("allowed create status = %d, body %s", rec.Code, rec.Body)
+	}
+	created, err := store.FindShipmentByExternalID(context.Background(), "D-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(created.ID)
+	before := locations.calls.Load()
+	move := map[string]any{"customer": map[string]any{"turvoId": 8}, "pickup": map[string]any{"name": "Other DC", "addressLine1": "5 Oak St", "city": "Joliet", "state": "IL"}}
+	if rec := serve(manager, http.MethodPut, "/api/loads/"+id, move); rec.Code != http.StatusForbidden {
+		t.Fatalf("update status = %d, body %s", rec.Code, rec.Body)
+	}
+	if n := locations.calls.Load() - before; n != 0 {
+		t.Fatalf("denied update made %d location calls", n)
+	}
+}
+
+func TestDuplicateHidesLoadsCallerCannotRead(t *testing.T) {
+	r := newLoadRouter(memstore.New(), withPolicy)
+	if rec := serve(asCaller(r, "dispatcher"), http.MethodPost, "/api/loads", customerLoad("HID-1", 8)); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	rep := asCaller(r, "rep")
+
+	rec := serve(rep, http.MethodPost, "/api/loads", customerLoad("HID-1", 7))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, body %s", rec.Code, rec.Body)
+	}
+	if e := decodeError(t, rec); e.Code != codeDuplicateLoad || e.Existing != nil {
+		t.Errorf("error = %+v", e)
+	}
+	rec = serve(rep, http.MethodPost, "/api/loads/bulk", []domain.Load{customerLoad("HID-1", 7)})
+	if resp := decodeBulk(t, rec); len(resp.Results) != 1 || resp.Results[0].Error == nil ||
+		resp.Results[0].Error.Code != codeDuplicateLoad || resp.Results[0].Error.Existing != nil {
+		t.Errorf("bulk body %s", rec.Body)
+	}
+
+	// a caller who may read it is still pointed at it
+	rec = serve(asCaller(r, "dispatcher"), http.MethodPost, "/api/loads", customerLoad("HID-1", 8))
+	if e := decodeError(t, rec); e.Existing == nil || e.Existing.ExternalTMSLoadID != "HID-1" {
+		t.Errorf("dispatcher error = %+v", e)
+	}
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/jobs"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+const (
+	maxBulkBody = 5 << 20
+	maxBulkRows = 1000
+	// bulkSyncRows is the largest upload answered within the request; larger
+	// ones, or any with ?async=true, run as a job the client polls.
+	bulkSyncRows = 25
+	// bulkJobSaveInterval is how often a running job's progress is written
+	// to the job store; its final state is always written.
+	bulkJobSaveInterval = time.Second
+	// DefaultBulkConcurrency is how many creates a bulk upload runs at once.
+	// The Turvo client still retries 429s, honouring Retry-After.
+	DefaultBulkConcurrency = 4
+)
+
+// Row outcomes in a bulk result.
+const (
+	bulkCreated = "created"
+	bulkValid   = "valid" // passed validation in a dry run
+	bulkFailed  = "failed"
+)
+
+// bulkResult is the outcome of one upload row. Row is 1-based and counts
+// data rows only, not the CSV header. StatusCode is the HTTP status a single
+// create of the row would have returned.
+type bulkResult struct {
+	Row               int          `json:"row"`
+	ExternalTMSLoadID string       `json:"externalTMSLoadID,omitempty"`
+	Status            string       `json:"status"`
+	StatusCode        int          `json:"statusCode,omitempty"`
+	ID                int          `json:"id,omitempty"`
+	Load              *domain.Load `json:"load,omitempty"`
+	Error             *errorBody   `json:"error,omitempty"`
+}
+
+// bulkJob tracks one upload. Results is filled in as rows finish. Async
+// jobs are saved to the job store under key so any instance can answer
+// polls; key is empty for uploads answered within the request.
+type bulkJob struct {
+	ID    string
+	owner string
+	key   string
+
+	mu        sync.Mutex
+	done      bool
+	processed int
+	results   []bulkResult
+
+	saveMu sync.Mutex
+	saved  time.Time
+}
+
+func (j *bulkJob) set(i int, res bulkResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results[i] = res
+	j.processed++
+}
+
+func (j *bulkJob) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done = true
+}
+
+// MarshalJSON reports progress and, once done, the per-row results with
+// counts by outcome.
+func (j *bulkJob) MarshalJSON() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	out := map[string]any{
+		"jobId":     j.ID,
+		"status":    "running",
+		"total":     len(j.results),
+		"processed": j.processed,
+	}
+	if j.done {
+		counts := map[string]int{bulkCreated: 0, bulkFailed: 0}
+		for _, r := range j.results {
+			counts[r.Status]++
+		}
+		out["status"] = "done"
+		out["counts"] = counts
+		out["results"] = j.results
+	}
+	return json.Marshal(out)
+}
+
+// storedBulkJob is a job as kept in the job store.
+type storedBulkJob struct {
+	Owner string          `json:"owner"`
+	Job   json.RawMessage `json:"job"`
+}
+
+// bulkJobKey scopes a job id to the request's tenant in the job store.
+func bulkJobKey(r *http.Request, jobID string) string {
+	var tenantID string
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	return "bulk:" + tenantID + ":" + jobID
+}
+
+// saveJob writes the job's state to the job store. Unless final, it skips
+// the write when the last one was under bulkJobSaveInterval ago. Writes are
+// serialized so an older snapshot never replaces a newer one.
+func (h *LoadHandler) saveJob(ctx context.Context, job *bulkJob, final bool) error {
+	job.saveMu.Lock()
+	defer job.saveMu.Unlock()
+	if !final && time.Since(job.saved) < bulkJobSaveInterval {
+		return nil
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(storedBulkJob{Owner: job.owner, Job: body})
+	if err != nil {
+		return err
+	}
+	if err := h.Jobs.Put(ctx, job.key, state); err != nil {
+		return err
+	}
+	job.saved = time.Now()
+	return nil
+}
+
+// record sets a row's result and saves the job's progress when it is due.
+func (h *LoadHandler) record(ctx context.Context, job *bulkJob, i int, res bulkResult) {
+	job.set(i, res)
+	if job.key == "" {
+		return
+	}
+	if err := h.saveJob(ctx, job, false); err != nil {
+		slog.WarnContext(ctx, "Saving bulk job progress failed", "job_id", job.ID, "error", err)
+	}
+}
+
+// BulkCreateLoads creates loads from a JSON array (application/json) or a CSV
+// (text/csv, see parseBulkCSV). Rows are validated and their locations
+// resolved one at a time, then valid rows are created with at most
+// BulkConcurrency requests in flight. Each row reports created or failed with
+// the same error body a single create would return; a row whose
+// externalTMSLoadID already exists fails with duplicate_load, and one like an
+// existing load fails with possible_duplicate unless ?allowSimilar=true is
+// set. ?dryRun=true validates without creating. Uploads over bulkSyncRows
+// rows, or with ?async=true, return 202 with a job to poll at
+// GET /api/loads/bulk/{jobId}; the job lives in h.Jobs.
+func (h *LoadHandler) BulkCreateLoads(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkBody)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		rows []bulkRow
+		err  error
+	)
+	switch mediaType {
+	case "application/json":
+		rows, err = parseBulkJSON(r.Body)
+	case "text/csv":
+		rows, err = parseBulkCSV(r.Body)
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, codeInvalidPayload, "Content-Type must be application/json or text/csv")
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, codeInvalidPayload, "upload exceeds 5 MB")
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, err.Error())
+		return
+	case len(rows) == 0:
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "upload has no rows")
+		return
+	case len(rows) > maxBulkRows:
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "upload exceeds "+strconv.Itoa(maxBulkRows)+" rows")
+		return
+	}
+
+	id, _ := auth.FromContext(r.Context())
+	job := &bulkJob{ID: newJobID(), owner: id.Subject, results: make([]bulkResult, len(rows))}
+	opts := bulkOptions{
+		dryRun:       r.URL.Query().Get("dryRun") == "true",
+		allowSimilar: r.URL.Query().Get("allowSimilar") == "true",
+	}
+	async := len(rows) > bulkSyncRows || r.URL.Query().Get("async") == "true"
+	if !async {
+		h.runBulk(r.Context(), job, rows, opts)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	// save the job before answering so a poll on any instance finds it
+	job.key = bulkJobKey(r, job.ID)
+	if err := h.saveJob(r.Context(), job, true); err != nil {
+		slog.ErrorContext(r.Context(), "Saving bulk job failed", "error", err)
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "bulk job store unavailable")
+		return
+	}
+	// keep the caller's identity and grant but outlive the request
+	ctx := context.WithoutCancel(r.Context())
+	go h.runBulk(ctx, job, rows, opts)
+	slog.InfoContext(r.Context(), "Bulk load job started", "job_id", job.ID, "rows", len(rows), "dry_run", opts.dryRun)
+	link := "/api/loads/bulk/" + job.ID
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", link)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{"jobId": job.ID, "status": "running", "total": len(rows), "link": link})
+}
+
+// GetBulkJob reports a bulk job's progress and, once done, its results, as
+// last saved to the job store. Jobs are visible only to the caller that
+// started them.
+func (h *LoadHandler) GetBulkJob(w http.ResponseWriter, r *http.Request) {
+	state, err := h.Jobs.Get(r.Context(), bulkJobKey(r, chi.URLParam(r, "jobID")))
+	if err != nil && !errors.Is(err, jobs.ErrNotFound) {
+		slog.ErrorContext(r.Context(), "Reading bulk job failed", "error", err)
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "bulk job store unavailable")
+		return
+	}
+	var stored storedBulkJob
+	if err == nil {
+		err = json.Unmarshal(state, &stored)
+	}
+	id, _ := auth.FromContext(r.Context())
+	if err != nil || stored.Owner != id.Subject {
+		writeError(w, http.StatusNotFound, codeNotFound, "bulk job not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(stored.Job, '\n'))
+}
+
+// bulkOptions are the query flags of a bulk upload.
+type bulkOptions struct {
+	dryRun       bool
+	allowSimilar bool
+}
+
+// runBulk validates rows in order, so repeated addresses resolve to one
+// Turvo location, then creates the valid ones concurrently.
+func (h *LoadHandler) runBulk(ctx context.Context, job *bulkJob, rows []bulkRow, opts bulkOptions) {
+	type pending struct {
+		idx      int
+		load     *domain.Load
+		shipment turvo.Shipment
+	}
+	var valid []pending
+	seen := make(map[string]int)
+	for i, row := range rows {
+		res := bulkResult{Row: i + 1, Status: bulkFailed}
+		if row.Load == nil {
+			res.StatusCode = http.StatusBadRequest
+			res.Error = &errorBody{Code: codeInvalidPayload, Message: "row could not be parsed", Fields: row.Fields}
+			h.record(ctx, job, i, res)
+			continue
+		}
+		res.ExternalTMSLoadID = row.Load.ExternalTMSLoadID
+		shipment, status, body := h.prepareBulkRow(ctx, row.Load, seen, opts)
+		if body != nil {
+			res.StatusCode, res.Error = status, body
+			h.record(ctx, job, i, res)
+			continue
+		}
+		seen[row.Load.ExternalTMSLoadID] = i + 1
+		if opts.dryRun {
+			res.Status = bulkValid
+			h.record(ctx, job, i, res)
+			continue
+		}
+		valid = append(valid, pending{idx: i, load: row.Load, shipment: shipment})
+	}
+
+	concurrency := h.BulkConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, p := range valid {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			h.record(ctx, job, p.idx, h.createBulkRow(ctx, p.idx+1, p.load, p.shipment))
+		}()
+	}
+	wg.Wait()
+	job.finish()
+	if job.key != "" {
+		if err := h.saveJob(ctx, job, true); err != nil {
+			slog.ErrorContext(ctx, "Saving finished bulk job failed", "job_id", job.ID, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "Bulk load job finished", "job_id", job.ID, "rows", len(rows), "valid", len(valid))
+}
+
+// prepareBulkRow runs the checks CreateLoad makes before calling Turvo and
+// returns the shipment to create, or the status and error body a single
+// create would have returned. Like a single create, a failed similar-load
+// lookup does not fail the row, and locations are resolved only once the
+// other checks pass. A dry run only looks locations up and never creates
+// them.
+func (h *LoadHandler) prepareBulkRow(ctx context.Context, load *domain.Load, seen map[string]int, opts bulkOptions) (turvo.Shipment, int, *errorBody) {
+	if load.ExternalTMSLoadID == "" {
+		return turvo.Shipment{}, http.StatusBadRequest, &errorBody{Code: codeValidationFailed, Message: "externalTMSLoadID is required",
+			Fields: []turvo.FieldError{{Field: "externalTMSLoadID", Message: "required"}}}
+	}
+	if first, ok := seen[load.ExternalTMSLoadID]; ok {
+		return turvo.Shipment{}, http.StatusConflict, &errorBody{Code: codeDuplicateLoad,
+			Message: "externalTMSLoadID repeats row " + strconv.Itoa(first)}
+	}
+	customerID := h.TurvoMapper.CustomerID(load)
+	if !mayUseCustomer(ctx, authz.LoadsCreate, customerID) {
+		return turvo.Shipment{}, http.StatusForbidden, &errorBody{Code: codeForbidden,
+			Message:    "not allowed to use " + string(authz.LoadsCreate) + " for customer " + strconv.Itoa(customerID),
+			Permission: string(authz.LoadsCreate), CustomerID: customerID}
+	}
+	if _, err := h.TurvoMapper.ToTurvoShipment(load); err != nil {
+		return turvo.Shipment{}, http.StatusBadRequest, &errorBody{Code: codeValidationFailed, Message: err.Error()}
+	}
+	existing, err := h.Shipments.FindShipmentByExternalID(ctx, load.ExternalTMSLoadID)
+	switch {
+	case err == nil:
+		body := h.duplicateLoadBody(ctx, load, existing)
+		return turvo.Shipment{}, http.StatusConflict, &body
+	case !errors.Is(err, turvo.ErrShipmentNotFound):
+		status, body := turvoErrorBody("duplicate check", err)
+		return turvo.Shipment{}, status, &body
+	}
+	if !opts.allowSimilar {
+		similar, err := h.findSimilar(ctx, load, customerID)
+		if err != nil {
+			slog.WarnContext(ctx, "Similar load check failed", "error", err)
+		} else if len(similar) > 0 {
+			body := possibleDuplicateBody(ctx, similar, customerID)
+			return turvo.Shipment{}, http.StatusConflict, &body
+		}
+	}
+	resolve := h.Locations.ResolveLoad
+	if opts.dryRun {
+		resolve = h.Locations.FindLoad
+	}
+	if err := resolve(ctx, load); err != nil {
+		status, body := turvoErrorBody("location", err)
+		return turvo.Shipment{}, status, &body
+	}
+	shipment, err := h.TurvoMapper.ToTurvoShipment(load)
+	if err != nil {
+		return turvo.Shipment{}, http.StatusBadRequest, &errorBody{Code: codeValidationFailed, Message: err.Error()}
+	}
+	return shipment, 0, nil
+}
+
+// createBulkRow creates one row that passed prepareBulkRow.
+func (h *LoadHandler) createBulkRow(ctx context.Context, row int, load *domain.Load, shipment turvo.Shipment) bulkResult {
+	res := bulkResult{Row: row, ExternalTMSLoadID: load.ExternalTMSLoadID, Status: bulkFailed}
+	// rows always carry a customId, which a retried create looks up first
+	created, err := h.Shipments.CreateShipment(turvo.WithSafeRetry(ctx), shipment)
+	if err != nil {
+		status, body := turvoErrorBody("create", err)
+		res.StatusCode, res.Error = status, &body
+		return res
+	}
+	res.Status, res.StatusCode, res.ID = bulkCreated, http.StatusCreated, created.ID
+	res.Load, _ = h.TurvoMapper.FromTurvoShipment(*created)
+	return res
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
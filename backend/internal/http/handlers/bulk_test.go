@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/jobs"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+)
+
+type bulkResponse struct {
+	JobID   string         `json:"jobId"`
+	Status  string         `json:"status"`
+	Total   int            `json:"total"`
+	Counts  map[string]int `json:"counts"`
+	Results []bulkResult   `json:"results"`
+}
+
+func decodeBulk(t *testing.T, rec *httptest.ResponseRecorder) bulkResponse {
+	t.Helper()
+	var resp bulkResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("bulk body %s", rec.Body)
+	}
+	return resp
+}
+
+const bulkCSV = `externalTMSLoadID,customer.turvoId,pickup.name,pickup.addressLine1,pickup.city,pickup.state,pickup.zipcode,pickup.readyTime,consignee.name,consignee.addressLine1,consignee.city,consignee.state,consignee.zipcode,specifications.totalWeight,specifications.hazmat
+CSV-1,500,Acme DC,1 Main St,Chicago,IL,60601,2026-03-02 08:00,Widget Co,9 Elm St,Dallas,TX,75201,42000,false
+CSV-2,500,Acme DC,1 Main St,Chicago,IL,60601,2026-03-03,Widget Co,9 Elm St,Dallas,TX,75201,heavy,no
+CSV-1,500,Acme DC,1 Main St,Chicago,IL,60601,2026-03-04,Widget Co,9 Elm St,Dallas,TX,75201,,
+`
+
+func TestBulkCreateCSV(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	rec := serve(r, http.MethodPost, "/api/loads/bulk", bulkCSV, "Content-Type", "text/csv")
+	resp := decodeBulk(t, rec)
+	if rec.Code != http.StatusOK || resp.Status != "done" || len(resp.Results) != 3 {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	first := resp.Results[0]
+	if first.Status != bulkCreated || first.ID == 0 || first.Load.Pickup.City != "Chicago" {
+		t.Errorf("row 1 = %+v", first)
+	}
+	// 08:00 without an offset is the pickup's local time, not UTC
+	if rt := first.Load.Pickup.ReadyTime; rt == nil || rt.Format(time.RFC3339) != "2026-03-02T08:00:00-06:00" {
+		t.Errorf("row 1 readyTime = %v, want 8am Central", rt)
+	}
+	bad := resp.Results[1]
+	if bad.Status != bulkFailed || bad.StatusCode != http.StatusBadRequest || len(bad.Error.Fields) != 2 {
+		t.Errorf("row 2 = %+v", bad)
+	}
+	if dup := resp.Results[2]; dup.Error == nil || dup.Error.Code != codeDuplicateLoad {
+		t.Errorf("row 3 = %+v", dup)
+	}
+	if resp.Counts[bulkCreated] != 1 || resp.Counts[bulkFailed] != 2 {
+		t.Errorf("counts = %v", resp.Counts)
+	}
+
+	rec = serve(r, http.MethodPost, "/api/loads/bulk", "externalTMSLoadID,pickup.cty\nX,Chicago\n", "Content-Type", "text/csv")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(decodeError(t, rec).Message, "pickup.cty") {
+		t.Errorf("unknown column status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBulkCreateJSONReportsExistingLoads(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	serve(r, http.MethodPost, "/api/loads", testLoad("OLD-1"))
+	loads := []any{testLoad("NEW-1"), testLoad("OLD-1"), "not a load"}
+
+	dry := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk?dryRun=true", loads))
+	if dry.Results[0].Status != bulkValid || len(listExternalIDs(t, serve(r, http.MethodGet, "/api/loads", nil))) != 1 {
+		t.Fatalf("dry run = %+v", dry.Results)
+	}
+
+	resp := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk", loads))
+	got := []string{resp.Results[0].Status, resp.Results[1].Status, resp.Results[2].Status}
+	if strings.Join(got, ",") != "created,failed,failed" {
+		t.Fatalf("statuses = %v, results %+v", got, resp.Results)
+	}
+	if e := resp.Results[1].Error; e.Existing == nil || e.Existing.ExternalTMSLoadID != "OLD-1" {
+		t.Errorf("existing row error = %+v", e)
+	}
+	if e := resp.Results[2].Error; e.Code != codeInvalidPayload {
+		t.Errorf("unparsable row error = %+v", e)
+	}
+}
+
+func TestBulkDryRunWritesNothing(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	loads := []any{testLoad("DRY-1")}
+	dry := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk?dryRun=true", loads))
+	if len(dry.Results) != 1 || dry.Results[0].Status != bulkValid {
+		t.Fatalf("dry run = %+v", dry.Results)
+	}
+	if n := srv.Calls(http.MethodPost, "locations"); n != 0 {
+		t.Errorf("dry run created %d locations", n)
+	}
+	if n := len(srv.Shipments()); n != 0 {
+		t.Errorf("dry run created %d shipments", n)
+	}
+
+	if resp := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk", loads)); resp.Results[0].Status != bulkCreated {
+		t.Fatalf("create = %+v", resp.Results)
+	}
+	if n := srv.Calls(http.MethodPost, "locations"); n != 2 {
+		t.Errorf("create added %d locations, want 2", n)
+	}
+}
+
+// awaitBulkJob polls a job through h as ops until it is done.
+func awaitBulkJob(t *testing.T, h http.Handler, jobID string) bulkResponse {
+	t.Helper()
+	var job bulkResponse
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		rec := serve(asCaller(h, "ops"), http.MethodGet, "/api/loads/bulk/"+jobID, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("poll status = %d, body %s", rec.Code, rec.Body)
+		}
+		if job = decodeBulk(t, rec); job.Status == "done" {
+			break
+		}
+	}
+	return job
+}
+
+func TestBulkCreateAsyncJob(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	rec := serve(asCaller(r, "ops"), http.MethodPost, "/api/loads/bulk?async=true", bulkCSV, "Content-Type", "text/csv")
+	started := decodeBulk(t, rec)
+	if rec.Code != http.StatusAccepted || started.JobID == "" || rec.Header().Get("Location") != "/api/loads/bulk/"+started.JobID {
+		t.Fatalf("status = %d, headers %v, body %s", rec.Code, rec.Header(), rec.Body)
+	}
+
+	if job := awaitBulkJob(t, r, started.JobID); job.Status != "done" || job.Counts[bulkCreated] != 1 {
+		t.Fatalf("job = %+v", job)
+	}
+	if rec := serve(asCaller(r, "someone-else"), http.MethodGet, "/api/loads/bulk/"+started.JobID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("other caller status = %d", rec.Code)
+	}
+}
+
+func TestBulkJobPolledOnAnotherInstance(t *testing.T) {
+	// two instances sharing Turvo and the job store
+	store, shared := memstore.New(), jobs.NewMemoryStore()
+	withShared := func(h *LoadHandler) { h.Jobs = shared }
+	first, second := newLoadRouter(store, withShared), newLoadRouter(store, withShared)
+
+	started := decodeBulk(t, serve(asCaller(first, "ops"), http.MethodPost, "/api/loads/bulk?async=true", bulkCSV, "Content-Type", "text/csv"))
+	job := awaitBulkJob(t, second, started.JobID)
+	if job.Status != "done" || job.Total != 3 || job.Counts[bulkCreated] != 1 || len(job.Results) != 3 {
+		t.Fatalf("job = %+v", job)
+	}
+}
+
+func TestBulkCreateChecksSimilarLoads(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	pickup := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	existing := testLoad("SIM-1")
+	existing.Pickup.ReadyTime = &pickup
+	if rec := serve(r, http.MethodPost, "/api/loads", existing); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	row := testLoad("SIM-2")
+	row.Pickup.ReadyTime = &pickup
+
+	res := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk", []domain.Load{row})).Results[0]
+	if res.Status != bulkFailed || res.StatusCode != http.StatusConflict || res.Error.Code != codePossibleDuplicate ||
+		len(res.Error.Similar) != 1 || res.Error.Similar[0].ExternalTMSLoadID != "SIM-1" {
+		t.Fatalf("row = %+v", res)
+	}
+	resp := decodeBulk(t, serve(r, http.MethodPost, "/api/loads/bulk?allowSimilar=true", []domain.Load{row}))
+	if res := resp.Results[0]; res.Status != bulkCreated {
+		t.Errorf("confirmed row = %+v", res)
+	}
+}
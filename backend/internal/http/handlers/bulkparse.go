@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// bulkRow is one parsed upload row. Load is nil when the row could not be
+// parsed, and Fields then says why.
+type bulkRow struct {
+	Load   *domain.Load
+	Fields []turvo.FieldError
+}
+
+// parseBulkJSON reads a JSON array of loads. Each element is decoded on its
+// own so one malformed row does not reject the upload.
+func parseBulkJSON(r io.Reader) ([]bulkRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("body must be a JSON array of loads: %w", err)
+	}
+	rows := make([]bulkRow, len(raw))
+	for i, m := range raw {
+		var l domain.Load
+		if err := json.Unmarshal(m, &l); err != nil {
+			rows[i].Fields = []turvo.FieldError{{Message: err.Error()}}
+			continue
+		}
+		rows[i].Load = &l
+	}
+	return rows, nil
+}
+
+// parseBulkCSV reads a CSV whose header names Load fields by their JSON
+// path, e.g. externalTMSLoadID, customer.turvoId, pickup.city,
+// pickup.readyTime, rateData.customerLhRateUsd or specifications.hazmat.
+// Names are case-insensitive. Empty cells leave the field unset, list fields
+// such as equipment take ';'-separated values, and times may be RFC 3339,
+// "2006-01-02 15:04" or a bare date. Times without an offset are local to
+// their stop, or UTC outside a stop. An unknown column rejects the whole
+// upload so a typo is not silently ignored.
+func parseBulkCSV(r io.Reader) ([]bulkRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	var unknown []string
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if _, err := loadField(reflect.ValueOf(&domain.Load{}).Elem(), header[i]); err != nil {
+			unknown = append(unknown, header[i])
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown CSV columns: %s", strings.Join(unknown, ", "))
+	}
+
+	var rows []bulkRow
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		var (
+			l     domain.Load
+			row   bulkRow
+			times []int
+		)
+		for i, v := range rec {
+			if i >= len(header) || strings.TrimSpace(v) == "" {
+				continue
+			}
+			f, _ := loadField(reflect.ValueOf(&l).Elem(), header[i])
+			if f.Kind() == reflect.Pointer {
+				// times wait until the stop's address is known
+				times = append(times, i)
+				continue
+			}
+			if err := setField(f, strings.TrimSpace(v)); err != nil {
+				row.Fields = append(row.Fields, turvo.FieldError{Field: header[i], Message: err.Error()})
+			}
+		}
+		for _, i := range times {
+			loc := time.UTC
+			if st := stopOf(&l, header[i]); st != nil {
+				loc = st.Location()
+			}
+			t, err := domain.ParseStopTime(strings.TrimSpace(rec[i]), loc)
+			if err != nil {
+				row.Fields = append(row.Fields, turvo.FieldError{Field: header[i], Message: err.Error()})
+				continue
+			}
+			f, _ := loadField(reflect.ValueOf(&l).Elem(), header[i])
+			f.Set(reflect.ValueOf(&t))
+		}
+		if row.Fields == nil {
+			row.Load = &l
+		}
+		rows = append(rows, row)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// loadField returns the settable field of v addressed by a dotted JSON path,
+// allocating nil struct pointers on the way. Only scalar, time and string
+// list fields can be addressed.
+func loadField(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct && v.Type().Elem() != timeType {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || v.Type() == timeType {
+			return reflect.Value{}, fmt.Errorf("unknown field %s", path)
+		}
+		f, ok := fieldByJSONName(v, name)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown field %s", path)
+		}
+		v = f
+	}
+	switch {
+	case v.Kind() == reflect.String, v.Kind() == reflect.Int, v.Kind() == reflect.Float64, v.Kind() == reflect.Bool:
+	case v.Kind() == reflect.Pointer && v.Type().Elem() == timeType:
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+	default:
+		return reflect.Value{}, fmt.Errorf("field %s cannot be set from CSV", path)
+	}
+	return v, nil
+}
+
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag != "" && tag != "-" && strings.EqualFold(tag, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// stopOf returns the stop holding the field at path, such as the pickup for
+// pickup.readyTime, or nil when the field is not on a stop.
+func stopOf(l *domain.Load, path string) *domain.Stop {
+	parent, _, ok := strings.Cut(path, ".")
+	if !ok {
+		return nil
+	}
+	f, ok := fieldByJSONName(reflect.ValueOf(l).Elem(), parent)
+	if !ok {
+		return nil
+	}
+	st, _ := f.Addr().Interface().(*domain.Stop)
+	return st
+}
+
+// setField parses s into f, a field returned by loadField. Time fields are
+// set by the caller with domain.ParseStopTime.
+func setField(f reflect.Value, s string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", s)
+		}
+		f.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, p := range strings.Split(s, ";") {
+			if p = strings.TrimSpace(p); p != "" {
+				items = append(items, p)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
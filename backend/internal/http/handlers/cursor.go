@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// listCursor is the position of the next page of a load listing. Filters
+// holds the listing's effective query, including the defaulted created[gte],
+// so every page sees the same filters. Key is Turvo's lastObjectKey of the
+// previous page and Start an offset after it, used only when Turvo returned
+// no key.
+type listCursor struct {
+	Filters string `json:"f"`
+	Key     string `json:"k,omitempty"`
+	Start   int    `json:"s,omitempty"`
+}
+
+var errInvalidCursor = errors.New("cursor is invalid or was issued by another server")
+
+// newCursorKey returns a random signing key, good until the process exits.
+func newCursorKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// encodeCursor returns c as an opaque token: its JSON and an HMAC of it,
+// each base64url encoded and joined with a dot.
+func (h *LoadHandler) encodeCursor(c listCursor) string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(h.signCursor(payload))
+}
+
+// decodeCursor verifies and parses a token from encodeCursor.
+func (h *LoadHandler) decodeCursor(token string) (listCursor, error) {
+	var c listCursor
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, h.signCursor(payload)) {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &c); err != nil || c.Start < 0 {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
+
+func (h *LoadHandler) signCursor(payload []byte) []byte {
+	m := hmac.New(sha256.New, h.CursorKey)
+	m.Write(payload)
+	return m.Sum(nil)
+}
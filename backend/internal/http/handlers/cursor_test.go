@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+)
+
+type listResponse struct {
+	Items      []domain.Load `json:"items"`
+	Pagination struct {
+		MoreAvailable bool   `json:"moreAvailable"`
+		NextCursor    string `json:"nextCursor"`
+	} `json:"pagination"`
+}
+
+func listPage(t *testing.T, r http.Handler, target string) listResponse {
+	t.Helper()
+	rec := serve(r, http.MethodGet, target, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, body %s", target, rec.Code, rec.Body)
+	}
+	var resp listResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestListLoadsCursorIsStableUnderCreates(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	for i := 0; i < 5; i++ {
+		serve(r, http.MethodPost, "/api/loads", testLoad(fmt.Sprintf("CUR-%d", i)))
+	}
+
+	page := listPage(t, r, "/api/loads?pageSize=2")
+	seen := map[string]bool{}
+	for pages := 0; ; pages++ {
+		for _, l := range page.Items {
+			if seen[l.ExternalTMSLoadID] {
+				t.Fatalf("%s listed twice", l.ExternalTMSLoadID)
+			}
+			seen[l.ExternalTMSLoadID] = true
+		}
+		if page.Pagination.NextCursor == "" {
+			break
+		}
+		if pages == 0 {
+			// newer loads would shift offset-based pages
+			serve(r, http.MethodPost, "/api/loads", testLoad("CUR-new-1"))
+			serve(r, http.MethodPost, "/api/loads", testLoad("CUR-new-2"))
+		}
+		page = listPage(t, r, "/api/loads?cursor="+url.QueryEscape(page.Pagination.NextCursor))
+	}
+	if len(seen) != 5 || seen["CUR-new-1"] {
+		t.Errorf("listed %v, want the 5 loads that existed at the start", seen)
+	}
+}
+
+func TestListLoadsRejectsForgedCursor(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	for i := 0; i < 3; i++ {
+		serve(r, http.MethodPost, "/api/loads", testLoad(fmt.Sprintf("CUR-%d", i)))
+	}
+	cursor := listPage(t, r, "/api/loads?pageSize=1").Pagination.NextCursor
+	if cursor == "" {
+		t.Fatal("no nextCursor")
+	}
+
+	payload, sig, _ := strings.Cut(cursor, ".")
+	other := newLoadRouter(memstore.New()) // signs with its own random key
+	for name, c := range map[string]string{
+		"garbage":      "not-a-cursor",
+		"tampered":     payload + "x." + sig,
+		"other server": cursor,
+	} {
+		h := r
+		if name == "other server" {
+			h = other
+		}
+		rec := serve(h, http.MethodGet, "/api/loads?cursor="+url.QueryEscape(c), nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", name, rec.Code)
+			continue
+		}
+		if e := decodeError(t, rec); e.Code != codeInvalidCursor {
+			t.Errorf("%s: error = %+v", name, e)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// Values of the onConflict query parameter on POST /api/loads.
+const (
+	onConflictError  = "error"
+	onConflictReturn = "return"
+)
+
+// loadRef points at an existing load in a duplicate response.
+type loadRef struct {
+	ID                int        `json:"id"`
+	ExternalTMSLoadID string     `json:"externalTMSLoadID,omitempty"`
+	Status            string     `json:"status,omitempty"`
+	PickupDate        *time.Time `json:"pickupDate,omitempty"`
+	Link              string     `json:"link"`
+}
+
+func (h *LoadHandler) newLoadRef(s turvo.Shipment) loadRef {
+	ref := loadRef{ID: s.ID, ExternalTMSLoadID: s.CustomID, Link: "/api/loads/" + strconv.Itoa(s.ID)}
+	if l, err := h.TurvoMapper.FromTurvoShipment(s); err == nil {
+		ref.Status = l.Status
+		ref.PickupDate = stopDate(firstPickup(l))
+	}
+	return ref
+}
+
+// guardDuplicates runs before a create. A shipment with the same custom id
+// gets a 409 duplicate_load pointing at it, or with ?onConflict=return is
+// returned as is with 200. Unless ?allowSimilar=true is set, loads for the
+// same customer, lane and pickup day get a 409 possible_duplicate listing
+// them so the UI can ask the user to confirm. Loads the caller may not read
+// are not pointed at or listed. It reports whether a response was written.
+func (h *LoadHandler) guardDuplicates(w http.ResponseWriter, r *http.Request, load *domain.Load, customerID int) bool {
+	q := r.URL.Query()
+	onConflict := q.Get("onConflict")
+	if onConflict != "" && onConflict != onConflictError && onConflict != onConflictReturn {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "onConflict must be error or return")
+		return true
+	}
+
+	if load.ExternalTMSLoadID != "" {
+		existing, err := h.Shipments.FindShipmentByExternalID(r.Context(), load.ExternalTMSLoadID)
+		switch {
+		case err == nil:
+			if onConflict == onConflictReturn {
+				if !allowCustomer(w, r, authz.LoadsRead, existing.CustomerID()) {
+					return true
+				}
+				l, _ := h.TurvoMapper.FromTurvoShipment(*existing)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(l)
+				return true
+			}
+			writeErrorBody(w, http.StatusConflict, h.duplicateLoadBody(r.Context(), load, existing))
+			return true
+		case !errors.Is(err, turvo.ErrShipmentNotFound):
+			writeTurvoError(w, "duplicate check", err)
+			return true
+		}
+	}
+
+	if q.Get("allowSimilar") == "true" {
+		return false
+	}
+	similar, err := h.findSimilar(r.Context(), load, customerID)
+	if err != nil {
+		// best effort: a failed lookup must not block the create
+		slog.WarnContext(r.Context(), "Similar load check failed", "error", err)
+		return false
+	}
+	if len(similar) == 0 {
+		return false
+	}
+	writeErrorBody(w, http.StatusConflict, possibleDuplicateBody(r.Context(), similar, customerID))
+	return true
+}
+
+// duplicateLoadBody is the 409 for a create whose custom id is taken by
+// existing. It points at existing only when the caller may read it.
+func (h *LoadHandler) duplicateLoadBody(ctx context.Context, load *domain.Load, existing *turvo.Shipment) errorBody {
+	body := errorBody{
+		Code:    codeDuplicateLoad,
+		Message: "a load with externalTMSLoadID " + load.ExternalTMSLoadID + " already exists",
+	}
+	if mayUseCustomer(ctx, authz.LoadsRead, existing.CustomerID()) {
+		ref := h.newLoadRef(*existing)
+		body.Existing = &ref
+	}
+	return body
+}
+
+// possibleDuplicateBody is the 409 for a create like the similar loads of
+// customerID. It lists them only when the caller may read them.
+func possibleDuplicateBody(ctx context.Context, similar []loadRef, customerID int) errorBody {
+	body := errorBody{
+		Code:    codePossibleDuplicate,
+		Message: "similar loads exist for this customer, lane and pickup date; resend with allowSimilar=true to create anyway",
+	}
+	if mayUseCustomer(ctx, authz.LoadsRead, customerID) {
+		body.Similar = similar
+	}
+	return body
+}
+
+// maxSimilarDetails caps how many listed shipments without a lane
+// findSimilar fetches in full, so a busy customer day costs a bounded number
+// of Turvo calls.
+const maxSimilarDetails = 10
+
+// findSimilar returns loads for customerID with the same pickup and
+// destination city and the same pickup day as load. Loads without a pickup
+// date or lane are never reported. Listed shipments whose lane is known and
+// differs are dropped before any are fetched in full.
+func (h *LoadHandler) findSimilar(ctx context.Context, load *domain.Load, customerID int) ([]loadRef, error) {
+	pickup, dest := firstPickup(load), lastDelivery(load)
+	day := stopDay(pickup)
+	if day == "" || customerID == 0 || pickup.City == "" || dest.City == "" {
+		return nil, nil
+	}
+	at := stopDate(pickup).In(pickup.Location())
+	start := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	q := url.Values{}
+	q.Set("customerId[eq]", strconv.Itoa(customerID))
+	q.Set("pickupDate[gte]", start.UTC().Format(time.RFC3339))
+	q.Set("pickupDate[lte]", start.AddDate(0, 0, 1).UTC().Format(time.RFC3339))
+	q.Set("created[gte]", time.Now().AddDate(0, 0, -90).UTC().Format(time.RFC3339))
+	q.Set("pageSize", "50")
+	shipments, _, err := h.Shipments.ListShipmentsPageWithQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []turvo.Shipment
+	unknown := 0
+	for _, s := range shipments {
+		if s.CustomerID() != customerID {
+			continue
+		}
+		if s.Lane == nil || (s.Lane.Start == "" && s.Lane.End == "") {
+			if unknown++; unknown > maxSimilarDetails {
+				continue
+			}
+		} else if l, err := h.TurvoMapper.FromTurvoShipment(s); err != nil ||
+			!samePlace(firstPickup(l), pickup) || !samePlace(lastDelivery(l), dest) {
+			continue
+		}
+		candidates = append(candidates, s)
+	}
+	if unknown > maxSimilarDetails {
+		slog.WarnContext(ctx, "Similar load check skipped shipments without a lane", "customer_id", customerID, "skipped", unknown-maxSimilarDetails)
+	}
+
+	var similar []loadRef
+	for _, s := range h.withDetails(ctx, candidates) {
+		l, err := h.TurvoMapper.FromTurvoShipment(s)
+		if err != nil {
+			continue
+		}
+		p, d := firstPickup(l), lastDelivery(l)
+		if stopDay(p) == day && samePlace(p, pickup) && samePlace(d, dest) {
+			similar = append(similar, h.newLoadRef(s))
+		}
+	}
+	return similar, nil
+}
+
+// firstPickup returns the load's first pickup. Stops are used only when
+// Pickup has no address, as on incoming multi-stop payloads; loads mapped
+// from Turvo carry the lane on Pickup.
+func firstPickup(l *domain.Load) domain.Stop {
+	if l.Pickup.City != "" {
+		return l.Pickup
+	}
+	for _, s := range l.Stops {
+		if s.StopType == domain.StopTypePickup || s.StopType == "" {
+			return s
+		}
+	}
+	return l.Pickup
+}
+
+// lastDelivery returns the load's final delivery, like firstPickup.
+func lastDelivery(l *domain.Load) domain.Stop {
+	if l.Consignee.City != "" {
+		return l.Consignee
+	}
+	for i := len(l.Stops) - 1; i >= 0; i-- {
+		if s := l.Stops[i]; s.StopType == domain.StopTypeDelivery || s.StopType == "" {
+			return s
+		}
+	}
+	return l.Consignee
+}
+
+// stopDate is the stop's ready time, or its appointment when no ready time
+// is set.
+func stopDate(s domain.Stop) *time.Time {
+	if s.ReadyTime != nil {
+		return s.ReadyTime
+	}
+	return s.ApptTime
+}
+
+// stopDay is the calendar day of the stop's date in the stop's own zone,
+// since pickup times are local to the facility. It is empty when the stop
+// has no date.
+func stopDay(s domain.Stop) string {
+	t := stopDate(s)
+	if t == nil {
+		return ""
+	}
+	return t.In(s.Location()).Format(time.DateOnly)
+}
+
+func samePlace(a, b domain.Stop) bool {
+	return strings.EqualFold(strings.TrimSpace(a.City), strings.TrimSpace(b.City)) &&
+		strings.EqualFold(strings.TrimSpace(a.State), strings.TrimSpace(b.State))
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+func TestCreateLoadRejectsDuplicateExternalID(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	first := serve(r, http.MethodPost, "/api/loads", testLoad("DUP-1"))
+	if first.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", first.Code, first.Body)
+	}
+
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("DUP-1"))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, body %s", rec.Code, rec.Body)
+	}
+	e := decodeError(t, rec)
+	if e.Code != codeDuplicateLoad || e.Existing == nil || e.Existing.Link != "/api/loads/"+strconv.Itoa(e.Existing.ID) {
+		t.Fatalf("error = %+v", e)
+	}
+	if got := serve(r, http.MethodGet, e.Existing.Link, nil); got.Code != http.StatusOK {
+		t.Errorf("existing link status = %d", got.Code)
+	}
+
+	rec = serve(r, http.MethodPost, "/api/loads?onConflict=return", testLoad("DUP-1"))
+	var got domain.Load
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || got.ExternalTMSLoadID != "DUP-1" {
+		t.Fatalf("onConflict=return status = %d, body %s", rec.Code, rec.Body)
+	}
+	if n := len(listExternalIDs(t, serve(r, http.MethodGet, "/api/loads", nil))); n != 1 {
+		t.Errorf("%d loads stored, want 1", n)
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads?onConflict=merge", testLoad("DUP-2")); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad onConflict status = %d", rec.Code)
+	}
+}
+
+func TestCreateLoadWarnsAboutSimilarLoads(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	pickup := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	load := func(id string, at time.Time) domain.Load {
+		l := testLoad(id)
+		l.Pickup.ReadyTime = &at
+		return l
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads", load("SIM-1", pickup)); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(r, http.MethodPost, "/api/loads", load("SIM-2", pickup.Add(4*time.Hour)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("similar status = %d, body %s", rec.Code, rec.Body)
+	}
+	e := decodeError(t, rec)
+	if e.Code != codePossibleDuplicate || len(e.Similar) != 1 || e.Similar[0].ExternalTMSLoadID != "SIM-1" {
+		t.Fatalf("error = %+v", e)
+	}
+
+	if rec := serve(r, http.MethodPost, "/api/loads?allowSimilar=true", load("SIM-2", pickup)); rec.Code != http.StatusCreated {
+		t.Errorf("confirmed create status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads", load("SIM-3", pickup.AddDate(0, 0, 1))); rec.Code != http.StatusCreated {
+		t.Errorf("next-day create status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestSimilarLoadsCompareDaysInThePickupZone(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	load := func(id, ready string) domain.Load {
+		l := testLoad(id)
+		at, _ := time.Parse(time.RFC3339, ready)
+		l.Pickup.ReadyTime = &at
+		return l
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads", load("ZONE-1", "2026-03-02T14:00:00Z")); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	// 02:00 UTC on the 3rd is still the evening of the 2nd in Chicago
+	rec := serve(r, http.MethodPost, "/api/loads", load("ZONE-2", "2026-03-03T02:00:00Z"))
+	if rec.Code != http.StatusConflict || decodeError(t, rec).Code != codePossibleDuplicate {
+		t.Fatalf("same local day status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads", load("ZONE-3", "2026-03-03T07:00:00Z")); rec.Code != http.StatusCreated {
+		t.Errorf("next local day status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+// countingShipments counts the shipments fetched in full.
+type countingShipments struct {
+	*memstore.Store
+	gets atomic.Int32
+}
+
+func (c *countingShipments) GetShipment(ctx context.Context, id string) (*turvo.Shipment, error) {
+	c.gets.Add(1)
+	return c.Store.GetShipment(ctx, id)
+}
+
+func TestSimilarLoadsCapDetailFetches(t *testing.T) {
+	store := &countingShipments{Store: memstore.New()}
+	r := newLoadRouter(store)
+	mapper := testMapper()
+
+	pickup := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	for i := range 3 * maxSimilarDetails {
+		// same day, another lane: half are listed with the lane, the rest
+		// without, so only a full fetch tells
+		l := testLoad("FAN-" + strconv.Itoa(i))
+		l.Pickup.ReadyTime = &pickup
+		l.Consignee.City = "Houston"
+		s, err := mapper.ToTurvoShipment(&l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			s.Lane = nil
+		}
+		if _, err := store.CreateShipment(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := testLoad("FAN-NEW")
+	l.Pickup.ReadyTime = &pickup
+	if rec := serve(r, http.MethodPost, "/api/loads", l); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	if n := store.gets.Load(); n != maxSimilarDetails {
+		t.Errorf("fetched %d shipments in full, want %d", n, maxSimilarDetails)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// Error codes returned in the JSON error envelope.
+const (
+	codeInvalidPayload   = "invalid_payload"
+	codeValidationFailed = "validation_failed"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeRateLimited      = "rate_limited"
+	codeUpstream         = "upstream_error"
+	codeUnauthorized     = "unauthorized"
+	codeUnavailable      = "unavailable"
+	codeForbidden        = "forbidden"
+	codeUnknownTenant    = "unknown_tenant"
+	// Idempotency-Key reused with another body, or still in progress
+	codeIdempotencyMismatch = "idempotency_key_reused"
+	codeInProgress          = "request_in_progress"
+	// a load with the same external id exists, or similar loads do
+	codeDuplicateLoad     = "duplicate_load"
+	codePossibleDuplicate = "possible_duplicate"
+	// a list cursor that was tampered with or signed with another key
+	codeInvalidCursor = "invalid_cursor"
+)
+
+// errorResponse is the JSON body of every failed API request:
+//
+//	{"error": {"code": "...", "message": "...", "fields": [...], "requestId": "..."}}
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Fields  []turvo.FieldError `json:"fields,omitempty"`
+	// RequestID is Turvo's request id, when the error came from Turvo.
+	RequestID string `json:"requestId,omitempty"`
+	// Permission and CustomerID explain a 403.
+	Permission string `json:"permission,omitempty"`
+	CustomerID int    `json:"customerId,omitempty"`
+	// Existing and Similar point at loads a create would duplicate.
+	Existing *loadRef  `json:"existing,omitempty"`
+	Similar  []loadRef `json:"similar,omitempty"`
+}
+
+// writeError writes a JSON error envelope with the given status.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorBody(w, status, errorBody{Code: code, Message: message})
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, body errorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: body})
+}
+
+// writeTurvoError maps an error from a Turvo call to an HTTP status and
+// writes it as a JSON error envelope. op names the failed operation.
+func writeTurvoError(w http.ResponseWriter, op string, err error) {
+	var rl turvo.RateLimitedError
+	if errors.As(err, &rl) && rl.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rl.RetryAfter.Seconds()))))
+	}
+	status, body := turvoErrorBody(op, err)
+	writeErrorBody(w, status, body)
+}
+
+// turvoErrorBody returns the status and error body for an error from a
+// Turvo call.
+func turvoErrorBody(op string, err error) (int, errorBody) {
+	var (
+		apiErr    *turvo.APIError
+		rl        turvo.RateLimitedError
+		ambiguous turvo.AmbiguousExternalIDError
+	)
+	switch {
+	case errors.As(err, &rl):
+		return http.StatusTooManyRequests, errorBody{Code: codeRateLimited, Message: "Turvo is rate limiting requests; try again shortly"}
+	case errors.Is(err, turvo.ErrShipmentNotFound):
+		return http.StatusNotFound, errorBody{Code: codeNotFound, Message: err.Error()}
+	case errors.As(err, &ambiguous):
+		return http.StatusConflict, errorBody{Code: codeConflict, Message: err.Error()}
+	case errors.As(err, &apiErr):
+		status, code := http.StatusBadGateway, codeUpstream
+		switch apiErr.StatusCode {
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			status, code = http.StatusBadRequest, codeValidationFailed
+		case http.StatusNotFound:
+			status, code = http.StatusNotFound, codeNotFound
+		case http.StatusConflict:
+			status, code = http.StatusConflict, codeConflict
+		case http.StatusTooManyRequests:
+			status, code = http.StatusTooManyRequests, codeRateLimited
+		}
+		msg := apiErr.Message
+		if msg == "" {
+			msg = http.StatusText(apiErr.StatusCode)
+		}
+		return status, errorBody{
+			Code:      code,
+			Message:   "turvo " + op + " error: " + msg,
+			Fields:    apiErr.FieldErrors,
+			RequestID: apiErr.RequestID,
+		}
+	default:
+		return http.StatusBadGateway, errorBody{Code: codeUpstream, Message: "turvo " + op + " error: " + err.Error()}
+	}
+}
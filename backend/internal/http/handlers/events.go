@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// loadEventBuffer is how many events a slow stream may fall behind before
+// further events are dropped for it.
+const loadEventBuffer = 32
+
+// loadEventHeartbeat keeps idle streams open through proxies and load
+// balancers that close quiet connections.
+const loadEventHeartbeat = 25 * time.Second
+
+// loadEvent is what the grid receives when a load changes in Turvo. It only
+// identifies the load; the grid refetches what it shows.
+type loadEvent struct {
+	Type              string `json:"type"`
+	ID                int    `json:"id"`
+	ExternalTMSLoadID string `json:"externalTMSLoadID,omitempty"`
+	// customerID is used to filter streams and is 0 when the event does not
+	// carry it.
+	customerID int
+}
+
+// LoadEvents fans Turvo shipment events out to the open load event streams
+// of one tenant. Publish is a turvo.EventHandler.
+type LoadEvents struct {
+	mu   sync.Mutex
+	subs map[chan loadEvent]struct{}
+}
+
+// NewLoadEvents returns a LoadEvents with no streams.
+func NewLoadEvents() *LoadEvents {
+	return &LoadEvents{subs: make(map[chan loadEvent]struct{})}
+}
+
+// Publish sends ev to every open stream. A stream whose buffer is full
+// misses the event rather than holding up the webhook.
+func (e *LoadEvents) Publish(ctx context.Context, ev turvo.Event) {
+	le := loadEvent{Type: ev.EventType()}
+	switch ev := ev.(type) {
+	case turvo.ShipmentCreatedEvent:
+		le.ID, le.ExternalTMSLoadID, le.customerID = ev.Shipment.ID, ev.Shipment.CustomID, ev.Shipment.CustomerID()
+	case turvo.ShipmentUpdatedEvent:
+		le.ID, le.ExternalTMSLoadID, le.customerID = ev.Shipment.ID, ev.Shipment.CustomID, ev.Shipment.CustomerID()
+	case turvo.ShipmentStatusChangedEvent:
+		le.ID, le.ExternalTMSLoadID = ev.ShipmentID, ev.CustomID
+	default:
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subs {
+		select {
+		case ch <- le:
+		default:
+			slog.WarnContext(ctx, "Load event dropped for a slow stream", "event_id", ev.EventID())
+		}
+	}
+}
+
+// subscribe opens a stream; the returned func closes it.
+func (e *LoadEvents) subscribe() (<-chan loadEvent, func()) {
+	ch := make(chan loadEvent, loadEventBuffer)
+	e.mu.Lock()
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+	return ch, func() {
+		e.mu.Lock()
+		delete(e.subs, ch)
+		e.mu.Unlock()
+	}
+}
+
+// StreamLoadEvents sends a server-sent event for each load created or changed
+// in Turvo, so the grid can refresh without polling. Callers restricted to
+// some customers only hear about those customers' loads.
+func (h *LoadHandler) StreamLoadEvents(w http.ResponseWriter, r *http.Request) {
+	if h.Events == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "load events not configured")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, codeUnavailable, "streaming unsupported")
+		return
+	}
+	allowed, restricted, ok := customerScope(w, r, authz.LoadsRead)
+	if !ok {
+		return
+	}
+	events, stop := h.Events.subscribe()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(loadEventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev := <-events:
+			if restricted && !h.eventAllowed(r.Context(), ev, allowed) {
+				continue
+			}
+			b, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: load\ndata: %s\n\n", b)
+		}
+		flusher.Flush()
+	}
+}
+
+// eventAllowed reports whether ev is about one of the allowed customers'
+// loads, looking the load up when the event does not name its customer.
+func (h *LoadHandler) eventAllowed(ctx context.Context, ev loadEvent, allowed []int) bool {
+	id := ev.customerID
+	if id == 0 {
+		s, err := h.Shipments.GetShipment(ctx, strconv.Itoa(ev.ID))
+		if err != nil {
+			return false
+		}
+		id = s.CustomerID()
+	}
+	return slices.Contains(allowed, id)
+}
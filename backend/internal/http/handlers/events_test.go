@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// readLoadEvent returns the data of the next "load" event on the stream.
+func readLoadEvent(t *testing.T, sc *bufio.Scanner) loadEvent {
+	t.Helper()
+	for sc.Scan() {
+		if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+			var ev loadEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatalf("event data %q", data)
+			}
+			return ev
+		}
+	}
+	t.Fatalf("stream ended: %v", sc.Err())
+	return loadEvent{}
+}
+
+func TestStreamLoadEventsFiltersByCustomer(t *testing.T) {
+	store := memstore.New()
+	events := NewLoadEvents()
+	srv := httptest.NewServer(asCaller(newLoadRouter(store, withPolicy, func(h *LoadHandler) { h.Events = events }), "rep"))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/loads/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// the rep is assigned customer 7; a status change names no customer, so
+	// the load is looked up
+	mapper := testMapper()
+	shipment := func(externalID string, customerID int) turvo.Shipment {
+		l := customerLoad(externalID, customerID)
+		s, err := mapper.ToTurvoShipment(&l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	created, err := store.CreateShipment(ctx, shipment("EV-2", 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events.Publish(ctx, turvo.ShipmentCreatedEvent{ID: "evt-1", Shipment: shipment("EV-1", 8)})
+	events.Publish(ctx, turvo.ShipmentStatusChangedEvent{ID: "evt-2", ShipmentID: created.ID, CustomID: "EV-2"})
+
+	ev := readLoadEvent(t, bufio.NewScanner(resp.Body))
+	if ev.Type != turvo.EventShipmentStatusChanged || ev.ID != created.ID || ev.ExternalTMSLoadID != "EV-2" {
+		t.Errorf("event = %+v", ev)
+	}
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/xlsx"
+)
+
+const (
+	// exportPageSize is how many shipments are fetched from Turvo, enriched
+	// and written per round trip.
+	exportPageSize = 100
+	// maxExportRows caps one export; each row may cost a Turvo detail call.
+	maxExportRows = 10000
+)
+
+// exportTruncatedTrailer is the trailer set when an export ends early, to
+// exportLimit or exportFailed. The file also says so in its last row, except
+// a failed XLSX export, which is left unfinished so it does not open.
+const exportTruncatedTrailer = "X-Export-Truncated"
+
+const (
+	exportLimit  = "limit"
+	exportFailed = "error"
+)
+
+// Values of the format query parameter on GET /api/loads/export.
+const (
+	exportCSV  = "csv"
+	exportXLSX = "xlsx"
+)
+
+// exportColumn is a column that can be selected in an export. value returns
+// a string, *float64, *time.Time or nil.
+type exportColumn struct {
+	key    string
+	header string
+	value  func(l *domain.Load) any
+}
+
+var exportColumns = []exportColumn{
+	{"externalTMSLoadID", "External ID", func(l *domain.Load) any { return l.ExternalTMSLoadID }},
+	{"status", "Status", func(l *domain.Load) any { return l.Status }},
+	{"phase", "Phase", func(l *domain.Load) any { return l.Phase }},
+	{"mode", "Mode", func(l *domain.Load) any { return l.Mode }},
+	{"serviceType", "Service Type", func(l *domain.Load) any { return l.ServiceType }},
+	{"customer", "Customer", func(l *domain.Load) any { return l.Customer.Name }},
+	{"lane", "Lane", func(l *domain.Load) any { return lane(l) }},
+	{"pickupCity", "Pickup City", func(l *domain.Load) any { return firstPickup(l).City }},
+	{"pickupState", "Pickup State", func(l *domain.Load) any { return firstPickup(l).State }},
+	{"pickupDate", "Pickup Date", func(l *domain.Load) any { return stopDate(firstPickup(l)) }},
+	{"deliveryCity", "Delivery City", func(l *domain.Load) any { return lastDelivery(l).City }},
+	{"deliveryState", "Delivery State", func(l *domain.Load) any { return lastDelivery(l).State }},
+	{"deliveryDate", "Delivery Date", func(l *domain.Load) any { return stopDate(lastDelivery(l)) }},
+	{"equipment", "Equipment", func(l *domain.Load) any { return strings.Join(l.Equipment, "; ") }},
+	{"miles", "Miles", func(l *domain.Load) any { return l.CustomerTotalMiles }},
+	{"margin", "Margin", func(l *domain.Load) any { return l.MarginAmount }},
+	{"marginValue", "Margin %", func(l *domain.Load) any { return l.MarginValue }},
+	{"carrier", "Carrier", func(l *domain.Load) any {
+		if l.Carrier == nil {
+			return nil
+		}
+		return l.Carrier.Name
+	}},
+	{"createdAt", "Created", func(l *domain.Load) any { return l.CreatedAt }},
+}
+
+var defaultExportColumns = []string{"externalTMSLoadID", "status", "phase", "lane", "pickupDate", "equipment", "miles", "margin"}
+
+// selectExportColumns resolves a comma-separated list of column keys; an
+// empty list selects the defaults.
+func selectExportColumns(list string) ([]exportColumn, []string) {
+	keys := defaultExportColumns
+	if strings.TrimSpace(list) != "" {
+		keys = strings.Split(list, ",")
+	}
+	var (
+		cols    []exportColumn
+		unknown []string
+	)
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		i := slices.IndexFunc(exportColumns, func(c exportColumn) bool { return strings.EqualFold(c.key, k) })
+		if i < 0 {
+			unknown = append(unknown, k)
+			continue
+		}
+		cols = append(cols, exportColumns[i])
+	}
+	return cols, unknown
+}
+
+// lane renders "City, ST -> City, ST", leaving out missing parts.
+func lane(l *domain.Load) string {
+	place := func(s domain.Stop) string {
+		var parts []string
+		for _, p := range []string{s.City, s.State} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	from, to := place(firstPickup(l)), place(lastDelivery(l))
+	if from == "" && to == "" {
+		return ""
+	}
+	return from + " -> " + to
+}
+
+// rowWriter is the part of the CSV and XLSX writers the export uses.
+type rowWriter interface {
+	WriteRow(cells []any) error
+	Flush() error
+	Close() error
+}
+
+// csvRows adapts encoding/csv to rowWriter.
+type csvRows struct{ w *csv.Writer }
+
+// WriteRow writes cells as one record. Text that a spreadsheet would run as
+// a formula is prefixed with a quote; numbers are written as they are.
+func (c csvRows) WriteRow(cells []any) error {
+	rec := make([]string, len(cells))
+	for i, v := range cells {
+		switch v := v.(type) {
+		case string:
+			rec[i] = escapeFormula(v)
+		case *float64:
+			if v != nil {
+				rec[i] = strconv.FormatFloat(*v, 'f', -1, 64)
+			}
+		case *time.Time:
+			if v != nil {
+				rec[i] = v.Format(time.RFC3339)
+			}
+		}
+	}
+	return c.w.Write(rec)
+}
+
+// escapeFormula prefixes s with a quote when it starts with a character
+// spreadsheets read as the start of a formula.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c csvRows) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c csvRows) Close() error { return c.Flush() }
+
+// ExportLoads streams loads as CSV or XLSX. It takes the same filters as
+// ListLoads plus format (csv or xlsx, default csv) and columns, a
+// comma-separated list of column keys. Turvo is paged through and each page
+// written out before the next is fetched, so the export never holds more
+// than a page in memory. Errors found before the first row is written get
+// the usual JSON error, as does an export Turvo reports as larger than
+// maxExportRows. Stopping early at the limit or on a later Turvo failure is
+// signalled by exportTruncatedTrailer.
+func (h *LoadHandler) ExportLoads(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportXLSX {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "format must be csv or xlsx")
+		return
+	}
+	cols, unknown := selectExportColumns(q.Get("columns"))
+	if len(unknown) > 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "unknown columns: "+strings.Join(unknown, ", "))
+		return
+	}
+	forward := listFilters(q)
+	allowed, restricted, ok := scopeQuery(w, r, forward)
+	if !ok {
+		return
+	}
+	start, _ := strconv.Atoi(forward.Get("start"))
+	forward.Set("pageSize", strconv.Itoa(exportPageSize))
+
+	// fetch the first page before committing to a download so a Turvo error
+	// can still be reported properly
+	shipments, meta, err := h.Shipments.ListShipmentsPageWithQuery(r.Context(), forward)
+	if err != nil {
+		writeTurvoError(w, "export", err)
+		return
+	}
+	if meta.TotalRecords > maxExportRows {
+		writeError(w, http.StatusBadRequest, codeValidationFailed,
+			fmt.Sprintf("%d loads match; narrow the filters to export at most %d", meta.TotalRecords, maxExportRows))
+		return
+	}
+
+	filename := "loads-" + time.Now().UTC().Format(time.DateOnly) + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Trailer", exportTruncatedTrailer)
+	var out rowWriter
+	if format == exportXLSX {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		xw, err := xlsx.NewWriter(w, "Loads")
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Starting load export failed", "error", err)
+			return
+		}
+		out = xw
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out = csvRows{csv.NewWriter(w)}
+	}
+
+	row := make([]any, len(cols))
+	for i, c := range cols {
+		row[i] = c.header
+	}
+	out.WriteRow(row)
+
+	written, truncated := 0, ""
+pages:
+	for {
+		for _, s := range h.withDetails(r.Context(), shipments) {
+			if restricted && !slices.Contains(allowed, s.CustomerID()) {
+				continue
+			}
+			l, err := h.TurvoMapper.FromTurvoShipment(s)
+			if err != nil {
+				continue
+			}
+			if written == maxExportRows {
+				truncated = exportLimit
+				break pages
+			}
+			for i, c := range cols {
+				row[i] = c.value(l)
+			}
+			out.WriteRow(row)
+			written++
+		}
+		if err := out.Flush(); err != nil {
+			// the client went away
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if !meta.MoreAvailable || len(shipments) == 0 {
+			break
+		}
+		// chain pages by Turvo's lastObjectKey when it sends one, so loads
+		// created during the export do not shift the pages
+		if meta.LastObjectKey != "" {
+			forward.Set("lastObjectKey", meta.LastObjectKey)
+			forward.Del("start")
+		} else {
+			start += len(shipments)
+			forward.Set("start", strconv.Itoa(start))
+		}
+		shipments, meta, err = h.Shipments.ListShipmentsPageWithQuery(r.Context(), forward)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Load export aborted", "rows", written, "error", err)
+			truncated = exportFailed
+			break
+		}
+	}
+
+	if truncated != "" {
+		w.Header().Set(exportTruncatedTrailer, truncated)
+		clear(row)
+		switch {
+		case truncated == exportLimit:
+			slog.WarnContext(r.Context(), "Load export truncated", "rows", written)
+			row[0] = fmt.Sprintf("Export stopped at %d rows; narrow the filters to export the rest", written)
+		case format == exportXLSX:
+			// a workbook without its closing parts fails to open instead of
+			// passing for a complete export
+			out.Flush()
+			return
+		default:
+			row[0] = fmt.Sprintf("Export failed after %d rows; the file is incomplete", written)
+		}
+		out.WriteRow(row)
+	}
+	if err := out.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "Finishing load export failed", "error", err)
+	}
+}
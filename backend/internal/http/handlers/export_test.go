@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+func readCSV(t *testing.T, body *bytes.Buffer) [][]string {
+	t.Helper()
+	recs, err := csv.NewReader(body).ReadAll()
+	if err != nil {
+		t.Fatalf("body is not CSV: %v", err)
+	}
+	return recs
+}
+
+func TestExportLoadsCSVPagesThroughAll(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	total := exportPageSize + 5
+	for i := 0; i < total; i++ {
+		if rec := serve(r, http.MethodPost, "/api/loads", testLoad(fmt.Sprintf("EXP-%03d", i))); rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(r, http.MethodGet, "/api/loads/export", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="loads-`) || !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	recs := readCSV(t, rec.Body)
+	if len(recs) != total+1 {
+		t.Fatalf("rows = %d, want %d", len(recs), total+1)
+	}
+	if got := strings.Join(recs[0], ","); got != "External ID,Status,Phase,Lane,Pickup Date,Equipment,Miles,Margin" {
+		t.Errorf("header = %s", got)
+	}
+	seen := map[string]bool{}
+	for _, rec := range recs[1:] {
+		seen[rec[0]] = true
+	}
+	if len(seen) != total {
+		t.Errorf("distinct loads = %d, want %d", len(seen), total)
+	}
+}
+
+func TestExportLoadsSelectsColumns(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	serve(r, http.MethodPost, "/api/loads", testLoad("EXP-1"))
+
+	rec := serve(r, http.MethodGet, "/api/loads/export?columns=lane,externalTMSLoadID", nil)
+	recs := readCSV(t, rec.Body)
+	if len(recs) != 2 {
+		t.Fatalf("rows = %v", recs)
+	}
+	if recs[0][0] != "Lane" || recs[1][0] != "Chicago, IL -> Dallas, TX" || recs[1][1] != "EXP-1" {
+		t.Errorf("rows = %v", recs)
+	}
+}
+
+func TestExportLoadsCSVEscapesFormulas(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	l := testLoad("=HYPERLINK(\"http://evil\")")
+	l.Pickup.City = "@SUM(A1)"
+	serve(r, http.MethodPost, "/api/loads", l)
+
+	rec := serve(r, http.MethodGet, "/api/loads/export?columns=externalTMSLoadID,lane", nil)
+	recs := readCSV(t, rec.Body)
+	if len(recs) != 2 || recs[1][0] != "'=HYPERLINK(\"http://evil\")" || recs[1][1] != "'@SUM(A1), IL -> Dallas, TX" {
+		t.Fatalf("rows = %q", recs)
+	}
+
+	var buf bytes.Buffer
+	w := csvRows{csv.NewWriter(&buf)}
+	loss := -250.5
+	w.WriteRow([]any{"+1", "-1", "\tcmd", "a=b", &loss})
+	w.Flush()
+	if got := strings.TrimSpace(buf.String()); got != "'+1,'-1,'\tcmd,a=b,-250.5" {
+		t.Errorf("row = %q", got)
+	}
+}
+
+func TestExportLoadsXLSX(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	serve(r, http.MethodPost, "/api/loads", testLoad("EXP-1"))
+
+	rec := serve(r, http.MethodGet, "/api/loads/export?format=xlsx", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("body is not a workbook: %v", err)
+	}
+	found := false
+	for _, f := range zr.File {
+		found = found || f.Name == "xl/worksheets/sheet1.xml"
+	}
+	if !found {
+		t.Error("workbook has no sheet")
+	}
+}
+
+func TestExportLoadsRejectsBadParams(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	for _, target := range []string{"/api/loads/export?format=pdf", "/api/loads/export?columns=status,rate"} {
+		rec := serve(r, http.MethodGet, target, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", target, rec.Code)
+			continue
+		}
+		if e := decodeError(t, rec); e.Code != codeInvalidPayload {
+			t.Errorf("%s: error = %+v", target, e)
+		}
+	}
+}
+
+func TestExportLoadsLimitedToCallersCustomers(t *testing.T) {
+	r := newLoadRouter(memstore.New(), withPolicy)
+	for i, customer := range []int{7, 8, 7} {
+		rec := serve(asCaller(r, "dispatcher"), http.MethodPost, "/api/loads", customerLoad(fmt.Sprintf("C-%d", i), customer))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(asCaller(r, "rep"), http.MethodGet, "/api/loads/export?columns=externalTMSLoadID", nil)
+	recs := readCSV(t, rec.Body)
+	if len(recs) != 3 || recs[1][0] == "C-1" || recs[2][0] == "C-1" {
+		t.Errorf("rows = %v", recs)
+	}
+	if rec := serve(asCaller(r, "rep"), http.MethodGet, "/api/loads/export?customerId[eq]=8", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("other customer status = %d", rec.Code)
+	}
+}
+
+// pagedShipments lists pages of generated shipments. The list fails from
+// page failAt on when it is set, and reports total when it is set.
+type pagedShipments struct {
+	*memstore.Store
+	pages, failAt, total int
+}
+
+func (p *pagedShipments) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) ([]turvo.Shipment, turvo.PageInfo, error) {
+	start, _ := strconv.Atoi(q.Get("start"))
+	page := start / exportPageSize
+	if p.failAt > 0 && page >= p.failAt {
+		return nil, turvo.PageInfo{}, errors.New("turvo unavailable")
+	}
+	shipments := make([]turvo.Shipment, exportPageSize)
+	for i := range shipments {
+		id := start + i + 1
+		shipments[i] = turvo.Shipment{ID: id, CustomID: "PG-" + strconv.Itoa(id), Lane: &turvo.Lane{Start: "Chicago, IL", End: "Dallas, TX"}}
+	}
+	return shipments, turvo.PageInfo{Start: start, MoreAvailable: page+1 < p.pages, TotalRecords: p.total}, nil
+}
+
+func TestExportLoadsSignalsTheRowLimit(t *testing.T) {
+	r := newLoadRouter(&pagedShipments{Store: memstore.New(), pages: maxExportRows/exportPageSize + 2})
+	rec := serve(r, http.MethodGet, "/api/loads/export?columns=externalTMSLoadID", nil)
+	if got := rec.Result().Trailer.Get(exportTruncatedTrailer); got != exportLimit {
+		t.Errorf("trailer = %q", got)
+	}
+	recs := readCSV(t, rec.Body)
+	if len(recs) != maxExportRows+2 || !strings.HasPrefix(recs[len(recs)-1][0], "Export stopped at 10000 rows") {
+		t.Fatalf("rows = %d, last %v", len(recs), recs[len(recs)-1])
+	}
+
+	// exactly at the limit nothing is cut off
+	r = newLoadRouter(&pagedShipments{Store: memstore.New(), pages: maxExportRows / exportPageSize})
+	rec = serve(r, http.MethodGet, "/api/loads/export?columns=externalTMSLoadID", nil)
+	if got := rec.Result().Trailer.Get(exportTruncatedTrailer); got != "" || len(readCSV(t, rec.Body)) != maxExportRows+1 {
+		t.Errorf("full export trailer = %q", got)
+	}
+}
+
+func TestExportLoadsRejectsTooManyUpFront(t *testing.T) {
+	r := newLoadRouter(&pagedShipments{Store: memstore.New(), pages: 200, total: maxExportRows + 1})
+	rec := serve(r, http.MethodGet, "/api/loads/export", nil)
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != codeValidationFailed {
+		t.Errorf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestExportLoadsSignalsLaterFailures(t *testing.T) {
+	r := newLoadRouter(&pagedShipments{Store: memstore.New(), pages: 3, failAt: 2})
+	rec := serve(r, http.MethodGet, "/api/loads/export?columns=externalTMSLoadID", nil)
+	if got := rec.Result().Trailer.Get(exportTruncatedTrailer); got != exportFailed {
+		t.Errorf("csv trailer = %q", got)
+	}
+	recs := readCSV(t, rec.Body)
+	if len(recs) != 2*exportPageSize+2 || !strings.HasPrefix(recs[len(recs)-1][0], "Export failed after 200 rows") {
+		t.Errorf("rows = %d, last %v", len(recs), recs[len(recs)-1])
+	}
+
+	// a workbook cut short is left unfinished rather than closed
+	rec = serve(r, http.MethodGet, "/api/loads/export?format=xlsx", nil)
+	if got := rec.Result().Trailer.Get(exportTruncatedTrailer); got != exportFailed {
+		t.Errorf("xlsx trailer = %q", got)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())); err == nil {
+		t.Error("partial workbook opens as complete")
+	}
+}
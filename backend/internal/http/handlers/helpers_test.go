@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo/turvotest"
+)
+
+// testBackend is what a test LoadHandler runs against: a memstore.Store, or
+// a Turvo client talking to a turvotest server.
+type testBackend interface {
+	ShipmentStore
+	CustomerDirectory
+	turvo.LocationDirectory
+}
+
+// testMapper maps loads with customer 500 as the default customer.
+func testMapper() *turvo.Mapper {
+	return turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500})
+}
+
+// newLoadRouter serves a LoadHandler over backend. opts adjust the handler
+// before its routes are registered.
+func newLoadRouter(backend testBackend, opts ...func(*LoadHandler)) *chi.Mux {
+	h := NewLoadHandler(backend, backend, testMapper(), turvo.NewLocationResolver(backend))
+	for _, opt := range opts {
+		opt(h)
+	}
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r
+}
+
+// newTurvoLoadRouter serves a LoadHandler over a Turvo client talking to a
+// turvotest server, with retries short enough for tests.
+func newTurvoLoadRouter(t *testing.T) (*chi.Mux, *turvotest.Server) {
+	t.Helper()
+	srv := turvotest.NewServer()
+	t.Cleanup(srv.Close)
+	client, err := turvo.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetRetryPolicy(turvo.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxRetryAfter: time.Second})
+	return newLoadRouter(client), srv
+}
+
+// withPolicy applies the test policy: "rep" and "manager" are restricted to
+// customer 7, "viewer" may only read, and "dispatcher" may do anything with
+// loads. asCaller picks the subject a request is authenticated as.
+func withPolicy(h *LoadHandler) {
+	policy := authz.DefaultPolicy()
+	policy.Roles["account-manager"] = authz.Role{Permissions: []authz.Permission{authz.LoadsRead, authz.LoadsCreate, authz.LoadsUpdate}, RestrictCustomers: true}
+	policy.Subjects["rep"] = authz.Subject{Roles: []string{"rep"}, Customers: []int{7}}
+	policy.Subjects["manager"] = authz.Subject{Roles: []string{"account-manager"}, Customers: []int{7}}
+	policy.Subjects["viewer"] = authz.Subject{Roles: []string{"viewer"}}
+	policy.Subjects["dispatcher"] = authz.Subject{Roles: []string{"dispatcher"}}
+	h.Policy = policy
+}
+
+func asCaller(h http.Handler, subject string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{Subject: subject})))
+	})
+}
+
+// mountAPI serves h under /api/* as the server does.
+func mountAPI(h http.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Handle("/api/*", h)
+	return r
+}
+
+// serve sends a request to h and records the response. A string body is
+// sent as is and anything else as JSON. header holds name/value pairs that
+// override the JSON Content-Type; pairs with an empty value are left out.
+func serve(h http.Handler, method, target string, body any, header ...string) *httptest.ResponseRecorder {
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+		r = http.NoBody
+	case string:
+		r = strings.NewReader(b)
+	default:
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(b)
+		r = &buf
+	}
+	req := httptest.NewRequest(method, target, r)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		if header[i+1] != "" {
+			req.Header.Set(header[i], header[i+1])
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) errorBody {
+	t.Helper()
+	var env errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("error body is not JSON: %q", rec.Body.String())
+	}
+	return env.Error
+}
+
+func testLoad(externalID string) domain.Load {
+	return domain.Load{
+		ExternalTMSLoadID: externalID,
+		Status:            "Tendered",
+		Pickup:            domain.Stop{Name: "Acme DC", AddressLine1: "1 Main St", City: "Chicago", State: "IL", Zipcode: "60601"},
+		Consignee:         domain.Stop{Name: "Widget Co", AddressLine1: "9 Elm St", City: "Dallas", State: "TX", Zipcode: "75201"},
+	}
+}
+
+func customerLoad(externalID string, customerID int) domain.Load {
+	l := testLoad(externalID)
+	l.Customer = domain.Party{TurvoID: customerID}
+	return l
+}
+
+// listLoads decodes a load list response.
+func listLoads(t *testing.T, rec *httptest.ResponseRecorder) []domain.Load {
+	t.Helper()
+	var list struct {
+		Items []domain.Load `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("list body %s", rec.Body)
+	}
+	return list.Items
+}
+
+func listExternalIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var ids []string
+	for _, l := range listLoads(t, rec) {
+		ids = append(ids, l.ExternalTMSLoadID)
+	}
+	return ids
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+)
+
+const (
+	// maxIdempotentBody bounds the request and response bodies kept for a
+	// key; larger requests are rejected.
+	maxIdempotentBody = 1 << 20
+	maxIdempotencyKey = 255
+	// idempotencyWait is how long a duplicate waits for the first request
+	// with its key to finish before getting a 409.
+	idempotencyWait = 10 * time.Second
+	idempotencyPoll = 100 * time.Millisecond
+	// idempotencyRenew is how often a running request renews its claim, well
+	// within the stores' lock period.
+	idempotencyRenew = idempotency.DefaultLockTTL / 3
+)
+
+// replayedHeader marks a response served from the idempotency store.
+const replayedHeader = "Idempotent-Replayed"
+
+// idempotent returns middleware that honours the Idempotency-Key header. The
+// first request with a key runs and its response is stored; a repeat with the
+// same body gets the stored response, and one with a different body a 409.
+// A duplicate arriving while the first is still running waits for it, and
+// the first renews its claim for as long as it runs.
+// 5xx, 409 and 429 responses are not stored, so those requests may be
+// retried, for example after the user confirms a possible duplicate.
+// Requests without the header and a nil store pass through.
+func idempotent(store idempotency.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if store == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				writeError(w, http.StatusBadRequest, codeInvalidPayload, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil || len(body) > maxIdempotentBody {
+				writeError(w, http.StatusBadRequest, codeInvalidPayload, "request body too large for an idempotent request")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scoped := scopedKey(r, key)
+			claim, resp, err := beginIdempotent(r.Context(), store, scoped, idempotency.Hash(r.Method, r.URL.RequestURI(), body))
+			switch {
+			case errors.Is(err, idempotency.ErrMismatch):
+				writeError(w, http.StatusConflict, codeIdempotencyMismatch, "Idempotency-Key was already used with a different request")
+				return
+			case errors.Is(err, idempotency.ErrInFlight):
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusConflict, codeInProgress, "a request with this Idempotency-Key is still in progress")
+				return
+			case err != nil && r.Context().Err() != nil:
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "Idempotency store failed", "error", err)
+				writeError(w, http.StatusServiceUnavailable, codeUnavailable, "idempotency store unavailable")
+				return
+			case resp != nil:
+				replay(w, resp)
+				return
+			}
+
+			// detach from the request so a client that hangs up still
+			// records the outcome or frees the key
+			ctx := context.WithoutCancel(r.Context())
+			stopRenew := renewClaim(ctx, store, scoped, claim)
+			rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+			stored := false
+			defer func() {
+				stopRenew()
+				if !stored {
+					if err := store.Release(ctx, scoped, claim); err != nil {
+						slog.ErrorContext(ctx, "Releasing idempotency key failed", "error", err)
+					}
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			stopRenew()
+			if rec.status >= 500 || rec.status == http.StatusConflict || rec.status == http.StatusTooManyRequests || rec.overflow {
+				return
+			}
+			err = store.Complete(ctx, scoped, claim, idempotency.Response{
+				Status: rec.status,
+				Header: keptHeaders(w.Header()),
+				Body:   rec.body.Bytes(),
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "Storing idempotent response failed", "error", err)
+				return
+			}
+			stored = true
+		})
+	}
+}
+
+// beginIdempotent claims key, polling while another request holds it for up
+// to idempotencyWait.
+func beginIdempotent(ctx context.Context, store idempotency.Store, key, hash string) (string, *idempotency.Response, error) {
+	deadline := time.Now().Add(idempotencyWait)
+	for {
+		claim, resp, err := store.Begin(ctx, key, hash)
+		if !errors.Is(err, idempotency.ErrInFlight) || time.Now().After(deadline) {
+			return claim, resp, err
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		case <-time.After(idempotencyPoll):
+		}
+	}
+}
+
+// renewClaim extends claim on key every idempotencyRenew until the returned
+// stop is called, so a request that outlasts the lock keeps its key. stop
+// may be called more than once.
+func renewClaim(ctx context.Context, store idempotency.Store, key, claim string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(idempotencyRenew)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if err := store.Extend(ctx, key, claim); err != nil {
+					slog.WarnContext(ctx, "Renewing idempotency claim failed", "error", err)
+					if errors.Is(err, idempotency.ErrClaimLost) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// scopedKey binds the client's key to the tenant and caller so callers
+// cannot see each other's responses by guessing keys.
+func scopedKey(r *http.Request, key string) string {
+	var tenantID string
+	if t, ok := tenant.FromContext(r.Context()); ok {
+		tenantID = t.ID
+	}
+	id, _ := auth.FromContext(r.Context())
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + id.Subject + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func replay(w http.ResponseWriter, resp *idempotency.Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(replayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// keptHeaders returns the response headers worth replaying.
+func keptHeaders(h http.Header) http.Header {
+	kept := http.Header{}
+	for _, k := range []string{"Content-Type", "Location"} {
+		if v := h.Values(k); len(v) > 0 {
+			kept[k] = v
+		}
+	}
+	return kept
+}
+
+// recordingWriter passes a response through while keeping a copy of its
+// status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.body.Len()+len(b) > maxIdempotentBody {
+		w.overflow = true
+	} else {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
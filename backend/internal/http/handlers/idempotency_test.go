@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+)
+
+func TestCreateLoadIdempotencyKey(t *testing.T) {
+	r := newLoadRouter(memstore.New(), func(h *LoadHandler) { h.Idempotency = idempotency.NewMemoryStore() })
+
+	first := serve(r, http.MethodPost, "/api/loads", testLoad("IDEM-1"), idempotency.Header, "wizard-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, body %s", first.Code, first.Body)
+	}
+	again := serve(r, http.MethodPost, "/api/loads", testLoad("IDEM-1"), idempotency.Header, "wizard-1")
+	if again.Code != http.StatusCreated || again.Header().Get(replayedHeader) != "true" || again.Body.String() != first.Body.String() {
+		t.Fatalf("replay status = %d, headers %v, body %s", again.Code, again.Header(), again.Body)
+	}
+	if n := len(listLoads(t, serve(r, http.MethodGet, "/api/loads", nil))); n != 1 {
+		t.Fatalf("created %d loads, want 1", n)
+	}
+
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("IDEM-2"), idempotency.Header, "wizard-1")
+	if rec.Code != http.StatusConflict || decodeError(t, rec).Code != codeIdempotencyMismatch {
+		t.Fatalf("reused key status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestIdempotentConcurrentDuplicateWaits(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := idempotent(idempotency.NewMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int32{"call": n})
+	}))
+
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, 2)
+	for i := range recs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = serve(h, http.MethodPost, "/api/loads", testLoad("X"), idempotency.Header, "dup")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times", calls.Load())
+	}
+	for i, rec := range recs {
+		if rec.Code != http.StatusCreated || rec.Body.String() != recs[0].Body.String() {
+			t.Errorf("response %d = %d %s", i, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestIdempotentServerErrorReleasesKey(t *testing.T) {
+	var calls atomic.Int32
+	h := idempotent(idempotency.NewMemoryStore())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			writeError(w, http.StatusBadGateway, codeUpstream, "turvo down")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	if rec := serve(h, http.MethodPost, "/api/loads", testLoad("X"), idempotency.Header, "k"); rec.Code != http.StatusBadGateway {
+		t.Fatalf("first status = %d", rec.Code)
+	}
+	if rec := serve(h, http.MethodPost, "/api/loads", testLoad("X"), idempotency.Header, "k"); rec.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d", rec.Code)
+	}
+}
@@ -3,30 +3,61 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
 	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/jobs"
 	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
 )
 
 // LoadHandler exposes HTTP handlers for listing, creating, and fetching loads.
-// It delegates remote operations to the Turvo API client and converts between
-// Turvo models and the app's domain models via the Mapper.
+// It delegates remote operations to a ShipmentStore (normally the Turvo API
+// client) and converts between Turvo models and the app's domain models via
+// the Mapper.
 type LoadHandler struct {
-	TurvoClient *turvo.Client
+	Shipments   ShipmentStore
+	Customers   CustomerDirectory
 	TurvoMapper *turvo.Mapper
+	Locations   *turvo.LocationResolver
+	// Policy decides which callers may use each route and for which
+	// customers; nil allows everything.
+	Policy *authz.Policy
+	// Idempotency stores responses to creates sent with an Idempotency-Key;
+	// nil ignores the header.
+	Idempotency idempotency.Store
+	// BulkConcurrency caps concurrent creates in a bulk upload.
+	BulkConcurrency int
+	// CursorKey signs list cursors. NewLoadHandler sets a random key, which
+	// only works while every page is served by the same process.
+	CursorKey []byte
+	// Events feeds GET /api/loads/events; nil answers it with 503.
+	Events *LoadEvents
+	// Jobs keeps async bulk upload jobs for polling. NewLoadHandler sets an
+	// in-memory store, which only works while every poll reaches the
+	// instance running the job.
+	Jobs jobs.Store
 }
 
 // NewLoadHandler returns a fully wired LoadHandler instance.
-func NewLoadHandler(client *turvo.Client, mapper *turvo.Mapper) *LoadHandler {
+func NewLoadHandler(shipments ShipmentStore, customers CustomerDirectory, mapper *turvo.Mapper, locations *turvo.LocationResolver) *LoadHandler {
 	return &LoadHandler{
-		TurvoClient: client,
+		Shipments:   shipments,
+		Customers:   customers,
 		TurvoMapper: mapper,
+		Locations:   locations,
+
+		BulkConcurrency: DefaultBulkConcurrency,
+		CursorKey:       newCursorKey(),
+		Jobs:            jobs.NewMemoryStore(),
 	}
 }
 
@@ -34,22 +65,103 @@ func NewLoadHandler(client *turvo.Client, mapper *turvo.Mapper) *LoadHandler {
 // also exposes /api/customers for a minimal customer list used by the UI.
 func (h *LoadHandler) RegisterRoutes(r *chi.Mux) {
 	r.Route("/api/loads", func(r chi.Router) {
-		r.Get("/", h.ListLoads)
-		r.Post("/", h.CreateLoad)
-		r.Get("/{id}", h.GetLoadByID)
-		r.Get("/by-external/{externalTMSLoadID}", h.GetLoadByExternalID)
-		r.Put("/{id}", h.UpdateLoad) // Stretch goal
+		r.With(require(h.Policy, authz.LoadsRead)).Get("/", h.ListLoads)
+		r.With(require(h.Policy, authz.LoadsRead)).Get("/export", h.ExportLoads)
+		r.With(require(h.Policy, authz.LoadsRead)).Get("/events", h.StreamLoadEvents)
+		r.With(require(h.Policy, authz.LoadsCreate), idempotent(h.Idempotency)).Post("/", h.CreateLoad)
+		r.With(require(h.Policy, authz.LoadsCreate), idempotent(h.Idempotency)).Post("/bulk", h.BulkCreateLoads)
+		r.With(require(h.Policy, authz.LoadsCreate)).Get("/bulk/{jobID}", h.GetBulkJob)
+		r.With(require(h.Policy, authz.LoadsRead)).Get("/{id}", h.GetLoadByID)
+		r.With(require(h.Policy, authz.LoadsRead)).Get("/by-external/{externalTMSLoadID}", h.GetLoadByExternalID)
+		r.With(require(h.Policy, authz.LoadsUpdate)).Put("/{id}", h.UpdateLoad)
+		r.With(require(h.Policy, authz.LoadsAssignCarrier)).Post("/{id}/carrier", h.AssignCarrier)
 	})
-	r.Get("/api/customers", h.ListCustomers)
+	r.With(require(h.Policy, authz.CustomersRead)).Get("/api/customers", h.ListCustomers)
 }
 
 // ListLoads returns a paged list of loads. Query parameters are whitelisted
 // and forwarded to Turvo (e.g. start, pageSize, created[gte], status[eq], sortBy).
+// When more loads are available the response carries a nextCursor; passing it
+// back as ?cursor= returns the next page with the first page's filters,
+// continuing from Turvo's lastObjectKey so loads created in between do not
+// shift the pages.
 func (h *LoadHandler) ListLoads(w http.ResponseWriter, r *http.Request) {
-	log.Printf("ListLoads called")
+	q := r.URL.Query()
+	filters := listFilters(q)
+	var cur listCursor
+	if token := q.Get("cursor"); token != "" {
+		var err error
+		if cur, err = h.decodeCursor(token); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidCursor, err.Error())
+			return
+		}
+		if filters, err = url.ParseQuery(cur.Filters); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidCursor, errInvalidCursor.Error())
+			return
+		}
+		if v := q.Get("pageSize"); v != "" {
+			filters.Set("pageSize", v)
+		}
+	} else {
+		cur.Start, _ = strconv.Atoi(filters.Get("start"))
+		filters.Del("start")
+	}
+
+	forward := url.Values{}
+	for k, v := range filters {
+		forward[k] = v
+	}
+	if cur.Key != "" {
+		forward.Set("lastObjectKey", cur.Key)
+	}
+	if cur.Start > 0 {
+		forward.Set("start", strconv.Itoa(cur.Start))
+	}
+	allowed, restricted, ok := scopeQuery(w, r, forward)
+	if !ok {
+		return
+	}
+
+	shipments, meta, err := h.Shipments.ListShipmentsPageWithQuery(r.Context(), forward)
+	if err != nil {
+		writeTurvoError(w, "list", err)
+		return
+	}
+	enriched := h.withDetails(r.Context(), shipments)
+	var loads []*domain.Load
+	for _, s := range enriched {
+		if restricted && !slices.Contains(allowed, s.CustomerID()) {
+			// Turvo applied the filter; this only guards against it being ignored
+			continue
+		}
+		l, _ := h.TurvoMapper.FromTurvoShipment(s)
+		loads = append(loads, l)
+	}
+	pagination := map[string]any{
+		"start":              meta.Start,
+		"pageSize":           meta.PageSize,
+		"totalRecordsInPage": meta.TotalRecordsInPage,
+		"moreAvailable":      meta.MoreAvailable,
+	}
+	if meta.MoreAvailable && len(shipments) > 0 {
+		next := listCursor{Filters: filters.Encode(), Key: cur.Key, Start: cur.Start + len(shipments)}
+		if meta.LastObjectKey != "" {
+			next.Key, next.Start = meta.LastObjectKey, 0
+		}
+		pagination["nextCursor"] = h.encodeCursor(next)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items":      loads,
+		"pagination": pagination,
+	})
+}
+
+// listFilters returns the request's whitelisted list parameters, defaulting
+// to loads created in the last 90 days and a page of 24.
+func listFilters(q url.Values) url.Values {
 	// Build query for Turvo with whitelist
 	forward := url.Values{}
-	q := r.URL.Query()
 	// pagination
 	if v := q.Get("start"); v != "" {
 		forward.Set("start", v)
@@ -77,21 +189,36 @@ func (h *LoadHandler) ListLoads(w http.ResponseWriter, r *http.Request) {
 	if forward.Get("pageSize") == "" {
 		forward.Set("pageSize", "24")
 	}
+	return forward
+}
 
-	log.Printf("About to call ListShipmentsPageWithQuery")
-	shipments, meta, err := h.TurvoClient.ListShipmentsPageWithQuery(r.Context(), forward)
-	if err != nil {
-		if rl, ok := err.(turvo.RateLimitedError); ok {
-			if rl.RetryAfter > 0 {
-				w.Header().Set("Retry-After", rl.RetryAfter.Round(time.Second).String())
+// scopeQuery narrows a list query to the caller's customers. It writes a 403
+// and reports !ok when the query names a customer the caller may not see.
+func scopeQuery(w http.ResponseWriter, r *http.Request, forward url.Values) (allowed []int, restricted, ok bool) {
+	// Callers limited to some customers only see those customers' loads
+	allowed, restricted, ok = customerScope(w, r, authz.LoadsRead)
+	if !ok {
+		return nil, false, false
+	}
+	if restricted {
+		if v := forward.Get("customerId[eq]"); v != "" {
+			id, _ := strconv.Atoi(v)
+			if !allowCustomer(w, r, authz.LoadsRead, id) {
+				return nil, false, false
 			}
-			http.Error(w, rl.Error(), http.StatusTooManyRequests)
-			return
+		} else if len(allowed) == 1 {
+			forward.Set("customerId[eq]", strconv.Itoa(allowed[0]))
+		} else {
+			forward.Set("customerId[in]", joinInts(allowed))
 		}
-		http.Error(w, "turvo list error: "+err.Error(), http.StatusBadGateway)
-		return
 	}
-	// Fetch full details for each shipment to obtain lane (pickup/destination)
+	return allowed, restricted, true
+}
+
+// withDetails fetches full details for list results that lack a lane, so
+// pickup and destination can be shown. Shipments whose details cannot be
+// fetched are returned as listed.
+func (h *LoadHandler) withDetails(ctx context.Context, shipments []turvo.Shipment) []turvo.Shipment {
 	type idxShipment struct {
 		idx int
 		s   turvo.Shipment
@@ -115,9 +242,9 @@ func (h *LoadHandler) ListLoads(w http.ResponseWriter, r *http.Request) {
 			sem <- struct{}{}
 			go func(i int, id int) {
 				defer func() { <-sem }()
-				ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+				ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 				defer cancel()
-				detail, err := h.TurvoClient.GetShipment(ctx, strconv.Itoa(id))
+				detail, err := h.Shipments.GetShipment(ctx, strconv.Itoa(id))
 				if err != nil || detail == nil {
 					results <- idxShipment{idx: i, s: shipments[i]}
 					return
@@ -130,21 +257,7 @@ func (h *LoadHandler) ListLoads(w http.ResponseWriter, r *http.Request) {
 			enriched[res.idx] = res.s
 		}
 	}
-	var loads []*domain.Load
-	for _, s := range enriched {
-		l, _ := h.TurvoMapper.FromTurvoShipment(s)
-		loads = append(loads, l)
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"items": loads,
-		"pagination": map[string]any{
-			"start":              meta.Start,
-			"pageSize":           meta.PageSize,
-			"totalRecordsInPage": meta.TotalRecordsInPage,
-			"moreAvailable":      meta.MoreAvailable,
-		},
-	})
+	return enriched
 }
 
 // CreateLoad creates a shipment in Turvo based on the posted Load payload.
@@ -152,17 +265,39 @@ func (h *LoadHandler) ListLoads(w http.ResponseWriter, r *http.Request) {
 func (h *LoadHandler) CreateLoad(w http.ResponseWriter, r *http.Request) {
 	var load domain.Load
 	if err := json.NewDecoder(r.Body).Decode(&load); err != nil {
-		http.Error(w, "invalid payload", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	customerID := h.TurvoMapper.CustomerID(&load)
+	if !allowCustomer(w, r, authz.LoadsCreate, customerID) {
+		return
+	}
+	// validate and check for duplicates before resolving locations, which
+	// can create them in Turvo
+	if _, err := h.TurvoMapper.ToTurvoShipment(&load); err != nil {
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+	if h.guardDuplicates(w, r, &load, customerID) {
+		return
+	}
+	if err := h.Locations.ResolveLoad(r.Context(), &load); err != nil {
+		writeTurvoError(w, "location", err)
 		return
 	}
 	shipment, err := h.TurvoMapper.ToTurvoShipment(&load)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
 		return
 	}
-	created, err := h.TurvoClient.CreateShipment(r.Context(), shipment)
+	ctx := r.Context()
+	if shipment.CustomID != "" {
+		// a retried create looks its customId up first, so it cannot duplicate
+		ctx = turvo.WithSafeRetry(ctx)
+	}
+	created, err := h.Shipments.CreateShipment(ctx, shipment)
 	if err != nil {
-		http.Error(w, "turvo create error: "+err.Error(), http.StatusBadGateway)
+		writeTurvoError(w, "create", err)
 		return
 	}
 	l, _ := h.TurvoMapper.FromTurvoShipment(*created)
@@ -174,9 +309,12 @@ func (h *LoadHandler) CreateLoad(w http.ResponseWriter, r *http.Request) {
 // GetLoadByID fetches a single shipment by Turvo id and maps it into a Load.
 func (h *LoadHandler) GetLoadByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	s, err := h.TurvoClient.GetShipment(r.Context(), id)
+	s, err := h.Shipments.GetShipment(r.Context(), id)
 	if err != nil {
-		http.Error(w, "turvo get error: "+err.Error(), http.StatusBadGateway)
+		writeTurvoError(w, "get", err)
+		return
+	}
+	if !allowCustomer(w, r, authz.LoadsRead, s.CustomerID()) {
 		return
 	}
 	l, _ := h.TurvoMapper.FromTurvoShipment(*s)
@@ -184,12 +322,16 @@ func (h *LoadHandler) GetLoadByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(l)
 }
 
-// GetLoadByExternalID finds a shipment by the external customId field.
+// GetLoadByExternalID finds a shipment by the external customId field. It
+// responds 404 when no shipment matches and 409 when the id is duplicated.
 func (h *LoadHandler) GetLoadByExternalID(w http.ResponseWriter, r *http.Request) {
 	externalID := chi.URLParam(r, "externalTMSLoadID")
-	s, err := h.TurvoClient.FindShipmentByExternalID(r.Context(), externalID)
+	s, err := h.Shipments.FindShipmentByExternalID(r.Context(), externalID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeTurvoError(w, "lookup", err)
+		return
+	}
+	if !allowCustomer(w, r, authz.LoadsRead, s.CustomerID()) {
 		return
 	}
 	l, _ := h.TurvoMapper.FromTurvoShipment(*s)
@@ -197,10 +339,133 @@ func (h *LoadHandler) GetLoadByExternalID(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(l)
 }
 
+// UpdateLoad applies a partial Load update to an existing Turvo shipment.
+// Only the top-level sections present in the payload are changed; nested
+// objects such as pickup are merged over the current values.
 func (h *LoadHandler) UpdateLoad(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "update load", "id": id})
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	fields := make(map[string]bool, len(raw))
+	for k := range raw {
+		fields[k] = true
+	}
+	existing, err := h.Shipments.GetShipment(r.Context(), id)
+	if err != nil {
+		writeTurvoError(w, "get", err)
+		return
+	}
+	if !allowCustomer(w, r, authz.LoadsUpdate, existing.CustomerID()) {
+		return
+	}
+	// Start from the current load so partial nested objects keep their values
+	load, _ := h.TurvoMapper.FromTurvoShipment(*existing)
+	if fields["stops"] {
+		// stops are replaced as a list; decoding over old elements would merge them
+		load.Stops = nil
+	}
+	if err := json.Unmarshal(body, load); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	// Changed addresses need a fresh location unless the caller sent one
+	if fields["pickup"] && !hasKey(raw["pickup"], "turvoLocationId") {
+		load.Pickup.TurvoLocationID = 0
+	}
+	if fields["consignee"] && !hasKey(raw["consignee"], "turvoLocationId") {
+		load.Consignee.TurvoLocationID = 0
+	}
+	// moving a load to another customer needs permission on that one too,
+	// checked before resolving locations, which can create them in Turvo
+	if !allowCustomer(w, r, authz.LoadsUpdate, h.TurvoMapper.UpdatedCustomerID(*existing, load, fields)) {
+		return
+	}
+	if fields["pickup"] || fields["consignee"] || fields["stops"] {
+		if err := h.Locations.ResolveLoad(r.Context(), load); err != nil {
+			writeTurvoError(w, "location", err)
+			return
+		}
+	}
+	shipment, err := h.TurvoMapper.ApplyLoadUpdate(*existing, load, fields)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+	updated, err := h.Shipments.UpdateShipment(r.Context(), id, shipment)
+	if err != nil {
+		writeTurvoError(w, "update", err)
+		return
+	}
+	l, _ := h.TurvoMapper.FromTurvoShipment(*updated)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+// joinInts formats ids as a comma-separated list for [in] filters.
+func joinInts(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// hasKey reports whether the JSON object in raw contains key.
+func hasKey(raw json.RawMessage, key string) bool {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return false
+	}
+	_, ok := m[key]
+	return ok
+}
+
+// AssignCarrier books a carrier on an existing shipment. Any previously
+// assigned carrier order is replaced, and the carrier rate, from the
+// optional rateData or the replaced order, is applied to the new one.
+func (h *LoadHandler) AssignCarrier(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req struct {
+		domain.Carrier
+		RateData *domain.RateData `json:"rateData,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	if _, err := h.TurvoMapper.ToTurvoCarrierOrder(&req.Carrier); err != nil {
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+	existing, err := h.Shipments.GetShipment(r.Context(), id)
+	if err != nil {
+		writeTurvoError(w, "get", err)
+		return
+	}
+	if !allowCustomer(w, r, authz.LoadsAssignCarrier, existing.CustomerID()) {
+		return
+	}
+	shipment, err := h.TurvoMapper.AssignCarrier(*existing, &req.Carrier, req.RateData)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+	updated, err := h.Shipments.UpdateShipment(r.Context(), id, shipment)
+	if err != nil {
+		writeTurvoError(w, "update", err)
+		return
+	}
+	l, _ := h.TurvoMapper.FromTurvoShipment(*updated)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
 }
 
 // ListCustomers proxies a minimal list of customers from Turvo for dropdowns.
@@ -212,11 +477,20 @@ func (h *LoadHandler) ListCustomers(w http.ResponseWriter, r *http.Request) {
 			forward.Set(key, v)
 		}
 	}
-	customers, err := h.TurvoClient.ListCustomers(r.Context(), forward)
+	allowed, restricted, ok := customerScope(w, r, authz.CustomersRead)
+	if !ok {
+		return
+	}
+	customers, err := h.Customers.ListCustomers(r.Context(), forward)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
+		writeTurvoError(w, "list customers", err)
 		return
 	}
+	if restricted {
+		customers = slices.DeleteFunc(customers, func(c turvo.MinimalCustomer) bool {
+			return !slices.Contains(allowed, c.ID)
+		})
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"items": customers})
 }
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo/turvotest"
+)
+
+func TestCreateLoad(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("EXT-1"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got domain.Load
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if got.ExternalTMSLoadID != "EXT-1" {
+		t.Errorf("externalTMSLoadID = %q", got.ExternalTMSLoadID)
+	}
+	stored := srv.Shipments()
+	if len(stored) != 1 || len(stored[0].GlobalRoute) != 2 {
+		t.Fatalf("stored shipments = %+v", stored)
+	}
+	if stored[0].GlobalRoute[0].Location.ID == 0 {
+		t.Error("pickup location was not resolved")
+	}
+}
+
+func TestRejectedCreateAddsNoLocations(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	if rec := serve(r, http.MethodPost, "/api/loads", testLoad("LOC-1")); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	before := srv.Calls(http.MethodPost, "locations")
+
+	invalid := testLoad("LOC-2")
+	invalid.Pickup.Name = "New DC"
+	invalid.Stops = []domain.Stop{
+		{StopType: domain.StopTypeDelivery, Name: "New DC", AddressLine1: "5 Oak St", City: "Denver", State: "CO"},
+	}
+	if rec := serve(r, http.MethodPost, "/api/loads", invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid status = %d, body %s", rec.Code, rec.Body)
+	}
+	duplicate := testLoad("LOC-1")
+	duplicate.Pickup.Name = "Other DC"
+	if rec := serve(r, http.MethodPost, "/api/loads", duplicate); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, body %s", rec.Code, rec.Body)
+	}
+	if n := srv.Calls(http.MethodPost, "locations") - before; n != 0 {
+		t.Errorf("rejected creates added %d locations", n)
+	}
+}
+
+func TestCreateLoadRejectsBadJSON(t *testing.T) {
+	r, _ := newTurvoLoadRouter(t)
+	if rec := serve(r, http.MethodPost, "/api/loads", "{"); rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != codeInvalidPayload {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateLoadTurvoValidationError(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	srv.InjectFault(http.MethodPost, "shipments", turvotest.Fault{
+		Status: http.StatusBadRequest,
+		Body:   `{"Status":"ERROR","details":{"errorCode":"VALIDATION_FAILED","errorMessage":"invalid","errors":[{"field":"lane.start","message":"required"}]}}`,
+	})
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("EXT-2"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	body := decodeError(t, rec)
+	if body.Code != codeValidationFailed || len(body.Fields) != 1 || body.Fields[0].Field != "lane.start" {
+		t.Fatalf("error body = %+v", body)
+	}
+}
+
+func TestGetLoadByIDNotFound(t *testing.T) {
+	r, _ := newTurvoLoadRouter(t)
+	rec := serve(r, http.MethodGet, "/api/loads/999", nil)
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != codeNotFound {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestGetLoadByExternalID(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	srv.AddShipment(turvo.Shipment{CustomID: "FOUND"})
+	srv.AddShipment(turvo.Shipment{CustomID: "TWICE"})
+	srv.AddShipment(turvo.Shipment{CustomID: "TWICE"})
+
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{"FOUND", http.StatusOK},
+		{"MISSING", http.StatusNotFound},
+		{"TWICE", http.StatusConflict},
+	} {
+		rec := serve(r, http.MethodGet, "/api/loads/by-external/"+tc.id, nil)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tc.id, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestListLoadsRateLimited(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	srv.InjectFault(http.MethodGet, "shipments/list", turvotest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "90"})
+	rec := serve(r, http.MethodGet, "/api/loads", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Retry-After = %q, want 90", got)
+	}
+}
+
+func TestUpdateLoadChangesOnlySentFields(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("UPD-1"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	id := srv.Shipments()[0].ID
+	before, _ := srv.Shipment(id)
+
+	rec = serve(r, http.MethodPut, "/api/loads/"+strconv.Itoa(id), map[string]any{"externalTMSLoadID": "UPD-2"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body %s", rec.Code, rec.Body)
+	}
+	after, _ := srv.Shipment(id)
+	if after.CustomID != "UPD-2" {
+		t.Errorf("customId = %q, want UPD-2", after.CustomID)
+	}
+	if len(after.GlobalRoute) != len(before.GlobalRoute) || after.Lane.Start != before.Lane.Start {
+		t.Errorf("route changed: before %+v after %+v", before.Lane, after.Lane)
+	}
+}
+
+func TestUpdateLoadMakesVanReefer(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	l := testLoad("REEF-1")
+	l.Specifications = &domain.Specifications{Hazmat: true}
+	if rec := serve(r, http.MethodPost, "/api/loads", l); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	id := srv.Shipments()[0].ID
+
+	// the load read back from Turvo names Van as its equipment
+	rec := serve(r, http.MethodPut, "/api/loads/"+strconv.Itoa(id), map[string]any{
+		"specifications": map[string]any{"reefer": true, "minTempFahrenheit": 34, "maxTempFahrenheit": 38},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got domain.Load
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if spec := got.Specifications; spec == nil || !spec.Reefer || spec.MinTempFahrenheit != 34 || spec.MaxTempFahrenheit != 38 {
+		t.Errorf("specifications = %+v", got.Specifications)
+	}
+	after, _ := srv.Shipment(id)
+	if eq := after.Equipment[0]; eq.Type.Value != "Reefer" || eq.Temp == nil || *eq.Temp != 34 {
+		t.Errorf("equipment = %+v", eq)
+	}
+}
+
+func TestAssignCarrierPricesCarrierOrder(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	l := testLoad("PAY-1")
+	l.RateData = &domain.RateData{CustomerLhRateUsd: 2000, CarrierLhRateUsd: 1500, CarrierMaxRate: 1600}
+	if rec := serve(r, http.MethodPost, "/api/loads", l); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	id := strconv.Itoa(srv.Shipments()[0].ID)
+
+	assign := func(body map[string]any) (domain.Load, turvo.Shipment) {
+		t.Helper()
+		rec := serve(r, http.MethodPost, "/api/loads/"+id+"/carrier", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("assign status = %d, body %s", rec.Code, rec.Body)
+		}
+		var got domain.Load
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		sid, _ := strconv.Atoi(id)
+		after, _ := srv.Shipment(sid)
+		return got, after
+	}
+	payable := func(s turvo.Shipment) float64 {
+		for _, co := range s.CarrierOrder {
+			if !co.Deleted && co.Costs != nil {
+				return co.Costs.TotalAmount
+			}
+		}
+		return 0
+	}
+
+	// the carrier rate is sent with the first carrier, as none was payable
+	got, after := assign(map[string]any{"turvoId": 77, "rateData": map[string]any{"carrierLhRateUsd": 1400}})
+	if payable(after) != 1400 {
+		t.Errorf("carrier costs = %+v", after.CarrierOrder)
+	}
+	if m := after.Margin; m == nil || m.TotalReceivableAmount != 2000 || m.TotalPayableAmount != 1400 || m.Amount != 600 || m.Value != 30 || m.MaxPay != 1600 {
+		t.Errorf("margin = %+v", after.Margin)
+	}
+	if rd := got.RateData; rd == nil || rd.CarrierLhRateUsd != 1400 || rd.NetProfitUsd != 600 {
+		t.Errorf("rate data = %+v", got.RateData)
+	}
+
+	// a replacement carrier keeps the rate
+	_, after = assign(map[string]any{"turvoId": 78})
+	if payable(after) != 1400 || after.Margin.Amount != 600 {
+		t.Errorf("after reassign: carrier orders %+v, margin %+v", after.CarrierOrder, after.Margin)
+	}
+}
+
+func TestListCustomers(t *testing.T) {
+	r, srv := newTurvoLoadRouter(t)
+	srv.AddCustomer(turvo.MinimalCustomer{ID: 1, Name: "Acme"})
+	rec := serve(r, http.MethodGet, "/api/customers", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got struct {
+		Items []turvo.MinimalCustomer `json:"items"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if len(got.Items) != 1 || got.Items[0].Name != "Acme" {
+		t.Fatalf("items = %+v", got.Items)
+	}
+}
+
+func TestLoadHandlerWithMemoryStore(t *testing.T) {
+	r := newLoadRouter(memstore.New())
+	rec := serve(r, http.MethodPost, "/api/loads", testLoad("MEM-1"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serve(r, http.MethodGet, "/api/loads/by-external/MEM-1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lookup status = %d, body %s", rec.Code, rec.Body)
+	}
+	if list := listLoads(t, serve(r, http.MethodGet, "/api/loads", nil)); len(list) != 1 || list[0].Pickup.City != "Chicago" {
+		t.Fatalf("list = %+v", list)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// OrderHandler exposes HTTP handlers for Turvo orders, from which shipments
+// are planned.
+type OrderHandler struct {
+	Orders      OrderStore
+	TurvoMapper *turvo.Mapper
+	// Policy guards each route; nil allows everything.
+	Policy *authz.Policy
+}
+
+// NewOrderHandler returns a fully wired OrderHandler instance.
+func NewOrderHandler(orders OrderStore, mapper *turvo.Mapper) *OrderHandler {
+	return &OrderHandler{
+		Orders:      orders,
+		TurvoMapper: mapper,
+	}
+}
+
+// RegisterRoutes mounts all order endpoints under /api/orders. Callers whose
+// role restricts customers only reach their own customers' orders.
+func (h *OrderHandler) RegisterRoutes(r *chi.Mux) {
+	r.Route("/api/orders", func(r chi.Router) {
+		r.With(require(h.Policy, authz.OrdersRead)).Get("/", h.ListOrders)
+		r.With(require(h.Policy, authz.OrdersCreate)).Post("/", h.CreateOrder)
+		r.With(require(h.Policy, authz.OrdersRead)).Get("/{id}", h.GetOrderByID)
+	})
+}
+
+// ListOrders returns one page of orders. start, pageSize and a small set of
+// filters are forwarded to Turvo. For a caller restricted to some customers,
+// a customer filter must name one of them, and orders for others are
+// dropped from the page.
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	forward := url.Values{}
+	q := r.URL.Query()
+	for _, key := range []string{"start", "pageSize", "customerId[eq]", "status[eq]", "created[gte]", "updated[lte]"} {
+		if v := q.Get(key); v != "" {
+			forward.Set(key, v)
+		}
+	}
+	allowed, restricted, ok := customerScope(w, r, authz.OrdersRead)
+	if !ok {
+		return
+	}
+	if restricted {
+		if v := forward.Get("customerId[eq]"); v != "" {
+			id, _ := strconv.Atoi(v)
+			if !allowCustomer(w, r, authz.OrdersRead, id) {
+				return
+			}
+		} else if len(allowed) == 1 {
+			forward.Set("customerId[eq]", strconv.Itoa(allowed[0]))
+		}
+	}
+	orders, more, err := h.Orders.ListOrdersPage(r.Context(), forward)
+	if err != nil {
+		writeTurvoError(w, "list orders", err)
+		return
+	}
+	items := make([]*domain.Order, 0, len(orders))
+	for _, o := range orders {
+		if restricted && !slices.Contains(allowed, o.Customer.ID) {
+			continue
+		}
+		items = append(items, h.TurvoMapper.FromTurvoOrder(o))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"items":      items,
+		"pagination": map[string]any{"moreAvailable": more},
+	})
+}
+
+// CreateOrder creates an order in Turvo from the posted Order payload,
+// linking any shipmentIds given.
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var order domain.Order
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	to, err := h.TurvoMapper.ToTurvoOrder(&order)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+	if !allowCustomer(w, r, authz.OrdersCreate, to.Customer.ID) {
+		return
+	}
+	created, err := h.Orders.CreateOrder(r.Context(), to)
+	if err != nil {
+		writeTurvoError(w, "create order", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.TurvoMapper.FromTurvoOrder(*created))
+}
+
+// GetOrderByID fetches a single order by Turvo id.
+func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	o, err := h.Orders.GetOrder(r.Context(), id)
+	if err != nil {
+		writeTurvoError(w, "get order", err)
+		return
+	}
+	if !allowCustomer(w, r, authz.OrdersRead, o.Customer.ID) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.TurvoMapper.FromTurvoOrder(*o))
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/authz"
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+var (
+	_ ShipmentStore     = (*memstore.Store)(nil)
+	_ CustomerDirectory = (*memstore.Store)(nil)
+	_ OrderStore        = (*memstore.Store)(nil)
+)
+
+func TestOrderHandlerRoundTrip(t *testing.T) {
+	store := memstore.New()
+	h := NewOrderHandler(store, turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500}))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	rec := serve(r, http.MethodPost, "/api/orders", domain.Order{
+		ExternalID:  "PO-9",
+		Origin:      domain.Stop{City: "Chicago", State: "IL"},
+		Destination: domain.Stop{City: "Dallas", State: "TX"},
+		Items:       []domain.OrderItem{{Name: "Widgets", Quantity: 10, Units: "pallets"}},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	var created domain.Order
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.TurvoID == 0 || created.ExternalID != "PO-9" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	rec = serve(r, http.MethodGet, "/api/orders/"+strconv.Itoa(created.TurvoID), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec = serve(r, http.MethodGet, "/api/orders/1", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing order status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateOrderRequiresItems(t *testing.T) {
+	h := NewOrderHandler(memstore.New(), turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500}))
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	rec := serve(r, http.MethodPost, "/api/orders", domain.Order{ExternalID: "PO-10"})
+	if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != codeValidationFailed {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestOrdersRestrictedToAssignedCustomers(t *testing.T) {
+	policy := authz.DefaultPolicy()
+	policy.Roles["order-rep"] = authz.Role{Permissions: []authz.Permission{authz.OrdersRead, authz.OrdersCreate}, RestrictCustomers: true}
+	policy.Subjects["rep"] = authz.Subject{Roles: []string{"order-rep"}, Customers: []int{7}}
+	policy.Subjects["dispatcher"] = authz.Subject{Roles: []string{"dispatcher"}}
+	h := NewOrderHandler(memstore.New(), turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500}))
+	h.Policy = policy
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	rep := asCaller(r, "rep")
+	order := func(externalID string, customerID int) domain.Order {
+		return domain.Order{
+			ExternalID: externalID,
+			Customer:   domain.Party{TurvoID: customerID},
+			Items:      []domain.OrderItem{{Name: "Widgets", Quantity: 1}},
+		}
+	}
+
+	if rec := serve(rep, http.MethodPost, "/api/orders", order("PO-7", 7)); rec.Code != http.StatusCreated {
+		t.Fatalf("own customer status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec := serve(rep, http.MethodPost, "/api/orders", order("PO-8", 8))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("other customer status = %d, body %s", rec.Code, rec.Body)
+	}
+	if e := decodeError(t, rec); e.CustomerID != 8 || e.Permission != string(authz.OrdersCreate) {
+		t.Errorf("error = %+v", e)
+	}
+
+	rec = serve(asCaller(r, "dispatcher"), http.MethodPost, "/api/orders", order("PO-8", 8))
+	var other domain.Order
+	json.Unmarshal(rec.Body.Bytes(), &other)
+	if rec := serve(rep, http.MethodGet, "/api/orders/"+strconv.Itoa(other.TurvoID), nil); rec.Code != http.StatusForbidden {
+		t.Errorf("get other customer's order status = %d", rec.Code)
+	}
+	if rec := serve(rep, http.MethodGet, "/api/orders?customerId[eq]=8", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("filter on other customer status = %d", rec.Code)
+	}
+	var list struct {
+		Items []domain.Order `json:"items"`
+	}
+	json.Unmarshal(serve(rep, http.MethodGet, "/api/orders", nil).Body.Bytes(), &list)
+	if len(list.Items) != 1 || list.Items[0].ExternalID != "PO-7" {
+		t.Errorf("rep list = %+v", list.Items)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// ShipmentStore is the shipment persistence used by LoadHandler. It is
+// satisfied by *turvo.Client and by the in-memory memstore.Store.
+type ShipmentStore interface {
+	ListShipmentsPageWithQuery(ctx context.Context, q url.Values) ([]turvo.Shipment, turvo.PageInfo, error)
+	GetShipment(ctx context.Context, id string) (*turvo.Shipment, error)
+	CreateShipment(ctx context.Context, shipment turvo.Shipment) (*turvo.Shipment, error)
+	UpdateShipment(ctx context.Context, id string, shipment turvo.Shipment) (*turvo.Shipment, error)
+	FindShipmentByExternalID(ctx context.Context, externalID string) (*turvo.Shipment, error)
+}
+
+// CustomerDirectory lists customers for the UI's customer picker.
+type CustomerDirectory interface {
+	ListCustomers(ctx context.Context, q url.Values) ([]turvo.MinimalCustomer, error)
+}
+
+// OrderStore is the order persistence used by OrderHandler.
+type OrderStore interface {
+	ListOrdersPage(ctx context.Context, q url.Values) ([]turvo.Order, bool, error)
+	GetOrder(ctx context.Context, id string) (*turvo.Order, error)
+	CreateOrder(ctx context.Context, order turvo.Order) (*turvo.Order, error)
+}
+
+var (
+	_ ShipmentStore     = (*turvo.Client)(nil)
+	_ CustomerDirectory = (*turvo.Client)(nil)
+	_ OrderStore        = (*turvo.Client)(nil)
+)
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+)
+
+// TenantRouter sends each request to the routes built for the caller's
+// tenant, so handlers, clients and caches are never shared across tenants.
+type TenantRouter struct {
+	Tenants *tenant.Registry
+	// Header names the request header that selects a tenant.
+	Header string
+	// Resolve returns the tenant bound to the authenticated caller, "" when
+	// the caller is not bound to one, and false when there is no
+	// authenticated caller. A nil Resolve treats every request as anonymous.
+	Resolve func(*http.Request) (tenant string, ok bool)
+}
+
+// NewTenantRouter returns a TenantRouter over tenants that reads the tenant
+// id from header.
+func NewTenantRouter(tenants *tenant.Registry, header string) *TenantRouter {
+	return &TenantRouter{Tenants: tenants, Header: header}
+}
+
+// ServeHTTP picks the tenant from the authenticated caller, then the header,
+// then the registry default. A caller bound to one tenant gets 403 when the
+// header names another. An authenticated caller bound to none only reaches
+// the default tenant once there is more than one; the header is not trusted
+// to choose for it. An unknown id gets 404.
+func (t *TenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(t.Header)
+	var bound string
+	authenticated := false
+	if t.Resolve != nil {
+		bound, authenticated = t.Resolve(r)
+	}
+	switch {
+	case bound != "":
+		if id != "" && id != bound {
+			writeError(w, http.StatusForbidden, codeForbidden, "caller is not allowed to use tenant "+id)
+			return
+		}
+		id = bound
+	case authenticated && id != "" && t.Tenants.Len() > 1:
+		if def := t.Tenants.Default(); def == nil || id != def.ID {
+			writeError(w, http.StatusForbidden, codeForbidden, "caller is not bound to a tenant and may not select tenant "+id)
+			return
+		}
+	}
+	ten := t.Tenants.Default()
+	if id != "" {
+		var ok bool
+		if ten, ok = t.Tenants.Get(id); !ok {
+			writeError(w, http.StatusNotFound, codeUnknownTenant, "unknown tenant "+id)
+			return
+		}
+	}
+	if ten == nil {
+		writeError(w, http.StatusBadRequest, codeUnknownTenant, "tenant is required")
+		return
+	}
+	ctx := tenant.WithTenant(r.Context(), ten)
+	// the tenant's router starts with a fresh chi routing context
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, (*chi.Context)(nil))
+	slog.DebugContext(ctx, "Tenant selected", "tenant", ten.ID)
+	ten.Handler.ServeHTTP(w, r.WithContext(ctx))
+}
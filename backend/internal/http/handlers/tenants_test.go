@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/auth"
+	"github.com/maceo-kwik/drumkit/backend/internal/memstore"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+)
+
+func newTestTenantRouter(ids ...string) *TenantRouter {
+	reg := tenant.NewRegistry(ids[0])
+	for _, id := range ids {
+		reg.Add(&tenant.Tenant{ID: id, Mapper: testMapper(), Handler: newLoadRouter(memstore.New())})
+	}
+	return NewTenantRouter(reg, "X-Tenant-ID")
+}
+
+func TestTenantRouterIsolatesTenants(t *testing.T) {
+	tr := newTestTenantRouter("east", "west")
+	if rec := serve(mountAPI(tr), http.MethodPost, "/api/loads", testLoad("WEST-1"), "X-Tenant-ID", "west"); rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := listExternalIDs(t, serve(mountAPI(tr), http.MethodGet, "/api/loads", nil, "X-Tenant-ID", "west")); len(got) != 1 || got[0] != "WEST-1" {
+		t.Errorf("west loads = %v", got)
+	}
+	// no header selects the default tenant, east
+	if got := listExternalIDs(t, serve(mountAPI(tr), http.MethodGet, "/api/loads", nil)); len(got) != 0 {
+		t.Errorf("east loads = %v", got)
+	}
+	if rec := serve(mountAPI(tr), http.MethodGet, "/api/loads/by-external/WEST-1", nil, "X-Tenant-ID", "east"); rec.Code != http.StatusNotFound {
+		t.Errorf("cross-tenant lookup status = %d", rec.Code)
+	}
+}
+
+func TestTenantRouterRejectsUnknownAndForeignTenants(t *testing.T) {
+	tr := newTestTenantRouter("east", "west")
+	if rec := serve(mountAPI(tr), http.MethodGet, "/api/loads", nil, "X-Tenant-ID", "north"); rec.Code != http.StatusNotFound || decodeError(t, rec).Code != codeUnknownTenant {
+		t.Fatalf("unknown tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	tr.Resolve = func(*http.Request) (string, bool) { return "east", true }
+	if rec := serve(mountAPI(tr), http.MethodGet, "/api/loads", nil, "X-Tenant-ID", "west"); rec.Code != http.StatusForbidden {
+		t.Fatalf("foreign tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serve(mountAPI(tr), http.MethodGet, "/api/loads", nil, "X-Tenant-ID", "east"); rec.Code != http.StatusOK {
+		t.Fatalf("own tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTenantRouterRefusesHeaderForUnboundCallers(t *testing.T) {
+	tr := newTestTenantRouter("east", "west")
+	tr.Resolve = func(r *http.Request) (string, bool) {
+		id, ok := auth.FromContext(r.Context())
+		return id.Tenant, ok
+	}
+	keys, err := auth.ParseAPIKeys([]string{"reports:0123456789abcdef", "west-sync:fedcba9876543210:west"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Authenticate(auth.NewAuthenticator(nil, keys), false)(tr)
+	withKey := func(key, tenantID string) *httptest.ResponseRecorder {
+		return serve(mountAPI(h), http.MethodGet, "/api/loads", nil, auth.APIKeyHeader, key, "X-Tenant-ID", tenantID)
+	}
+
+	if rec := withKey("0123456789abcdef", "west"); rec.Code != http.StatusForbidden || decodeError(t, rec).Code != codeForbidden {
+		t.Fatalf("unbound key, second tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+	for _, id := range []string{"", "east"} {
+		if rec := withKey("0123456789abcdef", id); rec.Code != http.StatusOK {
+			t.Fatalf("unbound key, default tenant %q: status = %d, body %s", id, rec.Code, rec.Body)
+		}
+	}
+	if rec := withKey("fedcba9876543210", "west"); rec.Code != http.StatusOK {
+		t.Fatalf("bound key, own tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := withKey("fedcba9876543210", "east"); rec.Code != http.StatusForbidden {
+		t.Fatalf("bound key, other tenant: status = %d, body %s", rec.Code, rec.Body)
+	}
+	// anonymous callers, allowed when AUTH_REQUIRED=false, still choose
+	if rec := serve(mountAPI(h), http.MethodGet, "/api/loads", nil, "X-Tenant-ID", "west"); rec.Code != http.StatusOK {
+		t.Fatalf("anonymous: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
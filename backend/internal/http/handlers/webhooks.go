@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// webhookTolerance bounds how old (or how far in the future) a signed webhook
+// timestamp may be.
+const webhookTolerance = 5 * time.Minute
+
+// maxWebhookBody caps the webhook payload size read from the request.
+const maxWebhookBody = 1 << 20
+
+// WebhookHandler receives signed Turvo webhooks, verifies them against the
+// shared secret, drops replays, and forwards typed events to the tenant's
+// Dispatcher.
+type WebhookHandler struct {
+	Secret  string
+	Tenants *tenant.Registry
+	// Replays remembers delivered event ids. Only a shared store, such as
+	// the DynamoDB idempotency table, catches a replay sent to another
+	// instance of the service.
+	Replays idempotency.Store
+
+	now func() time.Time
+}
+
+// NewWebhookHandler returns a WebhookHandler using secret for verification
+// and replays to remember event ids.
+func NewWebhookHandler(secret string, tenants *tenant.Registry, replays idempotency.Store) *WebhookHandler {
+	return &WebhookHandler{
+		Secret:  secret,
+		Tenants: tenants,
+		Replays: replays,
+		now:     time.Now,
+	}
+}
+
+// RegisterRoutes mounts POST /webhooks/turvo for the default tenant and
+// POST /webhooks/turvo/{tenant} for the others.
+func (h *WebhookHandler) RegisterRoutes(r *chi.Mux) {
+	r.Post("/webhooks/turvo", h.ReceiveTurvo)
+	r.Post("/webhooks/turvo/{tenant}", h.ReceiveTurvo)
+}
+
+// ReceiveTurvo verifies and dispatches a single Turvo webhook delivery.
+// Unsigned or stale payloads get 401; a repeated event id gets 200 without
+// being dispatched again so Turvo stops retrying it. While another instance
+// is still handling the same event the answer is 409, so Turvo retries
+// later.
+func (h *WebhookHandler) ReceiveTurvo(w http.ResponseWriter, r *http.Request) {
+	if h.Secret == "" {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "webhooks not configured")
+		return
+	}
+	ten := h.Tenants.Default()
+	if id := chi.URLParam(r, "tenant"); id != "" {
+		var ok bool
+		if ten, ok = h.Tenants.Get(id); !ok {
+			writeError(w, http.StatusNotFound, codeUnknownTenant, "unknown tenant "+id)
+			return
+		}
+	}
+	if ten == nil || ten.Events == nil {
+		writeError(w, http.StatusNotFound, codeUnknownTenant, "tenant is required")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, "invalid payload")
+		return
+	}
+	sig := r.Header.Get(turvo.WebhookSignatureHeader)
+	ts := r.Header.Get(turvo.WebhookTimestampHeader)
+	if err := turvo.VerifyWebhookSignature(h.Secret, sig, ts, body, webhookTolerance, h.now()); err != nil {
+		slog.WarnContext(r.Context(), "Turvo webhook rejected", "error", err)
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "invalid signature")
+		return
+	}
+	ev, err := turvo.ParseWebhookEvent(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPayload, err.Error())
+		return
+	}
+
+	// client keys are stored hashed, so this readable key cannot collide
+	key := "webhook:turvo:" + ten.ID + ":" + ev.EventID()
+	claim, prior, err := h.Replays.Begin(r.Context(), key, idempotency.Hash(r.Method, "/webhooks/turvo", body))
+	switch {
+	case prior != nil, errors.Is(err, idempotency.ErrMismatch):
+		// the id was delivered before, possibly re-signed with a new timestamp
+		slog.InfoContext(r.Context(), "Turvo webhook replay ignored", "event_id", ev.EventID(), "tenant", ten.ID)
+		w.WriteHeader(http.StatusOK)
+		return
+	case errors.Is(err, idempotency.ErrInFlight):
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusConflict, codeInProgress, "event is being processed")
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Recording webhook event failed", "event_id", ev.EventID(), "error", err)
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "webhook store unavailable")
+		return
+	}
+	ten.Events.Dispatch(r.Context(), ev)
+	if err := h.Replays.Complete(r.Context(), key, claim, idempotency.Response{Status: http.StatusNoContent}); err != nil {
+		slog.ErrorContext(r.Context(), "Recording webhook event failed", "event_id", ev.EventID(), "error", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/maceo-kwik/drumkit/backend/internal/idempotency"
+	"github.com/maceo-kwik/drumkit/backend/internal/tenant"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+const testWebhookSecret = "whsec-test"
+
+var webhookNow = time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+
+// webhookFixture serves a WebhookHandler for tenants east (the default) and
+// west, and records the events each tenant receives.
+type webhookFixture struct {
+	router  *chi.Mux
+	replays *idempotency.MemoryStore
+	got     map[string][]turvo.Event
+}
+
+func newWebhookFixture() *webhookFixture {
+	f := &webhookFixture{router: chi.NewRouter(), replays: idempotency.NewMemoryStore(), got: make(map[string][]turvo.Event)}
+	reg := tenant.NewRegistry("east")
+	for _, id := range []string{"east", "west"} {
+		t := &tenant.Tenant{ID: id, Events: turvo.NewDispatcher()}
+		t.Events.Subscribe("", func(_ context.Context, ev turvo.Event) { f.got[id] = append(f.got[id], ev) })
+		reg.Add(t)
+	}
+	h := NewWebhookHandler(testWebhookSecret, reg, f.replays)
+	h.now = func() time.Time { return webhookNow }
+	h.RegisterRoutes(f.router)
+	return f
+}
+
+// deliver posts body signed at ts; sig overrides the signature when set.
+func (f *webhookFixture) deliver(target, body string, ts time.Time, sig string) *httptest.ResponseRecorder {
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	if sig == "" {
+		sig = "sha256=" + turvo.SignWebhook(testWebhookSecret, stamp, []byte(body))
+	}
+	return serve(f.router, http.MethodPost, target, body, turvo.WebhookSignatureHeader, sig, turvo.WebhookTimestampHeader, stamp)
+}
+
+const statusChangedBody = `{"id":"evt-3","type":"SHIPMENT_STATUS_CHANGED","createdAt":"2026-03-02T14:59:00Z",
+	"data":{"shipmentId":42,"customId":"LD-42","previousStatus":{"code":{"key":"2101","value":"Tendered"}},"status":{"code":{"key":"2102","value":"Covered"}}}}`
+
+func TestWebhookDispatchesEachEventType(t *testing.T) {
+	f := newWebhookFixture()
+	bodies := []string{
+		`{"id":"evt-1","type":"SHIPMENT_CREATED","data":{"shipment":{"id":42,"customId":"LD-42"}}}`,
+		`{"id":"evt-2","type":"SHIPMENT_UPDATED","data":{"shipment":{"id":42,"customId":"LD-42"}}}`,
+		statusChangedBody,
+	}
+	for _, b := range bodies {
+		if rec := f.deliver("/webhooks/turvo", b, webhookNow, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+	}
+	got := f.got["east"]
+	if len(got) != 3 || len(f.got["west"]) != 0 {
+		t.Fatalf("east got %d events, west %d", len(got), len(f.got["west"]))
+	}
+	if ev, ok := got[0].(turvo.ShipmentCreatedEvent); !ok || ev.ID != "evt-1" || ev.Shipment.ID != 42 || ev.Shipment.CustomID != "LD-42" {
+		t.Errorf("created = %#v", got[0])
+	}
+	if ev, ok := got[1].(turvo.ShipmentUpdatedEvent); !ok || ev.ID != "evt-2" || ev.Shipment.ID != 42 {
+		t.Errorf("updated = %#v", got[1])
+	}
+	ev, ok := got[2].(turvo.ShipmentStatusChangedEvent)
+	if !ok || ev.ShipmentID != 42 || ev.CustomID != "LD-42" || ev.Status.Code.Value != "Covered" || ev.PreviousStatus.Code.Value != "Tendered" {
+		t.Errorf("status changed = %#v", got[2])
+	}
+	if !ev.CreatedAt.Equal(time.Date(2026, 3, 2, 14, 59, 0, 0, time.UTC)) {
+		t.Errorf("createdAt = %v", ev.CreatedAt)
+	}
+
+	if rec := f.deliver("/webhooks/turvo", `{"id":"evt-4","type":"CARRIER_UPDATED","data":{}}`, webhookNow, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown type status = %d", rec.Code)
+	}
+}
+
+func TestWebhookRoutesToNamedTenant(t *testing.T) {
+	f := newWebhookFixture()
+	if rec := f.deliver("/webhooks/turvo/west", statusChangedBody, webhookNow, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(f.got["west"]) != 1 || len(f.got["east"]) != 0 {
+		t.Errorf("west got %d events, east %d", len(f.got["west"]), len(f.got["east"]))
+	}
+	if rec := f.deliver("/webhooks/turvo/north", statusChangedBody, webhookNow, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown tenant status = %d", rec.Code)
+	}
+}
+
+func TestWebhookRejectsBadSignatures(t *testing.T) {
+	f := newWebhookFixture()
+	for name, sig := range map[string]string{
+		"wrong secret": "sha256=" + turvo.SignWebhook("other-secret", strconv.FormatInt(webhookNow.Unix(), 10), []byte(statusChangedBody)),
+		"not hex":      "sha256=zz",
+		"other body":   "sha256=" + turvo.SignWebhook(testWebhookSecret, strconv.FormatInt(webhookNow.Unix(), 10), []byte("{}")),
+	} {
+		if rec := f.deliver("/webhooks/turvo", statusChangedBody, webhookNow, sig); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d", name, rec.Code)
+		}
+	}
+	if len(f.got["east"]) != 0 {
+		t.Errorf("dispatched %d events", len(f.got["east"]))
+	}
+}
+
+func TestWebhookTimestampTolerance(t *testing.T) {
+	f := newWebhookFixture()
+	for _, ts := range []time.Time{webhookNow.Add(-5*time.Minute - time.Second), webhookNow.Add(5*time.Minute + time.Second)} {
+		if rec := f.deliver("/webhooks/turvo", statusChangedBody, ts, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("signed at %v: status = %d", ts, rec.Code)
+		}
+	}
+	if rec := f.deliver("/webhooks/turvo", statusChangedBody, webhookNow.Add(-5*time.Minute), ""); rec.Code != http.StatusNoContent {
+		t.Errorf("signed 5 minutes ago: status = %d", rec.Code)
+	}
+}
+
+func TestWebhookIgnoresReplays(t *testing.T) {
+	f := newWebhookFixture()
+	if rec := f.deliver("/webhooks/turvo", statusChangedBody, webhookNow, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("first delivery status = %d", rec.Code)
+	}
+	// the same event, and the same event re-signed later, are acknowledged
+	// without being dispatched again
+	for _, ts := range []time.Time{webhookNow, webhookNow.Add(-time.Minute)} {
+		if rec := f.deliver("/webhooks/turvo", statusChangedBody, ts, ""); rec.Code != http.StatusOK {
+			t.Errorf("replay status = %d", rec.Code)
+		}
+	}
+	if len(f.got["east"]) != 1 {
+		t.Errorf("dispatched %d times", len(f.got["east"]))
+	}
+	// event ids are per tenant
+	if rec := f.deliver("/webhooks/turvo/west", statusChangedBody, webhookNow, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("other tenant status = %d", rec.Code)
+	}
+}
+
+func TestWebhookInFlightOnAnotherInstance(t *testing.T) {
+	f := newWebhookFixture()
+	// another instance sharing the store has claimed the event
+	hash := idempotency.Hash(http.MethodPost, "/webhooks/turvo", []byte(statusChangedBody))
+	if _, _, err := f.replays.Begin(context.Background(), "webhook:turvo:east:evt-3", hash); err != nil {
+		t.Fatal(err)
+	}
+	if rec := f.deliver("/webhooks/turvo", statusChangedBody, webhookNow, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("in flight status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(f.got["east"]) != 0 {
+		t.Errorf("dispatched %d events", len(f.got["east"]))
+	}
+}
+
+func TestWebhookNotConfigured(t *testing.T) {
+	f := newWebhookFixture()
+	h := NewWebhookHandler("", tenant.NewRegistry("east"), f.replays)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	if rec := serve(r, http.MethodPost, "/webhooks/turvo", statusChangedBody); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,174 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoStore keeps keys in a DynamoDB table so every instance of the service
+// sees the same claims and responses. The table needs a string partition key
+// named "key"; enable DynamoDB TTL on the "expires" attribute to have old
+// records removed.
+type DynamoStore struct {
+	DB      dynamodbiface.DynamoDBAPI
+	Table   string
+	TTL     time.Duration
+	LockTTL time.Duration
+
+	now func() time.Time
+}
+
+// NewDynamoStore returns a DynamoStore for table in region with the default
+// lifetimes.
+func NewDynamoStore(region, table string) (*DynamoStore, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("create aws session: %w", err)
+	}
+	return &DynamoStore{DB: dynamodb.New(sess), Table: table, TTL: DefaultTTL, LockTTL: DefaultLockTTL}, nil
+}
+
+func (s *DynamoStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Begin implements Store. The claim is a conditional put that only succeeds
+// when the key is new, expired, or held by an abandoned request. It records
+// a claim token, so a request whose claim was taken over cannot complete or
+// release the key for the one that took it.
+func (s *DynamoStore) Begin(ctx context.Context, key, hash string) (string, *Response, error) {
+	now := s.clock()
+	claim := newClaim()
+	_, err := s.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"key":         {S: aws.String(key)},
+			"hash":        {S: aws.String(hash)},
+			"claim":       {S: aws.String(claim)},
+			"lockedUntil": unixAttr(now.Add(s.LockTTL)),
+			"expires":     unixAttr(now.Add(s.TTL)),
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#k) OR #e < :now OR (attribute_not_exists(#r) AND #l < :now)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#k": aws.String("key"), "#e": aws.String("expires"), "#r": aws.String("response"), "#l": aws.String("lockedUntil"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":now": unixAttr(now)},
+	})
+	if err == nil {
+		return claim, nil, nil
+	}
+	if !isConditionFailed(err) {
+		return "", nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+
+	out, err := s.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("read idempotency key: %w", err)
+	}
+	item := out.Item
+	if item == nil {
+		// released between the put and the get; let the caller retry
+		return "", nil, ErrInFlight
+	}
+	if aws.StringValue(item["hash"].S) != hash {
+		return "", nil, ErrMismatch
+	}
+	r, ok := item["response"]
+	if !ok {
+		return "", nil, ErrInFlight
+	}
+	var resp Response
+	if err := json.Unmarshal(r.B, &resp); err != nil {
+		return "", nil, fmt.Errorf("decode stored response: %w", err)
+	}
+	return "", &resp, nil
+}
+
+// heldCondition matches an item that claim still holds without a response.
+const heldCondition = "#c = :c AND attribute_not_exists(#r)"
+
+// Extend implements Store.
+func (s *DynamoStore) Extend(ctx context.Context, key, claim string) error {
+	_, err := s.DB.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(s.Table),
+		Key:                       map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		UpdateExpression:          aws.String("SET #l = :l"),
+		ConditionExpression:       aws.String(heldCondition),
+		ExpressionAttributeNames:  map[string]*string{"#c": aws.String("claim"), "#r": aws.String("response"), "#l": aws.String("lockedUntil")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String(claim)}, ":l": unixAttr(s.clock().Add(s.LockTTL))},
+	})
+	switch {
+	case isConditionFailed(err):
+		return ErrClaimLost
+	case err != nil:
+		return fmt.Errorf("extend idempotency claim: %w", err)
+	}
+	return nil
+}
+
+// Complete implements Store.
+func (s *DynamoStore) Complete(ctx context.Context, key, claim string, resp Response) error {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.Table),
+		Key:                      map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		UpdateExpression:         aws.String("SET #r = :r, #e = :e"),
+		ConditionExpression:      aws.String(heldCondition),
+		ExpressionAttributeNames: map[string]*string{"#c": aws.String("claim"), "#r": aws.String("response"), "#e": aws.String("expires")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":c": {S: aws.String(claim)}, ":r": {B: b}, ":e": unixAttr(s.clock().Add(s.TTL)),
+		},
+	})
+	switch {
+	case isConditionFailed(err):
+		return ErrClaimLost
+	case err != nil:
+		return fmt.Errorf("store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release implements Store. A key that already has a response, or that
+// another claim took over, is kept.
+func (s *DynamoStore) Release(ctx context.Context, key, claim string) error {
+	_, err := s.DB.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(s.Table),
+		Key:                       map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		ConditionExpression:       aws.String(heldCondition),
+		ExpressionAttributeNames:  map[string]*string{"#c": aws.String("claim"), "#r": aws.String("response")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":c": {S: aws.String(claim)}},
+	})
+	if err != nil && !isConditionFailed(err) {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func unixAttr(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+}
+
+func isConditionFailed(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
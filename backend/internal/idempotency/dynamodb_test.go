@@ -0,0 +1,40 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/maceo-kwik/drumkit/backend/internal/dynamotest"
+)
+
+func newTestDynamoStore() (*DynamoStore, *dynamotest.Table, func(time.Duration)) {
+	table := dynamotest.New("key")
+	now := time.Now()
+	s := &DynamoStore{DB: table, Table: "idempotency", TTL: DefaultTTL, LockTTL: DefaultLockTTL,
+		now: func() time.Time { return now }}
+	return s, table, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestDynamoStore(t *testing.T) {
+	s, _, advance := newTestDynamoStore()
+	testStore(t, s, advance)
+}
+
+func TestDynamoStoreAbandonedClaim(t *testing.T) {
+	s, _, advance := newTestDynamoStore()
+	testStoreAbandonedClaim(t, s, advance)
+}
+
+func TestDynamoStoreRecordsClaim(t *testing.T) {
+	s, table, _ := newTestDynamoStore()
+	claim, _, err := s.Begin(context.Background(), "k", "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := table.Item("k")
+	if aws.StringValue(item["claim"].S) != claim || aws.StringValue(item["hash"].S) != "h" || item["lockedUntil"] == nil || item["expires"] == nil {
+		t.Errorf("item = %v", item)
+	}
+}
@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps keys in process memory. It suits a single instance and
+// tests; records are lost on restart.
+type MemoryStore struct {
+	TTL     time.Duration
+	LockTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	swept   time.Time
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	hash        string
+	claim       string
+	resp        *Response
+	lockedUntil time.Time
+	expires     time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore with the default lifetimes.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		TTL:     DefaultTTL,
+		LockTTL: DefaultLockTTL,
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// Begin implements Store.
+func (s *MemoryStore) Begin(_ context.Context, key, hash string) (string, *Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && !s.expired(e, now) {
+		switch {
+		case e.hash != hash:
+			return "", nil, ErrMismatch
+		case e.resp != nil:
+			resp := *e.resp
+			return "", &resp, nil
+		default:
+			return "", nil, ErrInFlight
+		}
+	}
+	claim := newClaim()
+	s.entries[key] = &memoryEntry{hash: hash, claim: claim, lockedUntil: now.Add(s.LockTTL), expires: now.Add(s.TTL)}
+	return claim, nil, nil
+}
+
+// Extend implements Store.
+func (s *MemoryStore) Extend(_ context.Context, key, claim string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.held(key, claim)
+	if !ok {
+		return ErrClaimLost
+	}
+	e.lockedUntil = s.now().Add(s.LockTTL)
+	return nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(_ context.Context, key, claim string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.held(key, claim)
+	if !ok {
+		return ErrClaimLost
+	}
+	e.resp = &resp
+	e.expires = s.now().Add(s.TTL)
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(_ context.Context, key, claim string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.held(key, claim); ok {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// held returns the entry for key while claim holds it without a response.
+func (s *MemoryStore) held(key, claim string) (*memoryEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok || e.claim != claim || e.resp != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+func (s *MemoryStore) expired(e *memoryEntry, now time.Time) bool {
+	if e.resp == nil {
+		return now.After(e.lockedUntil)
+	}
+	return now.After(e.expires)
+}
+
+// sweep drops expired entries, at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+	for k, e := range s.entries {
+		if s.expired(e, now) {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMemoryStore() (*MemoryStore, func(time.Duration)) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, advance := newTestMemoryStore()
+	testStore(t, s, advance)
+}
+
+func TestMemoryStoreAbandonedClaim(t *testing.T) {
+	s, advance := newTestMemoryStore()
+	testStoreAbandonedClaim(t, s, advance)
+}
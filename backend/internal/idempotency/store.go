@@ -0,0 +1,79 @@
+// Package idempotency remembers the outcome of requests sent with an
+// Idempotency-Key so a retried request gets the original response instead of
+// repeating its side effects.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Header is the request header carrying the client's key.
+const Header = "Idempotency-Key"
+
+// Default lifetimes used by the stores when theirs are zero.
+const (
+	// DefaultTTL is how long a completed response is replayed.
+	DefaultTTL = 24 * time.Hour
+	// DefaultLockTTL is how long an in-flight claim blocks other requests
+	// before it is considered abandoned, e.g. after a crash. Requests that
+	// run longer renew their claim with Extend.
+	DefaultLockTTL = time.Minute
+)
+
+var (
+	// ErrInFlight means another request holds the key and has not finished.
+	ErrInFlight = errors.New("request with this key is in progress")
+	// ErrMismatch means the key was first used with a different request.
+	ErrMismatch = errors.New("key was used with a different request")
+	// ErrClaimLost means a claim expired and another request took its key.
+	ErrClaimLost = errors.New("claim on key was lost to another request")
+)
+
+// Response is a stored HTTP response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Store claims keys and records responses. Keys are opaque to the store;
+// callers scope them by tenant and caller.
+type Store interface {
+	// Begin claims key for a request whose contents hash to hash. It returns
+	// the stored response when the key already completed with the same hash,
+	// ErrMismatch when it was used with another hash, and ErrInFlight while
+	// another request holds it. A nil response and error mean the caller now
+	// holds the key under the returned claim and must Complete or Release it.
+	Begin(ctx context.Context, key, hash string) (claim string, resp *Response, err error)
+	// Extend keeps claim on key for another lock period. It returns
+	// ErrClaimLost when claim no longer holds key.
+	Extend(ctx context.Context, key, claim string) error
+	// Complete stores resp for key and releases claim. It returns
+	// ErrClaimLost, storing nothing, when claim no longer holds key.
+	Complete(ctx context.Context, key, claim string, resp Response) error
+	// Release drops claim without storing a response, so the request may be
+	// retried. A key claim no longer holds is left alone.
+	Release(ctx context.Context, key, claim string) error
+}
+
+// newClaim returns a random claim token.
+func newClaim() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Hash returns the fingerprint of a request used to detect a key reused with
+// a different request.
+func Hash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testStore runs the Store contract against s. advance moves s's clock.
+func testStore(t *testing.T, s Store, advance func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+
+	claim, resp, err := s.Begin(ctx, "k", "h1")
+	if claim == "" || resp != nil || err != nil {
+		t.Fatalf("first Begin = %q, %v, %v", claim, resp, err)
+	}
+	if _, _, err := s.Begin(ctx, "k", "h1"); !errors.Is(err, ErrInFlight) {
+		t.Fatalf("second Begin err = %v", err)
+	}
+	if _, _, err := s.Begin(ctx, "k", "h2"); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("other hash err = %v", err)
+	}
+	if err := s.Complete(ctx, "k", "other", Response{Status: 500}); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("Complete with another claim err = %v", err)
+	}
+
+	if err := s.Complete(ctx, "k", claim, Response{Status: 201, Body: []byte("done")}); err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err = s.Begin(ctx, "k", "h1")
+	if err != nil || resp == nil || resp.Status != 201 || string(resp.Body) != "done" {
+		t.Fatalf("replay = %+v, %v", resp, err)
+	}
+	s.Release(ctx, "k", claim)
+	if _, resp, _ := s.Begin(ctx, "k", "h1"); resp == nil {
+		t.Fatal("Release dropped a completed key")
+	}
+
+	advance(DefaultTTL + time.Second)
+	if claim, resp, err := s.Begin(ctx, "k", "h2"); claim == "" || resp != nil || err != nil {
+		t.Fatalf("Begin after expiry = %q, %v, %v", claim, resp, err)
+	}
+}
+
+// testStoreAbandonedClaim checks that a claim taken over after its lock
+// expired can no longer complete or release the key, and that Extend keeps
+// a claim past its lock.
+func testStoreAbandonedClaim(t *testing.T, s Store, advance func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+
+	stale, _, _ := s.Begin(ctx, "k", "h")
+	advance(DefaultLockTTL + time.Second)
+	claim, resp, err := s.Begin(ctx, "k", "h")
+	if claim == "" || claim == stale || resp != nil || err != nil {
+		t.Fatalf("Begin after lock expiry = %q, %v, %v", claim, resp, err)
+	}
+	if err := s.Complete(ctx, "k", stale, Response{Status: 201, Body: []byte("stale")}); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("stale Complete err = %v", err)
+	}
+	s.Release(ctx, "k", stale)
+	if _, _, err := s.Begin(ctx, "k", "h"); !errors.Is(err, ErrInFlight) {
+		t.Fatalf("Begin after stale Release err = %v", err)
+	}
+	if err := s.Extend(ctx, "k", stale); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("stale Extend err = %v", err)
+	}
+
+	// renewed halfway, the claim outlives its first lock period
+	advance(DefaultLockTTL / 2)
+	if err := s.Extend(ctx, "k", claim); err != nil {
+		t.Fatal(err)
+	}
+	advance(DefaultLockTTL/2 + 10*time.Second)
+	if _, _, err := s.Begin(ctx, "k", "h"); !errors.Is(err, ErrInFlight) {
+		t.Fatalf("Begin on renewed claim err = %v", err)
+	}
+	if err := s.Complete(ctx, "k", claim, Response{Status: 201, Body: []byte("fresh")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp, _ := s.Begin(ctx, "k", "h"); resp == nil || string(resp.Body) != "fresh" {
+		t.Fatalf("replay = %+v", resp)
+	}
+
+	claim, _, _ = s.Begin(ctx, "k2", "h")
+	s.Release(ctx, "k2", claim)
+	if _, _, err := s.Begin(ctx, "k2", "other"); err != nil {
+		t.Fatalf("Begin after Release err = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoStore keeps job state in a DynamoDB table shared by every instance.
+// The table needs a string partition key named "key"; enable DynamoDB TTL on
+// the "expires" attribute to have old jobs removed. It can share the
+// idempotency table as long as keys do not collide.
+type DynamoStore struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+	TTL   time.Duration
+
+	now func() time.Time
+}
+
+// NewDynamoStore returns a DynamoStore for table in region with the default
+// lifetime.
+func NewDynamoStore(region, table string) (*DynamoStore, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, fmt.Errorf("create aws session: %w", err)
+	}
+	return &DynamoStore{DB: dynamodb.New(sess), Table: table, TTL: DefaultTTL}, nil
+}
+
+func (s *DynamoStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Put implements Store.
+func (s *DynamoStore) Put(ctx context.Context, key string, state []byte) error {
+	_, err := s.DB.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.Table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"key":     {S: aws.String(key)},
+			"state":   {B: state},
+			"expires": {N: aws.String(strconv.FormatInt(s.clock().Add(s.TTL).Unix(), 10))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("store job state: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store. DynamoDB removes expired items lazily, so the
+// expiry is checked here too.
+func (s *DynamoStore) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.DB.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read job state: %w", err)
+	}
+	state, ok := out.Item["state"]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if e, ok := out.Item["expires"]; ok {
+		if n, err := strconv.ParseInt(aws.StringValue(e.N), 10, 64); err == nil && s.clock().Unix() > n {
+			return nil, ErrNotFound
+		}
+	}
+	return state.B, nil
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/maceo-kwik/drumkit/backend/internal/dynamotest"
+)
+
+func TestDynamoStore(t *testing.T) {
+	ctx := context.Background()
+	table := dynamotest.New("key")
+	now := time.Now()
+	s := &DynamoStore{DB: table, Table: "jobs", TTL: DefaultTTL, now: func() time.Time { return now }}
+
+	if _, err := s.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put err = %v", err)
+	}
+	if err := s.Put(ctx, "k", []byte("running")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(ctx, "k"); err != nil || string(got) != "running" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	// each Put restarts the lifetime
+	now = now.Add(DefaultTTL - time.Minute)
+	s.Put(ctx, "k", []byte("done"))
+	now = now.Add(2 * time.Minute)
+	if got, err := s.Get(ctx, "k"); err != nil || string(got) != "done" {
+		t.Fatalf("Get after update = %q, %v", got, err)
+	}
+	if e := table.Item("k")["expires"]; e == nil || aws.StringValue(e.N) == "" {
+		t.Errorf("item has no expiry: %v", table.Item("k"))
+	}
+
+	// DynamoDB removes expired items lazily, so Get checks the expiry
+	now = now.Add(DefaultTTL)
+	if _, err := s.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after expiry err = %v", err)
+	}
+}
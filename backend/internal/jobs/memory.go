@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps job state in process memory. It suits a single instance
+// and tests; state is lost on restart.
+type MemoryStore struct {
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore with the default lifetime.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{TTL: DefaultTTL, entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Put implements Store. Expired entries are dropped as new ones are added.
+func (s *MemoryStore) Put(_ context.Context, key string, state []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = memoryEntry{state: append([]byte(nil), state...), expires: now.Add(s.TTL)}
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || s.now().After(e.expires) {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), e.state...), nil
+}
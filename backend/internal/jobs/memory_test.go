@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put err = %v", err)
+	}
+	state := []byte("running")
+	s.Put(ctx, "k", state)
+	state[0] = 'R'
+	if got, err := s.Get(ctx, "k"); err != nil || string(got) != "running" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	// each Put restarts the lifetime
+	now = now.Add(DefaultTTL - time.Minute)
+	s.Put(ctx, "k", []byte("done"))
+	now = now.Add(2 * time.Minute)
+	if got, err := s.Get(ctx, "k"); err != nil || string(got) != "done" {
+		t.Fatalf("Get after update = %q, %v", got, err)
+	}
+
+	now = now.Add(DefaultTTL)
+	if _, err := s.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after expiry err = %v", err)
+	}
+	s.Put(ctx, "other", nil)
+	if len(s.entries) != 1 {
+		t.Errorf("expired entries kept: %d", len(s.entries))
+	}
+}
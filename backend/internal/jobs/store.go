@@ -0,0 +1,26 @@
+// Package jobs keeps the state of background jobs where every instance of
+// the service can read it, so a job started on one instance can be polled
+// through any other.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultTTL is how long job state is kept after its last update.
+const DefaultTTL = time.Hour
+
+// ErrNotFound means no state is stored for the key, or it expired.
+var ErrNotFound = errors.New("job not found")
+
+// Store saves job state under a key. State is opaque to the store; callers
+// scope keys by tenant.
+type Store interface {
+	// Put stores state for key, replacing any earlier state and restarting
+	// its lifetime.
+	Put(ctx context.Context, key string, state []byte) error
+	// Get returns the state stored for key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
@@ -0,0 +1,137 @@
+// Package logging configures the service's log/slog logger and masks secrets,
+// tokens and PII in everything it writes.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaxBodyLog is the number of body bytes logged at debug level.
+const MaxBodyLog = 2048
+
+const redacted = "[REDACTED]"
+
+// ParseLevel maps a LOG_LEVEL value (debug, info, warn, error) to a level.
+// Unknown values fall back to info.
+func ParseLevel(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// New returns a redacting logger writing to w at level. json selects the
+// JSON handler; otherwise the text handler is used.
+func New(w io.Writer, level string, json bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level), ReplaceAttr: Redact}
+	if json {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// sensitiveKeys are matched against attribute, header and JSON field names
+// after lowercasing and dropping '-' and '_'.
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "apikey", "cookie", "email", "phone", "signature"}
+
+// IsSensitiveKey reports whether values under key must not be logged.
+func IsSensitiveKey(key string) bool {
+	k := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	bearerRe    = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	jsonFieldRe = regexp.MustCompile(`"([A-Za-z_\-]*(?i:password|secret|token|authorization|api_?key|email|phone)[A-Za-z_\-]*)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+	queryRe     = regexp.MustCompile(`(?i)\b((?:client_)?secret|password|(?:access_|refresh_)?token|api_?key)=[^&\s"]+`)
+	emailRe     = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneRe     = regexp.MustCompile(`(?:\+?1[\s.\-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.\-])\d{3}[\s.\-]\d{4}\b`)
+)
+
+// RedactString masks bearer tokens, secret JSON fields and query parameters,
+// email addresses and phone numbers in s.
+func RedactString(s string) string {
+	s = bearerRe.ReplaceAllString(s, "Bearer "+redacted)
+	s = jsonFieldRe.ReplaceAllString(s, `"$1":"`+redacted+`"`)
+	s = queryRe.ReplaceAllString(s, "$1="+redacted)
+	s = emailRe.ReplaceAllString(s, redacted)
+	s = phoneRe.ReplaceAllString(s, redacted)
+	return s
+}
+
+// Redact is a slog ReplaceAttr function. It masks attributes with sensitive
+// names and scrubs string, error, header and query values.
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.TimeKey && a.Key != slog.LevelKey && IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, RedactString(v.Error()))
+		case http.Header:
+			return slog.Any(a.Key, RedactHeader(v))
+		case url.Values:
+			return slog.String(a.Key, RedactString(redactValues(v).Encode()))
+		case []byte:
+			return slog.String(a.Key, RedactString(string(v)))
+		}
+	}
+	return a
+}
+
+// RedactHeader returns a copy of h with sensitive headers masked.
+func RedactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if IsSensitiveKey(k) {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = vs
+	}
+	return out
+}
+
+func redactValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		if IsSensitiveKey(k) {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = vs
+	}
+	return out
+}
+
+// body defers truncation and redaction until the record is actually written,
+// so debug-only bodies cost nothing at higher levels.
+type body []byte
+
+// Body wraps a request or response body for logging. It is redacted and
+// then truncated to MaxBodyLog bytes. Log bodies at debug level only.
+func Body(b []byte) slog.LogValuer { return body(b) }
+
+func (b body) LogValue() slog.Value {
+	// redact first: a secret cut at the limit no longer matches the patterns
+	s := RedactString(string(b))
+	if len(s) > MaxBodyLog {
+		s = s[:MaxBodyLog] + "...(truncated)"
+	}
+	return slog.StringValue(s)
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type attrsKey struct{}
+
+// requestAttrs collects attributes added while a request is handled.
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// AddRequestAttrs adds attrs to the request's access log line, for example
+// the authenticated caller. It does nothing outside Middleware.
+func AddRequestAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if ra, ok := ctx.Value(attrsKey{}).(*requestAttrs); ok {
+		ra.mu.Lock()
+		ra.attrs = append(ra.attrs, attrs...)
+		ra.mu.Unlock()
+	}
+}
+
+// Middleware logs one line per request with method, path, status, size and
+// duration. Query strings are left out because filters can carry customer
+// data; they are logged at debug level.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ra := &requestAttrs{}
+			r = r.WithContext(context.WithValue(r.Context(), attrsKey{}, ra))
+			start := time.Now()
+			defer func() {
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", ww.Status()),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("duration", time.Since(start)),
+				}
+				if id := middleware.GetReqID(r.Context()); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+				ra.mu.Lock()
+				attrs = append(attrs, ra.attrs...)
+				ra.mu.Unlock()
+				level := slog.LevelInfo
+				if ww.Status() >= 500 {
+					level = slog.LevelWarn
+				}
+				logger.LogAttrs(r.Context(), level, "http request", attrs...)
+				if r.URL.RawQuery != "" {
+					logger.LogAttrs(r.Context(), slog.LevelDebug, "http request query", slog.Any("query", r.URL.Query()))
+				}
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,466 @@
+// Package memstore is an in-memory stand-in for the Turvo API. It satisfies
+// the handler store interfaces and turvo.LocationDirectory, and backs the
+// server's demo mode and handler tests.
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// Store keeps shipments, orders, customers and locations in memory. The zero
+// value is not usable; create one with New.
+type Store struct {
+	mu        sync.Mutex
+	nextID    int
+	shipments map[int]turvo.Shipment
+	orders    map[int]turvo.Order
+	locations map[int]turvo.LocationRecord
+	customers []turvo.MinimalCustomer
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		nextID:    10000,
+		shipments: make(map[int]turvo.Shipment),
+		orders:    make(map[int]turvo.Order),
+		locations: make(map[int]turvo.LocationRecord),
+	}
+}
+
+// AddCustomer adds a customer to the directory.
+func (s *Store) AddCustomer(c turvo.MinimalCustomer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.customers = append(s.customers, c)
+}
+
+// ListShipmentsPageWithQuery returns shipments newest first, or in sortBy
+// order. It honours start, pageSize, lastObjectKey, sortBy and the filters in
+// shipmentFilters; any other parameter, or a value it cannot parse, is a 400
+// *turvo.APIError rather than being ignored. Shipment ids serve as object
+// keys, so a page after lastObjectKey holds only older shipments; a sorted
+// listing has no lastObjectKey and is paged by start.
+func (s *Store) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) ([]turvo.Shipment, turvo.PageInfo, error) {
+	var pagination turvo.PageInfo
+	f, err := parseShipmentFilter(q)
+	if err != nil {
+		return nil, pagination, err
+	}
+	start, _ := strconv.Atoi(q.Get("start"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	before, _ := strconv.Atoi(q.Get("lastObjectKey"))
+
+	s.mu.Lock()
+	var matched []turvo.Shipment
+	for _, sh := range s.shipments {
+		if before > 0 && sh.ID >= before {
+			continue
+		}
+		if !f.match(sh) {
+			continue
+		}
+		matched = append(matched, clone(sh))
+	}
+	s.mu.Unlock()
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+	if f.sortKey != nil {
+		sort.SliceStable(matched, func(i, j int) bool {
+			c := strings.Compare(f.sortKey(matched[i]), f.sortKey(matched[j]))
+			if f.sortDesc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+
+	page := []turvo.Shipment{}
+	if start < len(matched) {
+		page = matched[start:min(start+pageSize, len(matched))]
+	}
+	pagination.Start = start
+	pagination.PageSize = pageSize
+	pagination.TotalRecordsInPage = len(page)
+	pagination.TotalRecords = len(matched)
+	pagination.MoreAvailable = start+len(page) < len(matched)
+	if len(page) > 0 && f.sortKey == nil {
+		pagination.LastObjectKey = strconv.Itoa(page[len(page)-1].ID)
+	}
+	return page, pagination, nil
+}
+
+// shipmentFilters are the list filters the store applies. Dates are RFC 3339
+// and bounds are inclusive; pickupDate and deliveryDate are the shipment's
+// start and end dates.
+var shipmentFilters = []string{
+	"customId[eq]", "customerId[eq]", "customerId[in]", "status[eq]", "status[in]", "locationId[eq]",
+	"created[gte]", "updated[lte]", "pickupDate[gte]", "pickupDate[lte]", "deliveryDate[gte]", "deliveryDate[lte]",
+}
+
+// shipmentSortKeys are the sortBy fields, given as field:asc or field:desc.
+var shipmentSortKeys = map[string]func(turvo.Shipment) string{
+	"id":            func(sh turvo.Shipment) string { return fmt.Sprintf("%012d", sh.ID) },
+	"customId":      func(sh turvo.Shipment) string { return sh.CustomID },
+	"createdDate":   func(sh turvo.Shipment) string { return timeKey(sh.CreatedDate) },
+	"updated":       func(sh turvo.Shipment) string { return timeKey(sh.Updated) },
+	"status.code":   statusKey,
+	"customer.name": customerName,
+	"pickupDate":    func(sh turvo.Shipment) string { return timeKey(&sh.StartDate.Date) },
+	"deliveryDate":  func(sh turvo.Shipment) string { return timeKey(&sh.EndDate.Date) },
+}
+
+// shipmentFilter is a parsed shipment list query.
+type shipmentFilter struct {
+	customID    string
+	customerIDs []int
+	statuses    []string
+	locationID  int
+	created     timeRange
+	updated     timeRange
+	pickup      timeRange
+	delivery    timeRange
+	sortKey     func(turvo.Shipment) string
+	sortDesc    bool
+}
+
+// timeRange is an inclusive range; a zero bound is open.
+type timeRange struct {
+	from, to time.Time
+}
+
+func (r timeRange) contains(t time.Time) bool {
+	return (r.from.IsZero() || !t.Before(r.from)) && (r.to.IsZero() || !t.After(r.to))
+}
+
+func (r timeRange) set() bool {
+	return !r.from.IsZero() || !r.to.IsZero()
+}
+
+func parseShipmentFilter(q url.Values) (shipmentFilter, error) {
+	var f shipmentFilter
+	for key, vs := range q {
+		switch key {
+		case "start", "pageSize", "lastObjectKey":
+			continue
+		case "sortBy":
+			field, dir, _ := strings.Cut(vs[0], ":")
+			key, ok := shipmentSortKeys[field]
+			if !ok || (dir != "" && dir != "asc" && dir != "desc") {
+				return f, badRequest("list shipments", "unsupported sortBy "+vs[0])
+			}
+			f.sortKey, f.sortDesc = key, dir == "desc"
+			continue
+		}
+		if !slices.Contains(shipmentFilters, key) {
+			return f, badRequest("list shipments", "unsupported filter "+key)
+		}
+	}
+
+	var err error
+	f.customID = q.Get("customId[eq]")
+	if v := q.Get("customerId[eq]"); v != "" {
+		if f.customerIDs, err = parseInts(v); err != nil {
+			return f, badRequest("list shipments", "invalid customerId[eq] "+v)
+		}
+	}
+	if v := q.Get("customerId[in]"); v != "" {
+		if f.customerIDs, err = parseInts(v); err != nil {
+			return f, badRequest("list shipments", "invalid customerId[in] "+v)
+		}
+	}
+	if v := q.Get("status[eq]"); v != "" {
+		f.statuses = []string{v}
+	}
+	if v := q.Get("status[in]"); v != "" {
+		for _, code := range strings.Split(v, ",") {
+			f.statuses = append(f.statuses, strings.TrimSpace(code))
+		}
+	}
+	if v := q.Get("locationId[eq]"); v != "" {
+		if f.locationID, err = strconv.Atoi(v); err != nil {
+			return f, badRequest("list shipments", "invalid locationId[eq] "+v)
+		}
+	}
+	for _, b := range []struct {
+		key string
+		to  *time.Time
+	}{
+		{"created[gte]", &f.created.from},
+		{"updated[lte]", &f.updated.to},
+		{"pickupDate[gte]", &f.pickup.from},
+		{"pickupDate[lte]", &f.pickup.to},
+		{"deliveryDate[gte]", &f.delivery.from},
+		{"deliveryDate[lte]", &f.delivery.to},
+	} {
+		if v := q.Get(b.key); v != "" {
+			if *b.to, err = time.Parse(time.RFC3339, v); err != nil {
+				return f, badRequest("list shipments", "invalid "+b.key+" "+v)
+			}
+		}
+	}
+	return f, nil
+}
+
+func (f shipmentFilter) match(sh turvo.Shipment) bool {
+	if f.customID != "" && sh.CustomID != f.customID {
+		return false
+	}
+	if f.customerIDs != nil && !slices.Contains(f.customerIDs, sh.CustomerID()) {
+		return false
+	}
+	if f.statuses != nil && !slices.Contains(f.statuses, statusKey(sh)) {
+		return false
+	}
+	if f.locationID > 0 && !slices.ContainsFunc(sh.GlobalRoute, func(gr turvo.GlobalRoute) bool { return gr.Location.ID == f.locationID }) {
+		return false
+	}
+	if f.created.set() && (sh.CreatedDate == nil || !f.created.contains(*sh.CreatedDate)) {
+		return false
+	}
+	if f.updated.set() && (sh.Updated == nil || !f.updated.contains(*sh.Updated)) {
+		return false
+	}
+	if f.pickup.set() && (sh.StartDate.Date.IsZero() || !f.pickup.contains(sh.StartDate.Date)) {
+		return false
+	}
+	if f.delivery.set() && (sh.EndDate.Date.IsZero() || !f.delivery.contains(sh.EndDate.Date)) {
+		return false
+	}
+	return true
+}
+
+// statusKey returns the shipment's status code, e.g. "2101".
+func statusKey(sh turvo.Shipment) string {
+	var st turvo.Status
+	if len(sh.Status) > 0 {
+		json.Unmarshal(sh.Status, &st)
+	}
+	return st.Code.Key
+}
+
+func customerName(sh turvo.Shipment) string {
+	for _, co := range sh.CustomerOrder {
+		if !co.Deleted && co.Customer != nil {
+			return co.Customer.Name
+		}
+	}
+	return ""
+}
+
+func timeKey(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseInts(v string) ([]int, error) {
+	var out []int
+	for _, f := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// GetShipment returns the shipment with id, or a 404 *turvo.APIError.
+func (s *Store) GetShipment(ctx context.Context, id string) (*turvo.Shipment, error) {
+	n, _ := strconv.Atoi(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh, ok := s.shipments[n]
+	if !ok {
+		return nil, notFound("get shipment", "shipment "+id)
+	}
+	out := clone(sh)
+	return &out, nil
+}
+
+// CreateShipment stores shipment under a new id.
+func (s *Store) CreateShipment(ctx context.Context, shipment turvo.Shipment) (*turvo.Shipment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	now := time.Now().UTC()
+	shipment = clone(shipment)
+	shipment.ID = s.nextID
+	shipment.CreatedDate = &now
+	shipment.Updated = &now
+	s.shipments[shipment.ID] = shipment
+	out := clone(shipment)
+	return &out, nil
+}
+
+// UpdateShipment replaces the stored shipment with id.
+func (s *Store) UpdateShipment(ctx context.Context, id string, shipment turvo.Shipment) (*turvo.Shipment, error) {
+	n, _ := strconv.Atoi(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.shipments[n]
+	if !ok {
+		return nil, notFound("update shipment", "shipment "+id)
+	}
+	now := time.Now().UTC()
+	shipment = clone(shipment)
+	shipment.ID = n
+	shipment.CreatedDate = existing.CreatedDate
+	shipment.Updated = &now
+	s.shipments[n] = shipment
+	out := clone(shipment)
+	return &out, nil
+}
+
+// FindShipmentByExternalID matches shipments on customId with the same
+// errors as turvo.Client.
+func (s *Store) FindShipmentByExternalID(ctx context.Context, externalID string) (*turvo.Shipment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matches []turvo.Shipment
+	for _, sh := range s.shipments {
+		if sh.CustomID == externalID {
+			matches = append(matches, sh)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w for external id %s", turvo.ErrShipmentNotFound, externalID)
+	case 1:
+		out := clone(matches[0])
+		return &out, nil
+	default:
+		ids := make([]int, len(matches))
+		for i, sh := range matches {
+			ids[i] = sh.ID
+		}
+		sort.Ints(ids)
+		return nil, turvo.AmbiguousExternalIDError{ExternalID: externalID, ShipmentIDs: ids}
+	}
+}
+
+// ListCustomers returns the directory, filtered by name[eq].
+func (s *Store) ListCustomers(ctx context.Context, q url.Values) ([]turvo.MinimalCustomer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []turvo.MinimalCustomer
+	for _, c := range s.customers {
+		if v := q.Get("name[eq]"); v != "" && c.Name != v {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// ListOrdersPage returns orders newest first, honouring start and pageSize.
+func (s *Store) ListOrdersPage(ctx context.Context, q url.Values) ([]turvo.Order, bool, error) {
+	start, _ := strconv.Atoi(q.Get("start"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	s.mu.Lock()
+	all := make([]turvo.Order, 0, len(s.orders))
+	for _, o := range s.orders {
+		all = append(all, clone(o))
+	}
+	s.mu.Unlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].ID > all[j].ID })
+	if start >= len(all) {
+		return []turvo.Order{}, false, nil
+	}
+	end := min(start+pageSize, len(all))
+	return all[start:end], end < len(all), nil
+}
+
+// GetOrder returns the order with id, or a 404 *turvo.APIError.
+func (s *Store) GetOrder(ctx context.Context, id string) (*turvo.Order, error) {
+	n, _ := strconv.Atoi(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[n]
+	if !ok {
+		return nil, notFound("get order", "order "+id)
+	}
+	out := clone(o)
+	return &out, nil
+}
+
+// CreateOrder stores order under a new id.
+func (s *Store) CreateOrder(ctx context.Context, order turvo.Order) (*turvo.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	order = clone(order)
+	order.ID = s.nextID
+	s.orders[order.ID] = order
+	out := clone(order)
+	return &out, nil
+}
+
+// ListLocations returns locations, filtered by name[eq].
+func (s *Store) ListLocations(ctx context.Context, q url.Values) ([]turvo.LocationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []turvo.LocationRecord
+	for _, loc := range s.locations {
+		if v := q.Get("name[eq]"); v != "" && loc.Name != v {
+			continue
+		}
+		out = append(out, clone(loc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// CreateLocation stores loc under a new id.
+func (s *Store) CreateLocation(ctx context.Context, loc turvo.LocationRecord) (*turvo.LocationRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	loc = clone(loc)
+	loc.ID = s.nextID
+	s.locations[loc.ID] = loc
+	out := clone(loc)
+	return &out, nil
+}
+
+var _ turvo.LocationDirectory = (*Store)(nil)
+
+func notFound(op, what string) error {
+	return &turvo.APIError{Op: op, StatusCode: http.StatusNotFound, Code: "NOT_FOUND", Message: what + " not found"}
+}
+
+func badRequest(op, msg string) error {
+	return &turvo.APIError{Op: op, StatusCode: http.StatusBadRequest, Code: "BAD_REQUEST", Message: msg}
+}
+
+// clone deep-copies v through JSON so callers cannot alias stored slices.
+func clone[T any](v T) T {
+	var out T
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+	return out
+}
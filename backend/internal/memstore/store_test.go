@@ -0,0 +1,118 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+var day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+// seed stores shipments LD-1..LD-3, picked up on consecutive days from day
+// and delivered the day after, with statuses Tendered, Covered, Tendered.
+func seed(t *testing.T) *Store {
+	t.Helper()
+	s := New()
+	for i, status := range []string{"2101", "2102", "2101"} {
+		st, _ := json.Marshal(turvo.Status{Code: turvo.KeyValuePair{Key: status}})
+		pickup := day.AddDate(0, 0, i).Add(15 * time.Hour)
+		sh := turvo.Shipment{
+			CustomID:    "LD-" + strconv.Itoa(i+1),
+			Status:      st,
+			StartDate:   turvo.DateWithTZ{Date: pickup},
+			EndDate:     turvo.DateWithTZ{Date: pickup.AddDate(0, 0, 1)},
+			GlobalRoute: []turvo.GlobalRoute{{Location: turvo.Location{ID: 100 + i}}},
+		}
+		if _, err := s.CreateShipment(context.Background(), sh); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func list(t *testing.T, s *Store, query string) ([]string, turvo.PageInfo) {
+	t.Helper()
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipments, meta, err := s.ListShipmentsPageWithQuery(context.Background(), q)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	var ids []string
+	for _, sh := range shipments {
+		ids = append(ids, sh.CustomID)
+	}
+	return ids, meta
+}
+
+func TestListShipmentsFilters(t *testing.T) {
+	s := seed(t)
+	for query, want := range map[string][]string{
+		"":                                     {"LD-3", "LD-2", "LD-1"},
+		"status[eq]=2101":                      {"LD-3", "LD-1"},
+		"status[in]=2102,2113":                 {"LD-2"},
+		"locationId[eq]=101":                   {"LD-2"},
+		"customId[eq]=LD-1":                    {"LD-1"},
+		"pickupDate[gte]=2026-03-03T00:00:00Z": {"LD-3", "LD-2"},
+		"pickupDate[gte]=2026-03-03T00:00:00Z&pickupDate[lte]=2026-03-03T15:00:00Z": {"LD-2"},
+		"deliveryDate[lte]=2026-03-03T15:00:00Z":                                    {"LD-1"},
+		"created[gte]=2999-01-01T00:00:00Z":                                         nil,
+		"updated[lte]=2000-01-01T00:00:00Z":                                         nil,
+	} {
+		if got, _ := list(t, s, query); !slices.Equal(got, want) {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestListShipmentsRejectsUnsupportedQueries(t *testing.T) {
+	s := seed(t)
+	for _, query := range []string{
+		"poNumber[eq]=PO-1",
+		"status[ne]=2101",
+		"pickupDate[gte]=2026-03-03",
+		"customerId[in]=7,x",
+		"sortBy=margin:desc",
+		"sortBy=customId:up",
+	} {
+		q, _ := url.ParseQuery(query)
+		_, _, err := s.ListShipmentsPageWithQuery(context.Background(), q)
+		var apiErr *turvo.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q err = %v", query, err)
+		}
+	}
+}
+
+func TestListShipmentsPaging(t *testing.T) {
+	s := seed(t)
+	got, meta := list(t, s, "pageSize=2")
+	if !slices.Equal(got, []string{"LD-3", "LD-2"}) || !meta.MoreAvailable || meta.TotalRecords != 3 || meta.LastObjectKey == "" {
+		t.Fatalf("first page = %v, %+v", got, meta)
+	}
+	if got, meta = list(t, s, "pageSize=2&lastObjectKey="+meta.LastObjectKey); !slices.Equal(got, []string{"LD-1"}) || meta.MoreAvailable {
+		t.Errorf("after lastObjectKey = %v, %+v", got, meta)
+	}
+
+	// a sorted listing is paged by start
+	got, meta = list(t, s, "pageSize=2&sortBy=status.code:desc")
+	if !slices.Equal(got, []string{"LD-2", "LD-3"}) || !meta.MoreAvailable || meta.LastObjectKey != "" {
+		t.Fatalf("sorted first page = %v, %+v", got, meta)
+	}
+	if got, _ = list(t, s, "pageSize=2&start=2&sortBy=status.code:desc"); !slices.Equal(got, []string{"LD-1"}) {
+		t.Errorf("sorted second page = %v", got)
+	}
+	if got, _ = list(t, s, "sortBy=customId:asc"); !slices.Equal(got, []string{"LD-1", "LD-2", "LD-3"}) {
+		t.Errorf("by customId = %v", got)
+	}
+}
@@ -0,0 +1,83 @@
+// Package tenant keeps one isolated Turvo connection per tenant, each with its
+// own client, token cache, defaults and mapper, and carries the tenant chosen
+// for a request in its context.
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// Tenant is one Turvo tenant and everything built for it.
+type Tenant struct {
+	ID     string
+	Config *config.Config
+	Client *turvo.Client
+	Mapper *turvo.Mapper
+	// Secrets is nil when the tenant's credentials do not rotate.
+	Secrets *config.SecretRefresher
+	// Handler serves the tenant's API routes.
+	Handler http.Handler
+	// Events receives the tenant's verified Turvo webhook events.
+	Events *turvo.Dispatcher
+}
+
+// Registry looks tenants up by id. It is built at startup and read-only
+// afterwards.
+type Registry struct {
+	tenants   map[string]*Tenant
+	defaultID string
+}
+
+// NewRegistry returns an empty registry whose default tenant is defaultID.
+func NewRegistry(defaultID string) *Registry {
+	return &Registry{tenants: make(map[string]*Tenant), defaultID: defaultID}
+}
+
+// Add registers t, replacing any tenant with the same id.
+func (r *Registry) Add(t *Tenant) {
+	r.tenants[t.ID] = t
+}
+
+// Get returns the tenant with id.
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// Default returns the default tenant, or nil if it was never added.
+func (r *Registry) Default() *Tenant {
+	return r.tenants[r.defaultID]
+}
+
+// Len returns the number of registered tenants.
+func (r *Registry) Len() int {
+	return len(r.tenants)
+}
+
+// IDs returns the registered tenant ids in sorted order.
+func (r *Registry) IDs() []string {
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type ctxKey struct{}
+
+// WithTenant returns a copy of ctx carrying t.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the tenant stored by WithTenant.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(*Tenant)
+	return t, ok
+}
@@ -0,0 +1,130 @@
+package turvo
+
+import (
+	"fmt"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// ToTurvoCarrierOrder converts a Drumkit carrier assignment into a Turvo
+// carrier order. The carrier must already exist in Turvo (TurvoID).
+func (m *Mapper) ToTurvoCarrierOrder(c *domain.Carrier) (CarrierOrder, error) {
+	if c == nil || c.TurvoID <= 0 {
+		return CarrierOrder{}, fmt.Errorf("carrier turvoId is required")
+	}
+	co := CarrierOrder{
+		CarrierID:            c.TurvoID,
+		CarrierOrderSourceID: 1,
+		Carrier: &CarrierRef{
+			ID:        c.TurvoID,
+			Name:      c.Name,
+			McNumber:  c.MCNumber,
+			DotNumber: c.DOTNumber,
+			Scac:      c.SCAC,
+		},
+		ExternalID:               c.ExternalTMSId,
+		TruckNumber:              c.ExternalTMSTruckId,
+		TrailerNumber:            c.ExternalTMSTrailerId,
+		SealNumber:               c.SealNumber,
+		ConfirmationSentDate:     c.ConfirmationSentTime,
+		ConfirmationReceivedDate: c.ConfirmationReceivedTime,
+		DispatchedDate:           c.DispatchedTime,
+		ExpectedPickupDate:       c.ExpectedPickupTime,
+		ExpectedDeliveryDate:     c.ExpectedDeliveryTime,
+		SignedBy:                 c.SignedBy,
+	}
+	if c.Dispatcher != "" || c.Phone != "" || c.Email != "" {
+		co.Dispatcher = &CarrierContact{Name: c.Dispatcher, Phone: c.Phone, Email: c.Email}
+	}
+	if c.FirstDriverName != "" || c.FirstDriverPhone != "" {
+		co.Drivers = append(co.Drivers, CarrierContact{Name: c.FirstDriverName, Phone: c.FirstDriverPhone})
+	}
+	if c.SecondDriverName != "" || c.SecondDriverPhone != "" {
+		co.Drivers = append(co.Drivers, CarrierContact{Name: c.SecondDriverName, Phone: c.SecondDriverPhone})
+	}
+	if c.DispatchCity != "" || c.DispatchState != "" {
+		co.DispatchLocation = &DispatchLocation{City: c.DispatchCity, State: c.DispatchState}
+	}
+	return co, nil
+}
+
+// fromTurvoCarrierOrders returns the active carrier on a shipment, filling
+// pickup and delivery actuals from the route when present. It returns nil
+// when no carrier is assigned.
+func (m *Mapper) fromTurvoCarrierOrders(orders []CarrierOrder, route []GlobalRoute) *domain.Carrier {
+	var co *CarrierOrder
+	for i := range orders {
+		if !orders[i].Deleted {
+			co = &orders[i]
+		}
+	}
+	if co == nil {
+		return nil
+	}
+	c := &domain.Carrier{
+		TurvoID:                  co.CarrierID,
+		ExternalTMSId:            co.ExternalID,
+		ExternalTMSTruckId:       co.TruckNumber,
+		ExternalTMSTrailerId:     co.TrailerNumber,
+		SealNumber:               co.SealNumber,
+		ConfirmationSentTime:     co.ConfirmationSentDate,
+		ConfirmationReceivedTime: co.ConfirmationReceivedDate,
+		DispatchedTime:           co.DispatchedDate,
+		ExpectedPickupTime:       co.ExpectedPickupDate,
+		ExpectedDeliveryTime:     co.ExpectedDeliveryDate,
+		SignedBy:                 co.SignedBy,
+	}
+	if co.Carrier != nil {
+		if c.TurvoID == 0 {
+			c.TurvoID = co.Carrier.ID
+		}
+		c.Name = co.Carrier.Name
+		c.MCNumber = co.Carrier.McNumber
+		c.DOTNumber = co.Carrier.DotNumber
+		c.SCAC = co.Carrier.Scac
+	}
+	if co.Dispatcher != nil {
+		c.Dispatcher = co.Dispatcher.Name
+		c.Phone = co.Dispatcher.Phone
+		c.Email = co.Dispatcher.Email
+	}
+	if len(co.Drivers) > 0 {
+		c.FirstDriverName, c.FirstDriverPhone = co.Drivers[0].Name, co.Drivers[0].Phone
+	}
+	if len(co.Drivers) > 1 {
+		c.SecondDriverName, c.SecondDriverPhone = co.Drivers[1].Name, co.Drivers[1].Phone
+	}
+	if co.DispatchLocation != nil {
+		c.DispatchCity, c.DispatchState = co.DispatchLocation.City, co.DispatchLocation.State
+	}
+	// Actual arrival/departure live on the route stops
+	for i := range route {
+		gr := route[i]
+		if gr.ActualPickupDate == nil {
+			continue
+		}
+		switch gr.StopType.Key {
+		case stopTypes[domain.StopTypePickup].Key:
+			if c.PickupStart == nil {
+				c.PickupStart, c.PickupEnd = gr.ActualPickupDate.Arrival, gr.ActualPickupDate.Departed
+			}
+		case stopTypes[domain.StopTypeDelivery].Key:
+			c.DeliveryStart, c.DeliveryEnd = gr.ActualPickupDate.Arrival, gr.ActualPickupDate.Departed
+		}
+	}
+	return c
+}
+
+// AssignCarrierOrder replaces the active carrier order with co. Previously
+// saved orders are marked deleted so Turvo drops them on update.
+func AssignCarrierOrder(existing []CarrierOrder, co CarrierOrder) []CarrierOrder {
+	out := make([]CarrierOrder, 0, len(existing)+1)
+	for _, e := range existing {
+		if e.ID == 0 {
+			continue
+		}
+		e.Deleted = true
+		out = append(out, e)
+	}
+	return append(out, co)
+}
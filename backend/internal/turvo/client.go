@@ -1,12 +1,12 @@
 package turvo
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/logging"
 )
 
 // RateLimitedError represents an HTTP 429 response from Turvo or an internal
@@ -38,11 +39,17 @@ type Client struct {
 	httpClient *http.Client
 	config     *config.Config
 	mu         sync.Mutex
+	creds      config.Credentials
 	token      string
 	tokenExp   time.Time
 	refresh    string
 	// simple cooldown to avoid hammering oauth on 429
 	nextOAuthAttempt time.Time
+	// customId -> Turvo id for shipments seen by this client
+	index *externalIDIndex
+	retry RetryPolicy
+	// onAuthRejected is called when Turvo rejects the credentials themselves
+	onAuthRejected func()
 }
 
 // NewClient creates a new Turvo API client.
@@ -50,10 +57,46 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	c := &Client{
 		httpClient: &http.Client{Timeout: 30 * time.Second},
 		config:     cfg,
+		creds:      cfg.Credentials(),
+		index:      newExternalIDIndex(),
+		retry:      DefaultRetryPolicy,
 	}
 	return c, nil
 }
 
+// SetCredentials swaps in rotated credentials and drops the cached tokens so
+// the next request authenticates with them. Requests already in flight finish
+// with the old token.
+func (c *Client) SetCredentials(creds config.Credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.creds = creds
+	c.token = ""
+	c.tokenExp = time.Time{}
+	c.refresh = ""
+	c.nextOAuthAttempt = time.Time{}
+}
+
+// SetOnAuthRejected registers fn to be called when Turvo rejects the
+// client's credentials, so a secret refresh can be triggered. fn must not
+// block. It runs without the client's lock held, so it may call the client,
+// for example to SetCredentials.
+func (c *Client) SetOnAuthRejected(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAuthRejected = fn
+}
+
+// authRejected runs the OnAuthRejected hook, if any. c.mu must not be held.
+func (c *Client) authRejected() {
+	c.mu.Lock()
+	fn := c.onAuthRejected
+	c.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
 func (c *Client) oauthTokenEndpoint() string {
 	base := strings.TrimRight(c.config.TurvoBaseURL, "/")
 	// OAuth docs specify /v1/oauth/token on publicapi host
@@ -63,44 +106,59 @@ func (c *Client) oauthTokenEndpoint() string {
 // fetchToken ensures there is a valid bearer token. It can use a refresh token
 // when available and sets a simple cooldown after 429 responses.
 func (c *Client) fetchToken(ctx context.Context, useRefresh bool) error {
+	rejected, err := c.requestToken(ctx, useRefresh)
+	if rejected {
+		// after c.mu is released, so the hook may call back into the client
+		c.authRejected()
+	}
+	return err
+}
+
+// requestToken does the work of fetchToken under c.mu. rejected reports
+// that Turvo turned down the credentials themselves.
+func (c *Client) requestToken(ctx context.Context, useRefresh bool) (rejected bool, err error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.token != "" && time.Until(c.tokenExp) > 60*time.Second {
-		return nil
+		return false, nil
 	}
 	// backoff respect
 	if time.Now().Before(c.nextOAuthAttempt) {
 		wait := time.Until(c.nextOAuthAttempt)
-		return RateLimitedError{RetryAfter: wait}
+		return false, RateLimitedError{RetryAfter: wait}
 	}
 
 	endpoint := c.oauthTokenEndpoint()
 	q := url.Values{}
-	q.Set("client_id", c.config.TurvoClientID)
-	q.Set("client_secret", c.config.TurvoClientSecret)
+	q.Set("client_id", c.creds.ClientID)
+	q.Set("client_secret", c.creds.ClientSecret)
 	endpointWithQuery := endpoint + "?" + q.Encode()
 
 	form := url.Values{}
 	headers := make(http.Header)
-	if c.config.TurvoAPIKey != "" {
-		headers.Set("x-api-key", c.config.TurvoAPIKey)
+	if c.creds.APIKey != "" {
+		headers.Set("x-api-key", c.creds.APIKey)
 	}
-	if useRefresh && c.refresh != "" {
+	switch {
+	case useRefresh && c.refresh != "":
 		form.Set("grant_type", "refresh_token")
 		form.Set("refresh_token", c.refresh)
-	} else {
+	case c.config.TurvoAuthMode == config.AuthOAuthClientCredentials:
+		form.Set("grant_type", "client_credentials")
+		form.Set("scope", c.config.TurvoOAuthScope)
+	default:
 		form.Set("grant_type", "password")
-		form.Set("username", c.config.TurvoOAuthUsername)
-		form.Set("password", c.config.TurvoOAuthPassword)
+		form.Set("username", c.creds.Username)
+		form.Set("password", c.creds.Password)
 		form.Set("scope", c.config.TurvoOAuthScope)
 		form.Set("type", c.config.TurvoOAuthUserType)
 	}
 
-	log.Printf("Turvo OAuth: POST %s", endpoint)
+	slog.Debug("Turvo OAuth request", "url", endpoint, "grant_type", form.Get("grant_type"))
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointWithQuery, strings.NewReader(form.Encode()))
 	if err != nil {
-		return err
+		return false, err
 	}
 	req.Header = headers
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -108,32 +166,27 @@ func (c *Client) fetchToken(ctx context.Context, useRefresh bool) error {
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer resp.Body.Close()
 
-	// Read the body once for logging and subsequent handling
 	bodyBytes, _ := io.ReadAll(resp.Body)
-	// Truncate for logging to avoid huge outputs
-	maxLog := 2048
-	bodyPreview := bodyBytes
-	if len(bodyBytes) > maxLog {
-		bodyPreview = bodyBytes[:maxLog]
-	}
-	log.Printf("Turvo OAuth response: %s - %s", resp.Status, string(bodyPreview))
+	slog.Debug("Turvo OAuth response", "status", resp.Status, "body", logging.Body(bodyBytes))
 
 	if resp.StatusCode == http.StatusTooManyRequests { // 429
-		cooldown := 60 * time.Second
-		if ra := resp.Header.Get("Retry-After"); ra != "" {
-			if secs, err := strconv.Atoi(ra); err == nil && secs > 0 {
-				cooldown = time.Duration(secs) * time.Second
-			}
+		cooldown := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if cooldown <= 0 {
+			cooldown = 60 * time.Second
 		}
 		c.nextOAuthAttempt = time.Now().Add(cooldown)
-		return RateLimitedError{RetryAfter: cooldown, Message: string(bodyBytes)}
+		return false, RateLimitedError{RetryAfter: cooldown, Message: string(bodyBytes)}
 	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("oauth token error: %s - %s", resp.Status, string(bodyBytes))
+		slog.Warn("Turvo OAuth failed", "status", resp.Status, "grant_type", form.Get("grant_type"))
+		// a rejected refresh token only means re-authenticating; anything
+		// else suggests the credentials have rotated
+		rejected = form.Get("grant_type") != "refresh_token" && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized)
+		return rejected, fmt.Errorf("oauth token error: %s - %s", resp.Status, string(bodyBytes))
 	}
 	var tok struct {
 		AccessToken  string `json:"access_token"`
@@ -143,10 +196,10 @@ func (c *Client) fetchToken(ctx context.Context, useRefresh bool) error {
 		RefreshToken string `json:"refresh_token"`
 	}
 	if err := json.Unmarshal(bodyBytes, &tok); err != nil {
-		return err
+		return false, err
 	}
 	if strings.TrimSpace(tok.AccessToken) == "" {
-		return fmt.Errorf("empty access_token from oauth")
+		return false, fmt.Errorf("empty access_token from oauth")
 	}
 	c.token = strings.TrimSpace(tok.AccessToken)
 	c.refresh = tok.RefreshToken
@@ -155,53 +208,60 @@ func (c *Client) fetchToken(ctx context.Context, useRefresh bool) error {
 	}
 	c.tokenExp = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
 	c.nextOAuthAttempt = time.Time{} // clear cooldown
-	return nil
+	return false, nil
 }
 
+// usesOAuth reports whether data calls carry a bearer token. Only api_key
+// mode skips OAuth; an unset mode means oauth_password.
+func (c *Client) usesOAuth() bool {
+	return c.config.TurvoAuthMode != config.AuthAPIKey
+}
+
+// buildPath joins the base URL, the configured API prefix and p. The prefix
+// is not repeated when the base URL already ends with it.
 func (c *Client) buildPath(p string) string {
 	base := strings.TrimRight(c.config.TurvoBaseURL, "/")
-	prefix := strings.Trim(c.config.TurvoAPIPrefix, "/")
-	// If we have a bearer token, use /v1; otherwise for API key-only mode on publicapi, use /public/v1 when prefix is v1
-	if c.token != "" {
-		prefix = "v1"
-	} else if strings.Contains(base, "publicapi.") && (prefix == "v1" || prefix == "/v1") {
-		prefix = "public/v1"
-	}
-	res := strings.TrimLeft(p, "/")
-	addPrefix := prefix != "" && !(strings.HasSuffix(base, "/"+prefix) || strings.HasSuffix(base, prefix))
-	full := base
-	if addPrefix {
-		full += "/" + prefix
-	}
-	full += "/" + res
-	return full
+	if prefix := strings.Trim(c.config.TurvoAPIPrefix, "/"); prefix != "" && !strings.HasSuffix(base, "/"+prefix) {
+		base += "/" + prefix
+	}
+	return base + "/" + strings.TrimLeft(p, "/")
+}
+
+// authHeaders returns the current bearer token and API key.
+func (c *Client) authHeaders() (token, apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token, c.creds.APIKey
 }
 
 func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
-	if err := c.fetchToken(ctx, false); err != nil {
-		return nil, err
+	if c.usesOAuth() {
+		if err := c.fetchToken(ctx, false); err != nil {
+			return nil, err
+		}
 	}
 	fullURL := c.buildPath(path)
-	if pc, file, line, ok := runtime.Caller(1); ok {
-		fn := runtime.FuncForPC(pc)
-		log.Printf("Turvo request: %s %s (caller: %s:%d %s)", method, fullURL, file, line, func() string {
-			if fn != nil {
-				return fn.Name()
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		caller := ""
+		if pc, _, _, ok := runtime.Caller(2); ok {
+			if fn := runtime.FuncForPC(pc); fn != nil {
+				caller = fn.Name()
 			}
-			return ""
-		}())
-	} else {
-		log.Printf("Turvo request: %s %s", method, fullURL)
+		}
+		slog.DebugContext(ctx, "Turvo request", "method", method, "url", fullURL, "caller", caller)
 	}
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
 		return nil, err
 	}
 	// Bearer and x-api-key on data requests per working curl
-	if c.config.TurvoAPIKey != "" {
-		req.Header.Set("x-api-key", c.config.TurvoAPIKey)
+	token, apiKey := c.authHeaders()
+	if apiKey != "" {
+		req.Header.Set("x-api-key", apiKey)
+	}
+	if c.usesOAuth() {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 	return req, nil
@@ -224,26 +284,11 @@ func (c *Client) ListCustomers(ctx context.Context, q url.Values) ([]MinimalCust
 	if _, ok := q["pageSize"]; !ok {
 		q.Set("pageSize", "50")
 	}
-	req, err := c.newRequest(ctx, http.MethodGet, "customers/list?"+q.Encode(), nil)
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, "customers/list?"+q.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		if err := c.fetchToken(ctx, true); err == nil {
-			return c.ListCustomers(ctx, q)
-		}
-	}
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to list customers: %s - %s", resp.Status, string(b))
-	}
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err := checkResponse("list customers", resp, bodyBytes); err != nil {
 		return nil, err
 	}
 	var wrapped struct {
@@ -271,82 +316,26 @@ func (c *Client) ListCustomers(ctx context.Context, q url.Values) ([]MinimalCust
 }
 
 // ListShipmentsPage fetches one page of shipments from Turvo.
-func (c *Client) ListShipmentsPage(ctx context.Context, start, pageSize int) ([]Shipment, struct {
-	Start, PageSize, TotalRecordsInPage int
-	MoreAvailable                       bool
-	LastObjectKey                       interface{}
-}, error) {
-	var pagination struct {
-		Start              int
-		PageSize           int
-		TotalRecordsInPage int
-		MoreAvailable      bool
-		LastObjectKey      interface{}
-	}
-	path := fmt.Sprintf("shipments/list?start=%d&pageSize=%d", start, pageSize)
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
-	if err != nil {
-		return nil, pagination, err
-	}
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, pagination, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		if err := c.fetchToken(ctx, true); err == nil {
-			return c.ListShipmentsPage(ctx, start, pageSize)
-		}
-	}
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, pagination, fmt.Errorf("failed to list shipments: %s - %s", resp.Status, string(b))
-	}
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, pagination, err
-	}
-	var wrapped struct {
-		Status  string `json:"Status"`
-		Details struct {
-			Shipments  []Shipment `json:"shipments"`
-			Pagination struct {
-				Start              int         `json:"start"`
-				PageSize           int         `json:"pageSize"`
-				TotalRecordsInPage int         `json:"totalRecordsInPage"`
-				MoreAvailable      bool        `json:"moreAvailable"`
-				LastObjectKey      interface{} `json:"lastObjectKey"`
-			} `json:"pagination"`
-		} `json:"details"`
-	}
-	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && wrapped.Details.Shipments != nil {
-		pagination.Start = wrapped.Details.Pagination.Start
-		pagination.PageSize = wrapped.Details.Pagination.PageSize
-		pagination.TotalRecordsInPage = wrapped.Details.Pagination.TotalRecordsInPage
-		pagination.MoreAvailable = wrapped.Details.Pagination.MoreAvailable
-		pagination.LastObjectKey = wrapped.Details.Pagination.LastObjectKey
-		return wrapped.Details.Shipments, pagination, nil
-	}
-	var shipments []Shipment
-	if err := json.Unmarshal(bodyBytes, &shipments); err != nil {
-		return nil, pagination, err
-	}
-
-	pagination.Start = start
-	pagination.PageSize = len(shipments)
-	pagination.TotalRecordsInPage = len(shipments)
-	pagination.MoreAvailable = false
-	return shipments, pagination, nil
+func (c *Client) ListShipmentsPage(ctx context.Context, start, pageSize int) ([]Shipment, PageInfo, error) {
+	q := url.Values{}
+	q.Set("start", strconv.Itoa(start))
+	q.Set("pageSize", strconv.Itoa(pageSize))
+	return c.ListShipmentsPageWithQuery(ctx, q)
 }
 
-// ListShipments fetches all shipments by paging until completion.
+// ListShipments fetches all shipments by paging until completion. Pages are
+// chained by lastObjectKey when Turvo returns one, so shipments created while
+// paging do not shift later pages.
 func (c *Client) ListShipments(ctx context.Context) ([]Shipment, error) {
 	var all []Shipment
 	start := 0
 	pageSize := 100
 	maxPages := 100
+	q := url.Values{}
+	q.Set("pageSize", strconv.Itoa(pageSize))
 	for page := 0; page < maxPages; page++ {
-		items, meta, err := c.ListShipmentsPage(ctx, start, pageSize)
+		q.Set("start", strconv.Itoa(start))
+		items, meta, err := c.ListShipmentsPageWithQuery(ctx, q)
 		if err != nil {
 			return nil, err
 		}
@@ -354,6 +343,10 @@ func (c *Client) ListShipments(ctx context.Context) ([]Shipment, error) {
 		if !meta.MoreAvailable {
 			break
 		}
+		if meta.LastObjectKey != "" {
+			q.Set("lastObjectKey", meta.LastObjectKey)
+			continue
+		}
 		incr := meta.TotalRecordsInPage
 		if incr <= 0 {
 			incr = len(items)
@@ -364,32 +357,17 @@ func (c *Client) ListShipments(ctx context.Context) ([]Shipment, error) {
 		start += incr
 	}
 
-	log.Println("Shipments listed from Turvo:", len(all))
+	slog.Debug("Shipments listed from Turvo", "count", len(all))
 	return all, nil
 }
 
 // GetShipment fetches a shipment by ID.
 func (c *Client) GetShipment(ctx context.Context, id string) (*Shipment, error) {
-	req, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("shipments/%s", id), nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.httpClient.Do(req)
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, fmt.Sprintf("shipments/%s", id), nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		if err := c.fetchToken(ctx, true); err == nil {
-			return c.GetShipment(ctx, id)
-		}
-	}
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get shipment: %s - %s", resp.Status, string(b))
-	}
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err := checkResponse("get shipment", resp, bodyBytes); err != nil {
 		return nil, err
 	}
 	// Flexible decode: wrapper -> details.shipment | details.shipments | details as shipment, else direct shipment
@@ -404,53 +382,62 @@ func (c *Client) GetShipment(ctx context.Context, id string) (*Shipment, error)
 		}
 		if err := json.Unmarshal(maybeWrapper.Details, &inner); err == nil {
 			if inner.Shipment != nil {
+				c.index.observe(*inner.Shipment)
 				return inner.Shipment, nil
 			}
 			if len(inner.Shipments) > 0 {
 				s := inner.Shipments[0]
+				c.index.observe(s)
 				return &s, nil
 			}
 		}
 		var fromDetails Shipment
 		if err := json.Unmarshal(maybeWrapper.Details, &fromDetails); err == nil && (fromDetails.ID != 0 || fromDetails.CustomID != "") {
+			c.index.observe(fromDetails)
 			return &fromDetails, nil
 		}
 	}
 	var direct Shipment
 	if err := json.Unmarshal(bodyBytes, &direct); err == nil && (direct.ID != 0 || direct.CustomID != "") {
+		c.index.observe(direct)
 		return &direct, nil
 	}
 	return nil, fmt.Errorf("empty or unrecognized shipment response")
 }
 
-// CreateShipment creates a shipment in Turvo.
+// CreateShipment creates a shipment in Turvo. With WithSafeRetry, a create
+// that failed with a 5xx or network error is repeated only after looking up
+// the shipment's customId; when the failed attempt was applied after all,
+// that shipment is returned instead.
 func (c *Client) CreateShipment(ctx context.Context, shipment Shipment) (*Shipment, error) {
 	payload, err := json.Marshal(shipment)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Turvo create payload: %s", string(payload))
-	req, err := c.newRequest(ctx, http.MethodPost, "shipments?fullResponse=true", bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
+	var applied *Shipment
+	if shipment.CustomID != "" {
+		ctx = withRetryCheck(ctx, func(ctx context.Context) bool {
+			s, err := c.FindShipmentByExternalID(ctx, shipment.CustomID)
+			if err == nil {
+				applied = s
+				return false
+			}
+			return errors.Is(err, ErrShipmentNotFound)
+		})
+	}
+	slog.DebugContext(ctx, "Turvo create shipment payload", "body", logging.Body(payload))
+	resp, bodyBytes, err := c.do(ctx, http.MethodPost, "shipments?fullResponse=true", payload)
+	if applied != nil {
+		slog.InfoContext(ctx, "Turvo create shipment was applied before failing", "id", applied.ID, "custom_id", applied.CustomID)
+		return applied, nil
 	}
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		if err := c.fetchToken(ctx, true); err == nil {
-			return c.CreateShipment(ctx, shipment)
-		}
-	}
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		log.Printf("Turvo create failed: %s - %s", resp.Status, string(bodyBytes))
-		log.Printf("Request URL: %s", resp.Request.URL.String())
-		log.Printf("Request Body: %s", string(payload))
-		log.Printf("Request Headers: %+v", resp.Request.Header)
-		return nil, fmt.Errorf("failed to create shipment: %s - %s", resp.Status, string(bodyBytes))
+	if err := checkResponse("create shipment", resp, bodyBytes); err != nil {
+		slog.WarnContext(ctx, "Turvo create shipment failed", "status", resp.Status, "error", err)
+		slog.DebugContext(ctx, "Turvo create shipment failed response", "body", logging.Body(bodyBytes))
+		return nil, err
 	}
 	// Try wrapped response first
 	var wrapped struct {
@@ -460,7 +447,7 @@ func (c *Client) CreateShipment(ctx context.Context, shipment Shipment) (*Shipme
 	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && len(wrapped.Details) > 0 {
 		var created Shipment
 		if err := json.Unmarshal(wrapped.Details, &created); err == nil && (created.ID != 0 || created.CustomID != "") {
-
+			c.index.observe(created)
 			return &created, nil
 		}
 	}
@@ -469,29 +456,96 @@ func (c *Client) CreateShipment(ctx context.Context, shipment Shipment) (*Shipme
 	if err := json.Unmarshal(bodyBytes, &created); err != nil {
 		return nil, fmt.Errorf("create decode error: %w", err)
 	}
+	c.index.observe(created)
 	return &created, nil
 }
 
-// FindShipmentByExternalID lists shipments and filters by CustomID as an external reference.
+// UpdateShipment updates an existing shipment in Turvo by ID. The shipment
+// should be based on the current Turvo state so untouched fields are preserved.
+func (c *Client) UpdateShipment(ctx context.Context, id string, shipment Shipment) (*Shipment, error) {
+	payload, err := json.Marshal(shipment)
+	if err != nil {
+		return nil, err
+	}
+	slog.DebugContext(ctx, "Turvo update shipment payload", "id", id, "body", logging.Body(payload))
+	resp, bodyBytes, err := c.do(ctx, http.MethodPut, fmt.Sprintf("shipments/%s?fullResponse=true", id), payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse("update shipment", resp, bodyBytes); err != nil {
+		slog.WarnContext(ctx, "Turvo update shipment failed", "id", id, "status", resp.Status, "error", err)
+		slog.DebugContext(ctx, "Turvo update shipment failed response", "body", logging.Body(bodyBytes))
+		return nil, err
+	}
+	var wrapped struct {
+		Status  string          `json:"Status"`
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && len(wrapped.Details) > 0 {
+		var updated Shipment
+		if err := json.Unmarshal(wrapped.Details, &updated); err == nil && (updated.ID != 0 || updated.CustomID != "") {
+			c.index.observe(updated)
+			return &updated, nil
+		}
+	}
+	var updated Shipment
+	if err := json.Unmarshal(bodyBytes, &updated); err != nil {
+		return nil, fmt.Errorf("update decode error: %w", err)
+	}
+	c.index.observe(updated)
+	return &updated, nil
+}
+
+// FindShipmentByExternalID looks up a shipment by its customId. Turvo is
+// always queried with customId[eq], so shipments sharing the id are caught
+// even when this client has only seen one of them. The local index is a
+// hint for shipments the listing has not caught up with yet: when the query
+// finds nothing, a known id is fetched directly. It returns
+// ErrShipmentNotFound when nothing matches and AmbiguousExternalIDError when
+// several shipments share the id.
 func (c *Client) FindShipmentByExternalID(ctx context.Context, externalID string) (*Shipment, error) {
-	shipments, err := c.ListShipments(ctx)
+	q := url.Values{}
+	q.Set("customId[eq]", externalID)
+	q.Set("pageSize", "10")
+	shipments, _, err := c.ListShipmentsPageWithQuery(ctx, q)
 	if err != nil {
 		return nil, err
 	}
+	var matches []Shipment
 	for _, s := range shipments {
 		if s.CustomID == externalID {
-			return &s, nil
+			matches = append(matches, s)
+		}
+	}
+	if len(matches) == 0 {
+		if id, ok := c.index.lookup(externalID); ok {
+			s, err := c.GetShipment(ctx, strconv.Itoa(id))
+			if err == nil && s.CustomID == externalID {
+				return s, nil
+			}
+			// stale entry (renamed or deleted shipment)
+			c.index.forget(externalID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w for external id %s", ErrShipmentNotFound, externalID)
+	case 1:
+		return &matches[0], nil
+	default:
+		ids := make([]int, len(matches))
+		for i, s := range matches {
+			ids[i] = s.ID
 		}
+		c.index.forget(externalID)
+		return nil, AmbiguousExternalIDError{ExternalID: externalID, ShipmentIDs: ids}
 	}
-	return nil, fmt.Errorf("shipment not found for external id %s", externalID)
 }
 
-// ListShipmentsPageWithQuery fetches one page with additional filters.
-func (c *Client) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) ([]Shipment, struct {
-	Start, PageSize, TotalRecordsInPage int
-	MoreAvailable                       bool
-	LastObjectKey                       interface{}
-}, error) {
+// ListShipmentsPageWithQuery fetches one page with additional filters. A
+// lastObjectKey in q, taken from an earlier page's PageInfo, continues after
+// that page instead of at start.
+func (c *Client) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) ([]Shipment, PageInfo, error) {
 	// Ensure start/pageSize exist
 	if q == nil {
 		q = url.Values{}
@@ -503,57 +557,25 @@ func (c *Client) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) (
 		q.Set("pageSize", "50")
 	}
 	path := "shipments/list?" + q.Encode()
-	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
-	var pagination struct {
-		Start, PageSize, TotalRecordsInPage int
-		MoreAvailable                       bool
-		LastObjectKey                       interface{}
-	}
+	var pagination PageInfo
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, pagination, err
 	}
-	resp, err := c.httpClient.Do(req)
-	log.Printf("Turvo shipment request: %s", req.URL.String())
-	if resp != nil {
-		log.Printf("Turvo shipment response: %s", resp.Status)
-	}
-	if err != nil {
-		return nil, pagination, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusUnauthorized {
-		if err := c.fetchToken(ctx, true); err == nil {
-			return c.ListShipmentsPageWithQuery(ctx, q)
-		}
-	}
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, pagination, fmt.Errorf("failed to list shipments: %s - %s", resp.Status, string(b))
-	}
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
+	slog.DebugContext(ctx, "Turvo list shipments response", "status", resp.Status, "bytes", len(bodyBytes))
+	if err := checkResponse("list shipments", resp, bodyBytes); err != nil {
 		return nil, pagination, err
 	}
 	var wrapped struct {
 		Status  string `json:"Status"`
 		Details struct {
 			Shipments  []Shipment `json:"shipments"`
-			Pagination struct {
-				Start              int         `json:"start"`
-				PageSize           int         `json:"pageSize"`
-				TotalRecordsInPage int         `json:"totalRecordsInPage"`
-				MoreAvailable      bool        `json:"moreAvailable"`
-				LastObjectKey      interface{} `json:"lastObjectKey"`
-			} `json:"pagination"`
+			Pagination PageInfo   `json:"pagination"`
 		} `json:"details"`
 	}
 	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && wrapped.Details.Shipments != nil {
-		pagination.Start = wrapped.Details.Pagination.Start
-		pagination.PageSize = wrapped.Details.Pagination.PageSize
-		pagination.TotalRecordsInPage = wrapped.Details.Pagination.TotalRecordsInPage
-		pagination.MoreAvailable = wrapped.Details.Pagination.MoreAvailable
-		pagination.LastObjectKey = wrapped.Details.Pagination.LastObjectKey
-		return wrapped.Details.Shipments, pagination, nil
+		c.index.observe(wrapped.Details.Shipments...)
+		return wrapped.Details.Shipments, wrapped.Details.Pagination, nil
 	}
 	var shipments []Shipment
 	if err := json.Unmarshal(bodyBytes, &shipments); err != nil {
@@ -562,7 +584,9 @@ func (c *Client) ListShipmentsPageWithQuery(ctx context.Context, q url.Values) (
 	pagination.Start = atoiOrZero(q.Get("start"))
 	pagination.PageSize = len(shipments)
 	pagination.TotalRecordsInPage = len(shipments)
+	pagination.TotalRecords = len(shipments)
 	pagination.MoreAvailable = false
+	c.index.observe(shipments...)
 	return shipments, pagination, nil
 }
 
@@ -570,3 +594,153 @@ func atoiOrZero(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
 }
+
+// CreateOrder creates an order in Turvo.
+func (c *Client) CreateOrder(ctx context.Context, order Order) (*Order, error) {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	resp, bodyBytes, err := c.do(ctx, http.MethodPost, "orders?fullResponse=true", payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse("create order", resp, bodyBytes); err != nil {
+		slog.WarnContext(ctx, "Turvo create order failed", "status", resp.Status, "error", err)
+		slog.DebugContext(ctx, "Turvo create order failed response", "body", logging.Body(bodyBytes))
+		return nil, err
+	}
+	return decodeOrder(bodyBytes)
+}
+
+// GetOrder fetches an order by ID.
+func (c *Client) GetOrder(ctx context.Context, id string) (*Order, error) {
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, fmt.Sprintf("orders/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse("get order", resp, bodyBytes); err != nil {
+		return nil, err
+	}
+	return decodeOrder(bodyBytes)
+}
+
+// ListOrdersPage fetches one page of orders with optional filters.
+func (c *Client) ListOrdersPage(ctx context.Context, q url.Values) ([]Order, bool, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	if _, ok := q["start"]; !ok {
+		q.Set("start", "0")
+	}
+	if _, ok := q["pageSize"]; !ok {
+		q.Set("pageSize", "50")
+	}
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, "orders/list?"+q.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkResponse("list orders", resp, bodyBytes); err != nil {
+		return nil, false, err
+	}
+	var wrapped struct {
+		Status  string `json:"Status"`
+		Details struct {
+			Orders     []Order `json:"orders"`
+			Pagination struct {
+				MoreAvailable bool `json:"moreAvailable"`
+			} `json:"pagination"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && wrapped.Details.Orders != nil {
+		return wrapped.Details.Orders, wrapped.Details.Pagination.MoreAvailable, nil
+	}
+	var orders []Order
+	if err := json.Unmarshal(bodyBytes, &orders); err != nil {
+		return nil, false, err
+	}
+	return orders, false, nil
+}
+
+// decodeOrder accepts either a wrapped { details: order } body or a bare order.
+func decodeOrder(body []byte) (*Order, error) {
+	var wrapped struct {
+		Status  string          `json:"Status"`
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil && len(wrapped.Details) > 0 {
+		var o Order
+		if err := json.Unmarshal(wrapped.Details, &o); err == nil && o.ID != 0 {
+			return &o, nil
+		}
+	}
+	var o Order
+	if err := json.Unmarshal(body, &o); err != nil {
+		return nil, fmt.Errorf("order decode error: %w", err)
+	}
+	if o.ID == 0 {
+		return nil, fmt.Errorf("empty or unrecognized order response")
+	}
+	return &o, nil
+}
+
+// ListLocations fetches locations matching the given filters (e.g. name[eq]).
+func (c *Client) ListLocations(ctx context.Context, q url.Values) ([]LocationRecord, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	if _, ok := q["pageSize"]; !ok {
+		q.Set("pageSize", "25")
+	}
+	resp, bodyBytes, err := c.do(ctx, http.MethodGet, "locations/list?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse("list locations", resp, bodyBytes); err != nil {
+		return nil, err
+	}
+	var wrapped struct {
+		Status  string `json:"Status"`
+		Details struct {
+			Locations []LocationRecord `json:"locations"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && wrapped.Details.Locations != nil {
+		return wrapped.Details.Locations, nil
+	}
+	var arr []LocationRecord
+	if err := json.Unmarshal(bodyBytes, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+// CreateLocation creates a location in Turvo.
+func (c *Client) CreateLocation(ctx context.Context, loc LocationRecord) (*LocationRecord, error) {
+	payload, err := json.Marshal(loc)
+	if err != nil {
+		return nil, err
+	}
+	resp, bodyBytes, err := c.do(ctx, http.MethodPost, "locations", payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse("create location", resp, bodyBytes); err != nil {
+		return nil, err
+	}
+	var wrapped struct {
+		Status  string          `json:"Status"`
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(bodyBytes, &wrapped); err == nil && len(wrapped.Details) > 0 {
+		var created LocationRecord
+		if err := json.Unmarshal(wrapped.Details, &created); err == nil && created.ID != 0 {
+			return &created, nil
+		}
+	}
+	var created LocationRecord
+	if err := json.Unmarshal(bodyBytes, &created); err != nil {
+		return nil, fmt.Errorf("location decode error: %w", err)
+	}
+	return &created, nil
+}
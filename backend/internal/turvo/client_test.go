@@ -0,0 +1,392 @@
+package turvo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo/turvotest"
+)
+
+func newTestClient(t *testing.T) (*turvo.Client, *turvotest.Server) {
+	t.Helper()
+	srv := turvotest.NewServer()
+	t.Cleanup(srv.Close)
+	c, err := turvo.NewClient(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetRetryPolicy(turvo.RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     time.Millisecond,
+		MaxDelay:      5 * time.Millisecond,
+		MaxRetryAfter: 2 * time.Second,
+	})
+	return c, srv
+}
+
+func TestListShipmentsWrappedAndBare(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddShipment(turvo.Shipment{CustomID: "A-1"})
+	srv.AddShipment(turvo.Shipment{CustomID: "A-2"})
+	srv.AddShipment(turvo.Shipment{CustomID: "A-3"})
+
+	q := url.Values{"start": {"0"}, "pageSize": {"2"}}
+	got, page, err := c.ListShipmentsPageWithQuery(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !page.MoreAvailable {
+		t.Fatalf("wrapped: got %d shipments, moreAvailable=%v", len(got), page.MoreAvailable)
+	}
+	if page.LastObjectKey != strconv.Itoa(got[1].ID) {
+		t.Fatalf("lastObjectKey = %q, want %d", page.LastObjectKey, got[1].ID)
+	}
+	next, _, err := c.ListShipmentsPageWithQuery(context.Background(), url.Values{"lastObjectKey": {page.LastObjectKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(next) != 1 || next[0].CustomID != "A-3" {
+		t.Fatalf("after lastObjectKey: got %+v", next)
+	}
+
+	srv.BareLists = true
+	got, page, err = c.ListShipmentsPageWithQuery(context.Background(), url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || page.MoreAvailable {
+		t.Fatalf("bare: got %d shipments, moreAvailable=%v", len(got), page.MoreAvailable)
+	}
+}
+
+func TestGetShipmentNotFoundIsAPIError(t *testing.T) {
+	c, _ := newTestClient(t)
+	_, err := c.GetShipment(context.Background(), "42")
+	var apiErr *turvo.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("want *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "NOT_FOUND" {
+		t.Fatalf("got status %d code %q", apiErr.StatusCode, apiErr.Code)
+	}
+}
+
+func TestCreateShipmentValidationErrors(t *testing.T) {
+	c, _ := newTestClient(t)
+	_, err := c.CreateShipment(context.Background(), turvo.Shipment{CustomID: "X"})
+	var apiErr *turvo.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("want *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || len(apiErr.FieldErrors) != 2 {
+		t.Fatalf("got status %d fields %+v", apiErr.StatusCode, apiErr.FieldErrors)
+	}
+}
+
+func TestRefreshesTokenOnceOn401(t *testing.T) {
+	c, srv := newTestClient(t)
+	id := srv.AddShipment(turvo.Shipment{CustomID: "R-1"})
+	ctx := context.Background()
+	if _, err := c.GetShipment(ctx, strconv.Itoa(id)); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	if _, err := c.GetShipment(ctx, strconv.Itoa(id)); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.GrantCalls("password"); n != 1 {
+		t.Errorf("password grants = %d, want 1", n)
+	}
+	if n := srv.GrantCalls("refresh_token"); n != 1 {
+		t.Errorf("refresh grants = %d, want 1", n)
+	}
+
+	// a 401 that survives the refresh is returned, not retried forever
+	before := srv.Calls(http.MethodGet, "shipments/"+strconv.Itoa(id))
+	srv.InjectFault(http.MethodGet, "shipments/", turvotest.Fault{Status: http.StatusUnauthorized, Times: 5})
+	_, err := c.GetShipment(ctx, strconv.Itoa(id))
+	var apiErr *turvo.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("want 401 APIError, got %v", err)
+	}
+	if n := srv.Calls(http.MethodGet, "shipments/"+strconv.Itoa(id)) - before; n != 2 {
+		t.Errorf("get calls = %d, want 2", n)
+	}
+}
+
+func TestRetriesServerErrorsOnlyWhenIdempotent(t *testing.T) {
+	c, srv := newTestClient(t)
+	id := srv.AddShipment(turvo.Shipment{CustomID: "S-1"})
+	ctx := context.Background()
+
+	srv.InjectFault(http.MethodGet, "shipments/", turvotest.Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	if _, err := c.GetShipment(ctx, strconv.Itoa(id)); err != nil {
+		t.Fatalf("get after two 503s: %v", err)
+	}
+
+	valid := turvo.Shipment{
+		CustomID:  "S-2",
+		StartDate: turvo.DateWithTZ{Date: time.Now()},
+		CustomerOrder: []turvo.CustomerOrder{{Customer: &struct {
+			ID   int    `json:"id"`
+			Name string `json:"name,omitempty"`
+		}{ID: 7}}},
+	}
+	srv.InjectFault(http.MethodPost, "shipments", turvotest.Fault{Status: http.StatusBadGateway})
+	if _, err := c.CreateShipment(ctx, valid); err == nil {
+		t.Fatal("create after 502 should fail without retry")
+	}
+	if n := srv.Calls(http.MethodPost, "shipments"); n != 1 {
+		t.Errorf("create calls = %d, want 1", n)
+	}
+
+	// a create marked safe is retried after a 502 or a dropped connection
+	safe := turvo.WithSafeRetry(ctx)
+	srv.InjectFault(http.MethodPost, "shipments", turvotest.Fault{Status: http.StatusBadGateway})
+	if _, err := c.CreateShipment(safe, valid); err != nil {
+		t.Fatalf("safe create after 502: %v", err)
+	}
+	valid.CustomID = "S-3"
+	srv.InjectFault(http.MethodPost, "shipments", turvotest.Fault{Status: 0})
+	if _, err := c.CreateShipment(safe, valid); err != nil {
+		t.Fatalf("safe create after network error: %v", err)
+	}
+	if n := srv.Calls(http.MethodPost, "shipments"); n != 5 {
+		t.Errorf("create calls = %d, want 5", n)
+	}
+
+	// one that was applied before failing is found, not created again
+	valid.CustomID = "S-4"
+	srv.InjectFault(http.MethodPost, "shipments", turvotest.Fault{Status: http.StatusBadGateway, Applied: true})
+	created, err := c.CreateShipment(safe, valid)
+	if err != nil || created.CustomID != "S-4" {
+		t.Fatalf("safe create applied before 502 = %+v, %v", created, err)
+	}
+	if n := srv.Calls(http.MethodPost, "shipments"); n != 6 {
+		t.Errorf("create calls = %d, want 6", n)
+	}
+	count := map[string]int{}
+	for _, sh := range srv.Shipments() {
+		count[sh.CustomID]++
+	}
+	for _, id := range []string{"S-2", "S-3", "S-4"} {
+		if count[id] != 1 {
+			t.Errorf("%s shipments = %d, want 1", id, count[id])
+		}
+	}
+}
+
+func TestRetriesNetworkErrors(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.AddCustomer(turvo.MinimalCustomer{ID: 1, Name: "Acme"})
+	srv.InjectFault(http.MethodGet, "customers/list", turvotest.Fault{Status: 0})
+	got, err := c.ListCustomers(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "Acme" {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestRateLimitRespectsRetryAfter(t *testing.T) {
+	c, srv := newTestClient(t)
+	ctx := context.Background()
+
+	srv.InjectFault(http.MethodGet, "customers/list", turvotest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "1"})
+	if _, err := c.ListCustomers(ctx, nil); err != nil {
+		t.Fatalf("short Retry-After should be waited out: %v", err)
+	}
+
+	srv.InjectFault(http.MethodGet, "customers/list", turvotest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "120"})
+	_, err := c.ListCustomers(ctx, nil)
+	var rl turvo.RateLimitedError
+	if !errors.As(err, &rl) || rl.RetryAfter != 120*time.Second {
+		t.Fatalf("want RateLimitedError after 120s, got %v", err)
+	}
+}
+
+func TestOAuthRateLimitCooldown(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.InjectFault(http.MethodPost, "oauth/token", turvotest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "30"})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := c.ListCustomers(ctx, nil)
+		var rl turvo.RateLimitedError
+		if !errors.As(err, &rl) {
+			t.Fatalf("call %d: want RateLimitedError, got %v", i, err)
+		}
+	}
+	if n := srv.Calls(http.MethodPost, "oauth/token"); n != 1 {
+		t.Errorf("token calls = %d, want 1 during cooldown", n)
+	}
+}
+
+func TestFindShipmentByExternalID(t *testing.T) {
+	c, srv := newTestClient(t)
+	ctx := context.Background()
+	srv.AddShipment(turvo.Shipment{CustomID: "ONE"})
+	srv.AddShipment(turvo.Shipment{CustomID: "DUP"})
+	srv.AddShipment(turvo.Shipment{CustomID: "DUP"})
+
+	if s, err := c.FindShipmentByExternalID(ctx, "ONE"); err != nil || s.CustomID != "ONE" {
+		t.Fatalf("ONE: %v %v", s, err)
+	}
+	if _, err := c.FindShipmentByExternalID(ctx, "NONE"); !errors.Is(err, turvo.ErrShipmentNotFound) {
+		t.Fatalf("NONE: want ErrShipmentNotFound, got %v", err)
+	}
+	var ambiguous turvo.AmbiguousExternalIDError
+	if _, err := c.FindShipmentByExternalID(ctx, "DUP"); !errors.As(err, &ambiguous) || len(ambiguous.ShipmentIDs) != 2 {
+		t.Fatalf("DUP: want AmbiguousExternalIDError, got %v", err)
+	}
+}
+
+func TestFindShipmentByExternalIDAfterListing(t *testing.T) {
+	c, srv := newTestClient(t)
+	ctx := context.Background()
+	srv.AddShipment(turvo.Shipment{CustomID: "DUP"})
+	srv.AddShipment(turvo.Shipment{CustomID: "DUP"})
+
+	// listing fills the local index; the lookup must still see both
+	if _, err := c.ListShipments(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var ambiguous turvo.AmbiguousExternalIDError
+	if _, err := c.FindShipmentByExternalID(ctx, "DUP"); !errors.As(err, &ambiguous) || len(ambiguous.ShipmentIDs) != 2 {
+		t.Fatalf("DUP after listing: want AmbiguousExternalIDError, got %v", err)
+	}
+
+	// a shipment found once, then duplicated behind the client's back
+	srv.AddShipment(turvo.Shipment{CustomID: "LATER"})
+	if _, err := c.FindShipmentByExternalID(ctx, "LATER"); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddShipment(turvo.Shipment{CustomID: "LATER"})
+	if _, err := c.FindShipmentByExternalID(ctx, "LATER"); !errors.As(err, &ambiguous) {
+		t.Fatalf("LATER: want AmbiguousExternalIDError, got %v", err)
+	}
+}
+
+func TestAuthModes(t *testing.T) {
+	srv := turvotest.NewServer()
+	t.Cleanup(srv.Close)
+	id := strconv.Itoa(srv.AddShipment(turvo.Shipment{CustomID: "M-1"}))
+
+	for _, tc := range []struct {
+		mode      string
+		configure func(*config.Config)
+		grant     string
+	}{
+		{config.AuthOAuthPassword, func(*config.Config) {}, "password"},
+		{config.AuthOAuthClientCredentials, func(c *config.Config) { c.TurvoOAuthUsername, c.TurvoOAuthPassword = "", "" }, "client_credentials"},
+		{config.AuthAPIKey, func(c *config.Config) { c.TurvoAPIKey = turvotest.APIKey }, ""},
+	} {
+		cfg := srv.Config()
+		cfg.TurvoAuthMode = tc.mode
+		tc.configure(cfg)
+		c, err := turvo.NewClient(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenCalls := srv.Calls(http.MethodPost, "oauth/token")
+		grantCalls := srv.GrantCalls(tc.grant)
+		if _, err := c.GetShipment(context.Background(), id); err != nil {
+			t.Errorf("%s: %v", tc.mode, err)
+			continue
+		}
+		if tc.grant == "" {
+			if n := srv.Calls(http.MethodPost, "oauth/token") - tokenCalls; n != 0 {
+				t.Errorf("%s: %d token requests, want none", tc.mode, n)
+			}
+		} else if n := srv.GrantCalls(tc.grant) - grantCalls; n != 1 {
+			t.Errorf("%s: %d %s grants, want 1", tc.mode, n, tc.grant)
+		}
+	}
+}
+
+func TestSetCredentialsAfterRejection(t *testing.T) {
+	srv := turvotest.NewServer()
+	t.Cleanup(srv.Close)
+	id := strconv.Itoa(srv.AddShipment(turvo.Shipment{CustomID: "R-1"}))
+	cfg := srv.Config()
+	cfg.TurvoOAuthPassword = "stale"
+	c, err := turvo.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := 0
+	c.SetOnAuthRejected(func() { rejected++ })
+
+	if _, err := c.GetShipment(context.Background(), id); err == nil {
+		t.Fatal("want error with stale password")
+	}
+	if rejected != 1 {
+		t.Fatalf("OnAuthRejected called %d times, want 1", rejected)
+	}
+
+	creds := cfg.Credentials()
+	creds.Password = turvotest.Password
+	c.SetCredentials(creds)
+	if _, err := c.GetShipment(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthRejectedHookMayUseClient(t *testing.T) {
+	srv := turvotest.NewServer()
+	t.Cleanup(srv.Close)
+	id := strconv.Itoa(srv.AddShipment(turvo.Shipment{CustomID: "R-1"}))
+	cfg := srv.Config()
+	cfg.TurvoOAuthPassword = "stale"
+	c, err := turvo.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the hook swaps in fresh credentials right away, as a secret refresh
+	// that finds them cached would
+	creds := cfg.Credentials()
+	creds.Password = turvotest.Password
+	c.SetOnAuthRejected(func() { c.SetCredentials(creds) })
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetShipment(context.Background(), id)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("want error with stale password")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("client deadlocked calling the hook")
+	}
+	if _, err := c.GetShipment(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPageInfoLastObjectKeyForms(t *testing.T) {
+	for raw, want := range map[string]string{
+		`{"moreAvailable":true,"lastObjectKey":"abc"}`: "abc",
+		`{"moreAvailable":true,"lastObjectKey":12345}`: "12345",
+		`{"moreAvailable":true,"lastObjectKey":null}`:  "",
+		`{"moreAvailable":true}`:                       "",
+	} {
+		var p turvo.PageInfo
+		if err := json.Unmarshal([]byte(raw), &p); err != nil {
+			t.Fatal(err)
+		}
+		if !p.MoreAvailable || p.LastObjectKey != want {
+			t.Errorf("%s: got %+v, want key %q", raw, p, want)
+		}
+	}
+}
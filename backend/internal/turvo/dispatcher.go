@@ -0,0 +1,49 @@
+package turvo
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// EventHandler receives verified webhook events.
+type EventHandler func(ctx context.Context, ev Event)
+
+// Dispatcher fans out verified webhook events to subscribers. Handlers are
+// invoked synchronously in subscription order; a panicking handler is logged
+// and does not stop delivery to the others.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// NewDispatcher creates an empty Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string][]EventHandler)}
+}
+
+// Subscribe registers h for the given event type. An empty type subscribes to
+// every event.
+func (d *Dispatcher) Subscribe(eventType string, h EventHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[eventType] = append(d.handlers[eventType], h)
+}
+
+// Dispatch delivers ev to the handlers for its type and to catch-all handlers.
+func (d *Dispatcher) Dispatch(ctx context.Context, ev Event) {
+	d.mu.RLock()
+	hs := append([]EventHandler{}, d.handlers[ev.EventType()]...)
+	hs = append(hs, d.handlers[""]...)
+	d.mu.RUnlock()
+	for _, h := range hs {
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					slog.Error("Webhook handler panic", "event_type", ev.EventType(), "event_id", ev.EventID(), "panic", rec)
+				}
+			}()
+			h(ctx, ev)
+		}()
+	}
+}
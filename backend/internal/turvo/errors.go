@@ -0,0 +1,119 @@
+package turvo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldError describes a validation failure on a single payload field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is a failed Turvo API call. StatusCode is the HTTP status Turvo
+// returned, or 400 when Turvo answered 2xx with an ERROR status envelope.
+type APIError struct {
+	Op          string
+	StatusCode  int
+	Code        string
+	Message     string
+	FieldErrors []FieldError
+	RequestID   string
+	// Body is the raw response body, kept for logging.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "turvo %s: %d %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		b.WriteString(" - " + e.Code)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	for _, fe := range e.FieldErrors {
+		fmt.Fprintf(&b, "; %s: %s", fe.Field, fe.Message)
+	}
+	return b.String()
+}
+
+// requestIDHeaders are checked in order for a request id to report.
+var requestIDHeaders = []string{"X-Request-Id", "X-Turvo-Request-Id", "X-Amzn-Requestid"}
+
+// checkResponse returns an *APIError when resp is not 2xx or when the body is
+// Turvo's {"Status":"ERROR"} envelope, and nil otherwise.
+func checkResponse(op string, resp *http.Response, body []byte) error {
+	ok := resp.StatusCode >= 200 && resp.StatusCode < 300
+	var env errorEnvelope
+	parsed := json.Unmarshal(body, &env) == nil
+	if ok && (!parsed || !strings.EqualFold(env.Status, "ERROR")) {
+		return nil
+	}
+	e := &APIError{Op: op, StatusCode: resp.StatusCode, Body: string(body)}
+	if ok {
+		e.StatusCode = http.StatusBadRequest
+	}
+	for _, h := range requestIDHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			e.RequestID = v
+			break
+		}
+	}
+	if !parsed {
+		e.Message = strings.TrimSpace(string(body))
+		return e
+	}
+	e.Code = firstNonEmpty(env.Details.ErrorCode, env.Code, env.Error)
+	e.Message = firstNonEmpty(env.Details.ErrorMessage, env.Details.Message, env.Message, env.ErrorDescription)
+	if e.RequestID == "" {
+		e.RequestID = env.RequestID
+	}
+	for _, fe := range append(env.Errors, env.Details.Errors...) {
+		field := firstNonEmpty(fe.Field, fe.Path, fe.Name)
+		msg := firstNonEmpty(fe.Message, fe.ErrorMessage)
+		if field != "" || msg != "" {
+			e.FieldErrors = append(e.FieldErrors, FieldError{Field: field, Message: msg})
+		}
+	}
+	return e
+}
+
+// errorEnvelope covers the error body shapes seen from Turvo: the wrapped
+// {"Status","details":{errorCode,errorMessage}} form, OAuth-style
+// {"error","error_description"}, and plain {"code","message","errors"}.
+type errorEnvelope struct {
+	Status  string `json:"Status"`
+	Details struct {
+		ErrorCode    string          `json:"errorCode"`
+		ErrorMessage string          `json:"errorMessage"`
+		Message      string          `json:"message"`
+		Errors       []rawFieldError `json:"errors"`
+	} `json:"details"`
+	Code             string          `json:"code"`
+	Message          string          `json:"message"`
+	Error            string          `json:"error"`
+	ErrorDescription string          `json:"error_description"`
+	RequestID        string          `json:"requestId"`
+	Errors           []rawFieldError `json:"errors"`
+}
+
+type rawFieldError struct {
+	Field        string `json:"field"`
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	Message      string `json:"message"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package turvo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrShipmentNotFound is returned when no shipment matches a lookup.
+var ErrShipmentNotFound = errors.New("shipment not found")
+
+// AmbiguousExternalIDError is returned when more than one Turvo shipment
+// carries the same customId.
+type AmbiguousExternalIDError struct {
+	ExternalID  string
+	ShipmentIDs []int
+}
+
+func (e AmbiguousExternalIDError) Error() string {
+	ids := make([]string, len(e.ShipmentIDs))
+	for i, id := range e.ShipmentIDs {
+		ids[i] = fmt.Sprint(id)
+	}
+	return fmt.Sprintf("external id %s matches multiple shipments: %s", e.ExternalID, strings.Join(ids, ", "))
+}
+
+// externalIDIndex remembers the Turvo id for each customId the client has
+// seen, so a shipment the list endpoint does not return yet can still be
+// fetched from shipments/{id}.
+type externalIDIndex struct {
+	mu  sync.RWMutex
+	ids map[string]int
+}
+
+func newExternalIDIndex() *externalIDIndex {
+	return &externalIDIndex{ids: make(map[string]int)}
+}
+
+// observe records the customId to id mapping of each shipment.
+func (x *externalIDIndex) observe(shipments ...Shipment) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, s := range shipments {
+		if s.ID != 0 && s.CustomID != "" {
+			x.ids[s.CustomID] = s.ID
+		}
+	}
+}
+
+func (x *externalIDIndex) lookup(externalID string) (int, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	id, ok := x.ids[externalID]
+	return id, ok
+}
+
+func (x *externalIDIndex) forget(externalID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.ids, externalID)
+}
@@ -0,0 +1,156 @@
+package turvo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// LocationDirectory searches and creates Turvo locations. It is satisfied by
+// *Client.
+type LocationDirectory interface {
+	ListLocations(ctx context.Context, q url.Values) ([]LocationRecord, error)
+	CreateLocation(ctx context.Context, loc LocationRecord) (*LocationRecord, error)
+}
+
+// LocationResolver maps Drumkit stop addresses to Turvo location ids. It
+// searches Turvo by name, matches on address, creates the location when none
+// matches, and caches the address-to-id result for the process lifetime.
+type LocationResolver struct {
+	client LocationDirectory
+	mu     sync.Mutex
+	cache  map[string]int
+}
+
+// NewLocationResolver creates a resolver backed by client.
+func NewLocationResolver(client LocationDirectory) *LocationResolver {
+	return &LocationResolver{client: client, cache: make(map[string]int)}
+}
+
+// ResolveLoad fills TurvoLocationID on the pickup, consignee and every stop
+// that has an address but no location yet.
+func (r *LocationResolver) ResolveLoad(ctx context.Context, load *domain.Load) error {
+	return r.resolveLoad(ctx, load, true)
+}
+
+// FindLoad is ResolveLoad without creating locations: stops whose address
+// has no Turvo location yet are left without one. Dry runs use it so they
+// write nothing to Turvo.
+func (r *LocationResolver) FindLoad(ctx context.Context, load *domain.Load) error {
+	return r.resolveLoad(ctx, load, false)
+}
+
+func (r *LocationResolver) resolveLoad(ctx context.Context, load *domain.Load, create bool) error {
+	if err := r.resolve(ctx, &load.Pickup, create); err != nil {
+		return fmt.Errorf("pickup: %w", err)
+	}
+	if err := r.resolve(ctx, &load.Consignee, create); err != nil {
+		return fmt.Errorf("consignee: %w", err)
+	}
+	for i := range load.Stops {
+		if err := r.resolve(ctx, &load.Stops[i], create); err != nil {
+			return fmt.Errorf("stop %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Resolve sets stop.TurvoLocationID. Stops that already have an id, or have
+// no name or street address to search by, are left unchanged.
+func (r *LocationResolver) Resolve(ctx context.Context, stop *domain.Stop) error {
+	return r.resolve(ctx, stop, true)
+}
+
+func (r *LocationResolver) resolve(ctx context.Context, stop *domain.Stop, create bool) error {
+	if stop.TurvoLocationID > 0 || strings.TrimSpace(stop.Name) == "" || strings.TrimSpace(stop.AddressLine1) == "" {
+		return nil
+	}
+	key := addressKey(stop)
+	r.mu.Lock()
+	id, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		stop.TurvoLocationID = id
+		return nil
+	}
+
+	q := url.Values{}
+	q.Set("name[eq]", strings.TrimSpace(stop.Name))
+	found, err := r.client.ListLocations(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, loc := range found {
+		for _, addr := range loc.Address {
+			if addressMatches(addr, stop) {
+				id = loc.ID
+				break
+			}
+		}
+		if id != 0 {
+			break
+		}
+	}
+	if id == 0 && !create {
+		return nil
+	}
+	if id == 0 {
+		created, err := r.client.CreateLocation(ctx, LocationRecord{
+			Name: strings.TrimSpace(stop.Name),
+			Address: []LocationAddress{{
+				Line1:     stop.AddressLine1,
+				Line2:     stop.AddressLine2,
+				City:      stop.City,
+				State:     stop.State,
+				Zip:       stop.Zipcode,
+				Country:   stop.Country,
+				IsPrimary: true,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		id = created.ID
+	}
+	if id == 0 {
+		return fmt.Errorf("turvo returned no location id for %q", stop.Name)
+	}
+	r.mu.Lock()
+	r.cache[key] = id
+	r.mu.Unlock()
+	stop.TurvoLocationID = id
+	return nil
+}
+
+// addressKey normalizes the identifying parts of a stop for caching.
+func addressKey(stop *domain.Stop) string {
+	parts := []string{stop.Name, stop.AddressLine1, stop.City, stop.State, zip5(stop.Zipcode)}
+	for i, p := range parts {
+		parts[i] = normalize(p)
+	}
+	return strings.Join(parts, "|")
+}
+
+// addressMatches compares street, city, state and 5-digit zip loosely.
+func addressMatches(addr LocationAddress, stop *domain.Stop) bool {
+	return normalize(addr.Line1) == normalize(stop.AddressLine1) &&
+		normalize(addr.City) == normalize(stop.City) &&
+		normalize(addr.State) == normalize(stop.State) &&
+		(stop.Zipcode == "" || zip5(addr.Zip) == zip5(stop.Zipcode))
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(s, ".", ""))), " ")
+}
+
+func zip5(z string) string {
+	z = strings.TrimSpace(z)
+	if len(z) > 5 {
+		return z[:5]
+	}
+	return z
+}
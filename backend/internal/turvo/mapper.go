@@ -2,6 +2,7 @@ package turvo
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 
@@ -20,39 +21,26 @@ func NewMapper(cfg *config.Config) *Mapper {
 }
 
 // ToTurvoShipment converts a Drumkit Load into a Turvo Shipment. It composes
-// lane strings, selects defaults, and sets start/end dates.
+// lane strings, selects defaults, and sets start/end dates. When Stops is set
+// the ordered stops become the globalRoute and the first pickup and last
+// delivery define the lane. When every stop has a Turvo location the route is
+// sent with distance calculation enabled.
 func (m *Mapper) ToTurvoShipment(load *domain.Load) (Shipment, error) {
-	now := time.Now()
-	var pickupAt time.Time
-	if load.Pickup.ReadyTime != nil && !load.Pickup.ReadyTime.IsZero() {
-		pickupAt = *load.Pickup.ReadyTime
-	} else {
-		pickupAt = now
-	}
-	var deliveryAt time.Time
-	if load.Consignee.MustDeliver != nil && !load.Consignee.MustDeliver.IsZero() {
-		deliveryAt = *load.Consignee.MustDeliver
-	} else {
-		deliveryAt = pickupAt.Add(24 * time.Hour)
-	}
-
-	// Default location IDs (unused when SkipDistanceCalculation and lane are provided)
-	_ = m.cfg.TurvoDefaultOriginLocationID
-	_ = m.cfg.TurvoDefaultDestinationLocationID
-	custID := m.cfg.TurvoDefaultCustomerID
-	if load.Customer.TurvoID > 0 {
-		custID = load.Customer.TurvoID
+	pickup, consignee := load.Pickup, load.Consignee
+	if len(load.Stops) > 0 {
+		p, c, err := routeEndpoints(load.Stops)
+		if err != nil {
+			return Shipment{}, err
+		}
+		pickup, consignee = p, c
 	}
+	pickupAt, deliveryAt := shipmentWindow(pickup, consignee)
+
+	custID := m.CustomerID(load)
 
 	// Build lane strings in "city, state" format as required by Turvo
-	startLane := strings.TrimSpace(strings.Join([]string{
-		strings.TrimSpace(load.Pickup.City),
-		strings.TrimSpace(load.Pickup.State),
-	}, ", "))
-	endLane := strings.TrimSpace(strings.Join([]string{
-		strings.TrimSpace(load.Consignee.City),
-		strings.TrimSpace(load.Consignee.State),
-	}, ", "))
+	startLane := laneEndpoint(pickup)
+	endLane := laneEndpoint(consignee)
 
 	// Build customer order with nested customer id
 	co := CustomerOrder{
@@ -66,16 +54,259 @@ func (m *Mapper) ToTurvoShipment(load *domain.Load) (Shipment, error) {
 	shipment := Shipment{
 		CustomID:                load.ExternalTMSLoadID,
 		LtlShipment:             false,
-		StartDate:               DateWithTZ{Date: pickupAt, TimeZone: "UTC"},
-		EndDate:                 DateWithTZ{Date: deliveryAt, TimeZone: "UTC"},
+		StartDate:               stopDate(pickupAt, pickup),
+		EndDate:                 stopDate(deliveryAt, consignee),
 		CustomerOrder:           []CustomerOrder{co},
 		Lane:                    &Lane{Start: startLane, End: endLane},
 		SkipDistanceCalculation: true,
 		GlobalRoute:             nil,
 	}
+	if stops := m.routeStops(load); allLocated(stops) {
+		shipment.GlobalRoute = m.toGlobalRoute(stops, pickupAt, deliveryAt)
+		shipment.SkipDistanceCalculation = false
+	} else if len(load.Stops) > 0 {
+		shipment.GlobalRoute = m.toGlobalRoute(load.Stops, pickupAt, deliveryAt)
+	}
+	if load.Carrier != nil && load.Carrier.TurvoID > 0 {
+		co, err := m.ToTurvoCarrierOrder(load.Carrier)
+		if err != nil {
+			return Shipment{}, err
+		}
+		shipment.CarrierOrder = []CarrierOrder{co}
+	}
+	m.applyRates(&shipment, load)
+	m.applySpecifications(&shipment, load)
+	if st := m.toTurvoStatus(load.Status); st != nil {
+		shipment.Status = st
+	}
 	return shipment, nil
 }
 
+// CustomerID returns the Turvo customer ToTurvoShipment books load for: its
+// own customer, or the configured default.
+func (m *Mapper) CustomerID(load *domain.Load) int {
+	if load.Customer.TurvoID > 0 {
+		return load.Customer.TurvoID
+	}
+	return m.cfg.TurvoDefaultCustomerID
+}
+
+// UpdatedCustomerID returns the Turvo customer ApplyLoadUpdate leaves on the
+// shipment, so it can be authorized before anything is sent to Turvo.
+func (m *Mapper) UpdatedCustomerID(existing Shipment, load *domain.Load, fields map[string]bool) int {
+	if fields["customer"] && load.Customer.TurvoID > 0 {
+		return load.Customer.TurvoID
+	}
+	return existing.CustomerID()
+}
+
+// ApplyLoadUpdate overlays the sections of load named in fields onto a copy of
+// the existing Turvo shipment. fields holds the top-level JSON keys present in
+// the update request, so sections the caller did not send are left untouched.
+func (m *Mapper) ApplyLoadUpdate(existing Shipment, load *domain.Load, fields map[string]bool) (Shipment, error) {
+	updated := existing
+	if fields["externalTMSLoadID"] {
+		updated.CustomID = load.ExternalTMSLoadID
+	}
+	if fields["status"] {
+		st := m.toTurvoStatus(load.Status)
+		if st == nil {
+			return Shipment{}, fmt.Errorf("unknown status %q", load.Status)
+		}
+		updated.Status = st
+	}
+	if fields["customer"] && load.Customer.TurvoID > 0 {
+		orders := make([]CustomerOrder, len(existing.CustomerOrder))
+		copy(orders, existing.CustomerOrder)
+		if len(orders) == 0 {
+			orders = append(orders, CustomerOrder{CustomerOrderSourceID: 1})
+		}
+		orders[0].Customer = &struct {
+			ID   int    `json:"id"`
+			Name string `json:"name,omitempty"`
+		}{ID: load.Customer.TurvoID, Name: load.Customer.Name}
+		updated.CustomerOrder = orders
+	}
+	if fields["carrier"] && load.Carrier != nil {
+		co, err := m.ToTurvoCarrierOrder(load.Carrier)
+		if err != nil {
+			return Shipment{}, err
+		}
+		updated.CarrierOrder = AssignCarrierOrder(existing.CarrierOrder, co)
+	}
+	if fields["rateData"] || fields["carrier"] {
+		m.applyRates(&updated, load)
+	}
+	if fields["specifications"] {
+		m.applySpecifications(&updated, load)
+	}
+	if fields["stops"] && len(load.Stops) > 0 {
+		pickup, consignee, err := routeEndpoints(load.Stops)
+		if err != nil {
+			return Shipment{}, err
+		}
+		pickupAt, deliveryAt := shipmentWindow(pickup, consignee)
+		updated.GlobalRoute = m.toGlobalRoute(load.Stops, pickupAt, deliveryAt)
+		updated.SkipDistanceCalculation = !allLocated(load.Stops)
+		updated.Lane = &Lane{Start: laneEndpoint(pickup), End: laneEndpoint(consignee)}
+		updated.StartDate = stopDate(pickupAt, pickup)
+		updated.EndDate = stopDate(deliveryAt, consignee)
+		return updated, nil
+	}
+	if fields["pickup"] || fields["consignee"] {
+		lane := Lane{}
+		if existing.Lane != nil {
+			lane = *existing.Lane
+		}
+		if fields["pickup"] {
+			lane.Start = laneEndpoint(load.Pickup)
+			if load.Pickup.ReadyTime != nil && !load.Pickup.ReadyTime.IsZero() {
+				updated.StartDate = stopDate(*load.Pickup.ReadyTime, load.Pickup)
+			}
+		}
+		if fields["consignee"] {
+			lane.End = laneEndpoint(load.Consignee)
+			if load.Consignee.MustDeliver != nil && !load.Consignee.MustDeliver.IsZero() {
+				updated.EndDate = stopDate(*load.Consignee.MustDeliver, load.Consignee)
+			}
+		}
+		updated.Lane = &lane
+		updated.SkipDistanceCalculation = true
+		// Rebuild simple two-stop routes; multi-stop routes change via stops
+		if len(existing.GlobalRoute) <= 2 {
+			stops := m.routeStops(&domain.Load{Pickup: load.Pickup, Consignee: load.Consignee})
+			if allLocated(stops) {
+				updated.GlobalRoute = m.toGlobalRoute(stops, updated.StartDate.Date, updated.EndDate.Date)
+				updated.SkipDistanceCalculation = false
+			}
+		}
+	}
+	return updated, nil
+}
+
+// routeStops returns the ordered stops of a load. Loads without Stops get a
+// two-stop route from Pickup and Consignee, using the configured default
+// origin and destination locations when no location was resolved.
+func (m *Mapper) routeStops(load *domain.Load) []domain.Stop {
+	if len(load.Stops) > 0 {
+		return load.Stops
+	}
+	pickup, consignee := load.Pickup, load.Consignee
+	pickup.StopType, consignee.StopType = domain.StopTypePickup, domain.StopTypeDelivery
+	if pickup.TurvoLocationID == 0 {
+		pickup.TurvoLocationID = m.cfg.TurvoDefaultOriginLocationID
+	}
+	if consignee.TurvoLocationID == 0 {
+		consignee.TurvoLocationID = m.cfg.TurvoDefaultDestinationLocationID
+	}
+	return []domain.Stop{pickup, consignee}
+}
+
+// allLocated reports whether every stop has a Turvo location id.
+func allLocated(stops []domain.Stop) bool {
+	for _, st := range stops {
+		if st.TurvoLocationID <= 0 {
+			return false
+		}
+	}
+	return len(stops) > 0
+}
+
+// shipmentWindow returns the shipment start and end dates from the pickup and
+// delivery stops, each expressed in its stop's zone. Stop times are instants:
+// wall-clock times sent without an offset were placed in the stop's zone when
+// the load was decoded (see domain.ParseStopTime). Without times the pickup
+// defaults to today and the delivery to the following day, both as local
+// dates with no time of day.
+func shipmentWindow(pickup, consignee domain.Stop) (time.Time, time.Time) {
+	pickup.StopType = domain.StopTypePickup
+	consignee.StopType = domain.StopTypeDelivery
+	pickupLoc, deliveryLoc := pickup.Location(), consignee.Location()
+	pickupAt := localMidnight(time.Now(), pickupLoc)
+	if t := stopTime(pickup); t != nil {
+		pickupAt = t.In(pickupLoc)
+	}
+	// the day after the pickup's local date, wherever the delivery is
+	deliveryAt := time.Date(pickupAt.Year(), pickupAt.Month(), pickupAt.Day()+1, 0, 0, 0, 0, deliveryLoc)
+	if t := stopTime(consignee); t != nil {
+		deliveryAt = t.In(deliveryLoc)
+	}
+	return pickupAt, deliveryAt
+}
+
+// stopDate formats t as a Turvo date in the stop's zone.
+func stopDate(t time.Time, st domain.Stop) DateWithTZ {
+	loc := st.Location()
+	return DateWithTZ{Date: t.In(loc), TimeZone: loc.String()}
+}
+
+// localMidnight returns midnight of the given day in loc.
+func localMidnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// statusCodes maps Turvo shipment status display values to their codes.
+var statusCodes = map[string]string{
+	"Quote active":      "2100",
+	"Tendered":          "2101",
+	"Covered":           "2102",
+	"Dispatched":        "2103",
+	"At pickup":         "2104",
+	"En route":          "2105",
+	"At delivery":       "2106",
+	"Delivered":         "2107",
+	"Ready for billing": "2108",
+	"Processing":        "2109",
+	"Carrier paid":      "2110",
+	"Customer paid":     "2111",
+	"Completed":         "2112",
+	"Canceled":          "2113",
+	"Quote inactive":    "2114",
+	"Picked up":         "2115",
+	"Route Complete":    "2116",
+}
+
+// statusCode resolves either a status code ("2101") or its display value
+// ("Tendered") into a Turvo status code pair.
+func statusCode(status string) (KeyValuePair, bool) {
+	status = strings.TrimSpace(status)
+	if status == "" {
+		return KeyValuePair{}, false
+	}
+	for value, key := range statusCodes {
+		if key == status || strings.EqualFold(value, status) {
+			return KeyValuePair{Key: key, Value: value}, true
+		}
+	}
+	return KeyValuePair{}, false
+}
+
+// toTurvoStatus builds a Turvo shipment status payload. It returns nil when
+// the status is empty or not recognized.
+func (m *Mapper) toTurvoStatus(status string) json.RawMessage {
+	code, ok := statusCode(status)
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(Status{Code: code})
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// laneEndpoint formats a stop as a "city, state" lane string.
+func laneEndpoint(stop domain.Stop) string {
+	var parts []string
+	for _, p := range []string{stop.City, stop.State} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 // FromTurvoShipment converts a Turvo Shipment into a simplified Load for the UI.
 func (m *Mapper) FromTurvoShipment(s Shipment) (*domain.Load, error) {
 	// Try to parse Status.Code.Value if present; otherwise leave empty
@@ -104,8 +335,8 @@ func (m *Mapper) FromTurvoShipment(s Shipment) (*domain.Load, error) {
 		ExternalTMSLoadID: s.CustomID,
 		Status:            status,
 		CreatedAt:         s.CreatedDate,
-		Customer:          domain.Party{Name: customerName},
-		Specifications:    &domain.Specifications{},
+		Customer:          domain.Party{TurvoID: s.CustomerID(), Name: customerName},
+		Specifications:    m.fromTurvoSpecifications(s),
 	}
 
 	// If lane is present, populate pickup/consignee city/state for UI columns
@@ -129,6 +360,29 @@ func (m *Mapper) FromTurvoShipment(s Shipment) (*domain.Load, error) {
 		load.Consignee = domain.Stop{City: dc, State: ds}
 	}
 
+	// Shipment window in its own zone when the route does not provide times
+	if !s.StartDate.Date.IsZero() {
+		t := inZone(s.StartDate.Date, s.StartDate.TimeZone)
+		load.Pickup.ReadyTime, load.Pickup.Timezone = &t, s.StartDate.TimeZone
+	}
+	if !s.EndDate.Date.IsZero() {
+		t := inZone(s.EndDate.Date, s.EndDate.TimeZone)
+		load.Consignee.MustDeliver, load.Consignee.Timezone = &t, s.EndDate.TimeZone
+	}
+
+	// Ordered stops from globalRoute; endpoints keep the lane city/state
+	if len(s.GlobalRoute) > 0 {
+		load.Stops = m.fromGlobalRoute(s.GlobalRoute)
+		if p, c, err := routeEndpoints(load.Stops); err == nil {
+			p.City, p.State = load.Pickup.City, load.Pickup.State
+			c.City, c.State = load.Consignee.City, load.Consignee.State
+			load.Pickup, load.Consignee = p, c
+		}
+	}
+
+	load.Carrier = m.fromTurvoCarrierOrders(s.CarrierOrder, s.GlobalRoute)
+	load.RateData = m.fromTurvoRates(s)
+
 	// Optional enrichments from detailed shipment for table columns
 	if s.Phase.Value != "" {
 		load.Phase = s.Phase.Value
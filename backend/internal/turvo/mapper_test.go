@@ -0,0 +1,135 @@
+package turvo_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// roundTrip sends load through ToTurvoShipment, the wire format and
+// FromTurvoShipment, as a create followed by a read would.
+func roundTrip(t *testing.T, load *domain.Load) (turvo.Shipment, *domain.Load) {
+	t.Helper()
+	m := turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500})
+	s, err := m.ToTurvoShipment(load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(s)
+	var wire turvo.Shipment
+	if err := json.Unmarshal(b, &wire); err != nil {
+		t.Fatal(err)
+	}
+	back, err := m.FromTurvoShipment(wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, back
+}
+
+func decodeLoad(t *testing.T, body string) *domain.Load {
+	t.Helper()
+	var l domain.Load
+	if err := json.Unmarshal([]byte(body), &l); err != nil {
+		t.Fatal(err)
+	}
+	return &l
+}
+
+func TestShipmentDatesUseStopZones(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		pickupTime      string
+		wantStart       string
+		deliveryTime    string
+		wantEnd         string
+		wantStartZone   string
+		wantEndZoneName string
+	}{
+		{
+			name:       "wall-clock times are local to each stop",
+			pickupTime: "2026-03-02T08:00", wantStart: "2026-03-02T08:00:00-06:00",
+			deliveryTime: "2026-03-03T17:30", wantEnd: "2026-03-03T17:30:00-08:00",
+			wantStartZone: "America/Chicago", wantEndZoneName: "America/Los_Angeles",
+		},
+		{
+			// 8am Eastern sent as an instant stays that instant
+			name:       "instants are kept",
+			pickupTime: "2026-03-02T13:00:00Z", wantStart: "2026-03-02T07:00:00-06:00",
+			deliveryTime: "2026-07-01T12:00:00Z", wantEnd: "2026-07-01T05:00:00-07:00",
+			wantStartZone: "America/Chicago", wantEndZoneName: "America/Los_Angeles",
+		},
+	} {
+		load := decodeLoad(t, `{
+			"externalTMSLoadID": "TZ-1",
+			"pickup": {"city": "Chicago", "state": "IL", "zipcode": "60601", "readyTime": "`+tc.pickupTime+`"},
+			"consignee": {"city": "Los Angeles", "state": "CA", "zipcode": "90001", "mustDeliver": "`+tc.deliveryTime+`"}
+		}`)
+		s, back := roundTrip(t, load)
+		if got := s.StartDate.Date.Format(time.RFC3339); got != tc.wantStart || s.StartDate.TimeZone != tc.wantStartZone {
+			t.Errorf("%s: startDate = %s %s, want %s %s", tc.name, got, s.StartDate.TimeZone, tc.wantStart, tc.wantStartZone)
+		}
+		if got := s.EndDate.Date.Format(time.RFC3339); got != tc.wantEnd || s.EndDate.TimeZone != tc.wantEndZoneName {
+			t.Errorf("%s: endDate = %s %s, want %s %s", tc.name, got, s.EndDate.TimeZone, tc.wantEnd, tc.wantEndZoneName)
+		}
+		if back.Pickup.ReadyTime == nil || back.Pickup.ReadyTime.Format(time.RFC3339) != tc.wantStart || back.Pickup.Timezone != tc.wantStartZone {
+			t.Errorf("%s: read back pickup = %v %s", tc.name, back.Pickup.ReadyTime, back.Pickup.Timezone)
+		}
+		if back.Consignee.MustDeliver == nil || back.Consignee.MustDeliver.Format(time.RFC3339) != tc.wantEnd {
+			t.Errorf("%s: read back delivery = %v", tc.name, back.Consignee.MustDeliver)
+		}
+	}
+}
+
+func TestShipmentDatesDefaultToLocalDays(t *testing.T) {
+	load := decodeLoad(t, `{"pickup": {"state": "NY"}, "consignee": {"state": "AZ"}}`)
+	s, _ := roundTrip(t, load)
+	start, end := s.StartDate.Date, s.EndDate.Date
+	if start.Hour() != 0 || start.Minute() != 0 || s.StartDate.TimeZone != "America/New_York" {
+		t.Errorf("startDate = %v %s, want local midnight in New York", start, s.StartDate.TimeZone)
+	}
+	if end.Hour() != 0 || s.EndDate.TimeZone != "America/Phoenix" {
+		t.Errorf("endDate = %v %s, want local midnight in Phoenix", end, s.EndDate.TimeZone)
+	}
+	if got, want := end.Format(time.DateOnly), start.AddDate(0, 0, 1).Format(time.DateOnly); got != want {
+		t.Errorf("endDate day = %s, want the day after pickup (%s)", got, want)
+	}
+}
+
+func TestRouteAppointmentsRoundTrip(t *testing.T) {
+	load := decodeLoad(t, `{
+		"externalTMSLoadID": "TZ-2",
+		"stops": [
+			{"stopType": "pickup", "turvoLocationId": 1, "city": "Denver", "state": "CO", "apptTime": "2026-03-02T09:15"},
+			{"stopType": "delivery", "turvoLocationId": 2, "city": "El Paso", "state": "TX", "zipcode": "79901", "apptTime": "2026-03-03T06:00"},
+			{"stopType": "delivery", "turvoLocationId": 3, "city": "Houston", "state": "TX", "timezone": "America/Chicago"}
+		]
+	}`)
+	s, back := roundTrip(t, load)
+	if len(s.GlobalRoute) != 3 {
+		t.Fatalf("globalRoute = %+v", s.GlobalRoute)
+	}
+	for i, want := range []struct{ zone, at string }{
+		{"America/Denver", "2026-03-02T09:15:00-07:00"},
+		{"America/Denver", "2026-03-03T06:00:00-07:00"},
+		{"America/Chicago", ""},
+	} {
+		gr := s.GlobalRoute[i]
+		if gr.Timezone != want.zone || gr.Appointment.Timezone != want.zone {
+			t.Errorf("stop %d zone = %s/%s, want %s", i+1, gr.Timezone, gr.Appointment.Timezone, want.zone)
+		}
+		if want.at != "" && (gr.Appointment.Date.Format(time.RFC3339) != want.at || !gr.Appointment.HasTime) {
+			t.Errorf("stop %d appointment = %s hasTime=%v, want %s", i+1, gr.Appointment.Date.Format(time.RFC3339), gr.Appointment.HasTime, want.at)
+		}
+	}
+	if len(back.Stops) != 3 {
+		t.Fatalf("stops read back = %+v", back.Stops)
+	}
+	if got := back.Stops[1].ApptTime; got == nil || got.Format(time.RFC3339) != "2026-03-03T06:00:00-07:00" || back.Stops[1].Timezone != "America/Denver" {
+		t.Errorf("El Paso appointment read back = %v %s", got, back.Stops[1].Timezone)
+	}
+}
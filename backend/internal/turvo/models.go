@@ -74,6 +74,26 @@ type Location struct {
 	ID int `json:"id"`
 }
 
+// LocationRecord is a Turvo location (facility) as listed or created via the
+// locations API.
+type LocationRecord struct {
+	ID      int               `json:"id,omitempty"`
+	Name    string            `json:"name"`
+	Address []LocationAddress `json:"address,omitempty"`
+}
+
+// LocationAddress is a postal address on a Turvo location.
+type LocationAddress struct {
+	Line1     string        `json:"line1"`
+	Line2     string        `json:"line2,omitempty"`
+	City      string        `json:"city"`
+	State     string        `json:"state"`
+	Zip       string        `json:"zip"`
+	Country   string        `json:"country,omitempty"`
+	Type      *KeyValuePair `json:"type,omitempty"`
+	IsPrimary bool          `json:"isPrimary,omitempty"`
+}
+
 // Appointment represents scheduling information for a stop.
 type Appointment struct {
 	Date         time.Time `json:"date"`
@@ -154,6 +174,17 @@ type Shipment struct {
 	UseRoutingGuide         bool            `json:"use_routing_guide,omitempty"`
 }
 
+// CustomerID returns the id of the shipment's first active customer order,
+// or 0 when it has none.
+func (s Shipment) CustomerID() int {
+	for _, co := range s.CustomerOrder {
+		if !co.Deleted && co.Customer != nil {
+			return co.Customer.ID
+		}
+	}
+	return 0
+}
+
 // Lane represents the start and end points of a shipment.
 type Lane struct {
 	Start string `json:"start"`
@@ -171,12 +202,71 @@ type CustomerOrder struct {
 	CustomerID            int     `json:"customerId,omitempty"`
 	CustomerOrderSourceID int     `json:"customerOrderSourceId,omitempty"`
 	TotalMiles            float64 `json:"totalMiles,omitempty"`
+	Costs                 *Costs  `json:"costs,omitempty"`
 }
 
-// CarrierOrder links a carrier to the shipment (kept for completeness).
+// CarrierOrder links a carrier to the shipment with its drivers, equipment
+// and dispatch milestones.
 type CarrierOrder struct {
-	CarrierID            int `json:"carrierId"`
-	CarrierOrderSourceID int `json:"carrierOrderSourceId"`
+	ID                       int               `json:"id,omitempty"`
+	Deleted                  bool              `json:"deleted,omitempty"`
+	CarrierID                int               `json:"carrierId"`
+	CarrierOrderSourceID     int               `json:"carrierOrderSourceId"`
+	Carrier                  *CarrierRef       `json:"carrier,omitempty"`
+	ExternalID               string            `json:"externalId,omitempty"`
+	Dispatcher               *CarrierContact   `json:"dispatcher,omitempty"`
+	Drivers                  []CarrierContact  `json:"drivers,omitempty"`
+	TruckNumber              string            `json:"truckNumber,omitempty"`
+	TrailerNumber            string            `json:"trailerNumber,omitempty"`
+	SealNumber               string            `json:"sealNumber,omitempty"`
+	DispatchLocation         *DispatchLocation `json:"dispatchLocation,omitempty"`
+	ConfirmationSentDate     *time.Time        `json:"confirmationSentDate,omitempty"`
+	ConfirmationReceivedDate *time.Time        `json:"confirmationReceivedDate,omitempty"`
+	DispatchedDate           *time.Time        `json:"dispatchedDate,omitempty"`
+	ExpectedPickupDate       *time.Time        `json:"expectedPickupDate,omitempty"`
+	ExpectedDeliveryDate     *time.Time        `json:"expectedDeliveryDate,omitempty"`
+	SignedBy                 string            `json:"signedBy,omitempty"`
+	Costs                    *Costs            `json:"costs,omitempty"`
+}
+
+// CarrierRef identifies the carrier on a carrier order.
+type CarrierRef struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name,omitempty"`
+	McNumber  string `json:"mcNumber,omitempty"`
+	DotNumber string `json:"dotNumber,omitempty"`
+	Scac      string `json:"scac,omitempty"`
+}
+
+// CarrierContact is a named contact on a carrier order (dispatcher or driver).
+type CarrierContact struct {
+	Name  string `json:"name,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// DispatchLocation is where the carrier dispatches the truck from.
+type DispatchLocation struct {
+	City  string `json:"city,omitempty"`
+	State string `json:"state,omitempty"`
+}
+
+// Costs holds the receivable (customer order) or payable (carrier order)
+// charges on a shipment.
+type Costs struct {
+	TotalAmount float64        `json:"totalAmount"`
+	LineItem    []CostLineItem `json:"lineItem,omitempty"`
+}
+
+// CostLineItem is a single charge such as linehaul or fuel surcharge.
+type CostLineItem struct {
+	Code     KeyValuePair  `json:"code"`
+	Qty      float64       `json:"qty"`
+	Price    float64       `json:"price"`
+	Amount   float64       `json:"amount"`
+	Units    *KeyValuePair `json:"units,omitempty"`
+	Billable bool          `json:"billable,omitempty"`
+	Notes    string        `json:"notes,omitempty"`
 }
 
 // Margin represents margin information for a shipment.
@@ -191,6 +281,8 @@ type Margin struct {
 
 // Order represents a Turvo Order.
 type Order struct {
+	ID                        int             `json:"id,omitempty"`
+	Status                    *Status         `json:"status,omitempty"`
 	StartDate                 time.Time       `json:"start_date"`
 	EndDate                   time.Time       `json:"end_date"`
 	PlannedPickup             *time.Time      `json:"planned_pickup,omitempty"`
@@ -200,21 +292,39 @@ type Order struct {
 	Customer                  OrderCustomer   `json:"customer"`
 	OrderType                 *KeyValuePair   `json:"order_type,omitempty"`
 	Direction                 *KeyValuePair   `json:"direction,omitempty"`
-	Origin                    interface{}     `json:"origin"`      // Define if structure is known
-	Destination               interface{}     `json:"destination"` // Define if structure is known
+	Origin                    *OrderLocation  `json:"origin"`
+	Destination               *OrderLocation  `json:"destination"`
 	OriginFlexAttributes      []FlexAttribute `json:"origin_flex_attributes,omitempty"`
 	DestinationFlexAttributes []FlexAttribute `json:"destination_flex_attributes,omitempty"`
 	Items                     []OrderItem     `json:"items"`
 	ExternalIDs               []ExternalID    `json:"external_ids,omitempty"`
 	FlexAttributes            []FlexAttribute `json:"flex_attributes,omitempty"`
-	Shipments                 []Shipment      `json:"shipments,omitempty"`
+	Shipments                 []OrderShipment `json:"shipments,omitempty"`
 	Carrier                   *OrderCarrier   `json:"carrier,omitempty"`
 	UserGroups                []interface{}   `json:"user_groups,omitempty"` // Define if structure is known
 }
 
 // OrderCustomer holds the customer ID for an order.
 type OrderCustomer struct {
-	ID int `json:"id"`
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// OrderLocation is the origin or destination of an order.
+type OrderLocation struct {
+	Location Location `json:"location"`
+	Name     string   `json:"name,omitempty"`
+	Address  string   `json:"address,omitempty"`
+	City     string   `json:"city,omitempty"`
+	State    string   `json:"state,omitempty"`
+	Zip      string   `json:"zip,omitempty"`
+	Country  string   `json:"country,omitempty"`
+}
+
+// OrderShipment links an order to a shipment planned from it.
+type OrderShipment struct {
+	ID       int    `json:"id"`
+	CustomID string `json:"customId,omitempty"`
 }
 
 // OrderItem represents an item within an order.
@@ -255,7 +365,8 @@ type OrderItem struct {
 
 // ItemQuantity represents the quantity of an item.
 type ItemQuantity struct {
-	// Define if structure is known
+	Value float64       `json:"value"`
+	Units *KeyValuePair `json:"units,omitempty"`
 }
 
 // ItemCategory represents the category of an item.
@@ -305,7 +416,8 @@ type ItemTemperature struct {
 
 // ItemWeight represents the weight of an item.
 type ItemWeight struct {
-	// Define if structure is known
+	Value float64       `json:"value"`
+	Units *KeyValuePair `json:"units,omitempty"`
 }
 
 // OrderCosts represents the costs of an order.
@@ -315,10 +427,51 @@ type OrderCosts struct {
 
 // ExternalID represents an external identifier for an order.
 type ExternalID struct {
-	// Define if structure is known
+	Type  KeyValuePair `json:"type"`
+	Value string       `json:"value"`
 }
 
 // OrderCarrier represents the carrier of an order.
 type OrderCarrier struct {
-	// Define if structure is known
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// PageInfo describes one page of a Turvo list.
+type PageInfo struct {
+	Start              int  `json:"start"`
+	PageSize           int  `json:"pageSize"`
+	TotalRecordsInPage int  `json:"totalRecordsInPage"`
+	MoreAvailable      bool `json:"moreAvailable"`
+	// LastObjectKey identifies the last record on the page. Sent back as the
+	// lastObjectKey query parameter it continues the list after that record,
+	// so records created meanwhile do not shift later pages. Turvo returns a
+	// string or a number; either is kept as text. Empty when not returned.
+	LastObjectKey string `json:"lastObjectKey,omitempty"`
+	// TotalRecords is the number of records matching the query across all
+	// pages, or 0 when the API does not report it.
+	TotalRecords int `json:"totalRecords,omitempty"`
+}
+
+// UnmarshalJSON accepts lastObjectKey as any JSON value.
+func (p *PageInfo) UnmarshalJSON(b []byte) error {
+	type plain PageInfo
+	var raw struct {
+		plain
+		LastObjectKey json.RawMessage `json:"lastObjectKey"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*p = PageInfo(raw.plain)
+	p.LastObjectKey = ""
+	var s string
+	switch {
+	case len(raw.LastObjectKey) == 0 || string(raw.LastObjectKey) == "null":
+	case json.Unmarshal(raw.LastObjectKey, &s) == nil:
+		p.LastObjectKey = s
+	default:
+		p.LastObjectKey = string(raw.LastObjectKey)
+	}
+	return nil
 }
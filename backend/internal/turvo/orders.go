@@ -0,0 +1,153 @@
+package turvo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// Turvo codes used when mapping orders.
+var (
+	externalIDCustomerRef = KeyValuePair{Key: "1400", Value: "Customer reference"}
+
+	itemUnits = map[string]KeyValuePair{
+		"pallets": {Key: "6000", Value: "Pallets"},
+		"cases":   {Key: "6001", Value: "Cases"},
+		"pieces":  {Key: "6002", Value: "Pieces"},
+	}
+)
+
+// ToTurvoOrder converts a Drumkit Order into a Turvo Order. Linked shipment
+// ids are sent as Order.Shipments.
+func (m *Mapper) ToTurvoOrder(o *domain.Order) (Order, error) {
+	if strings.TrimSpace(o.ExternalID) == "" {
+		return Order{}, fmt.Errorf("externalId is required")
+	}
+	custID := m.cfg.TurvoDefaultCustomerID
+	if o.Customer.TurvoID > 0 {
+		custID = o.Customer.TurvoID
+	}
+	if custID <= 0 {
+		return Order{}, fmt.Errorf("customer turvoId is required")
+	}
+	if len(o.Items) == 0 {
+		return Order{}, fmt.Errorf("at least one item is required")
+	}
+	startAt, endAt := shipmentWindow(o.Origin, o.Destination)
+
+	order := Order{
+		ID:          o.TurvoID,
+		StartDate:   startAt,
+		EndDate:     endAt,
+		Customer:    OrderCustomer{ID: custID, Name: o.Customer.Name},
+		Origin:      toOrderLocation(o.Origin),
+		Destination: toOrderLocation(o.Destination),
+		ExternalIDs: []ExternalID{{Type: externalIDCustomerRef, Value: o.ExternalID}},
+	}
+	if o.Origin.ReadyTime != nil {
+		order.PlannedPickup = o.Origin.ReadyTime
+	}
+	if o.Destination.MustDeliver != nil {
+		order.PlannedDelivery = o.Destination.MustDeliver
+	}
+	if code, ok := statusCode(o.Status); ok {
+		order.Status = &Status{Code: code}
+	}
+	for _, it := range o.Items {
+		item := OrderItem{
+			Name:     it.Name,
+			Notes:    it.Notes,
+			Quantity: ItemQuantity{Value: it.Quantity},
+		}
+		if u, ok := itemUnits[strings.ToLower(it.Units)]; ok {
+			item.Quantity.Units = &u
+		}
+		if it.Weight > 0 {
+			units := weightUnitsPounds
+			item.UnitGrossWeight = &ItemWeight{Value: it.Weight, Units: &units}
+		}
+		order.Items = append(order.Items, item)
+	}
+	if o.CarrierTurvoID > 0 {
+		order.Carrier = &OrderCarrier{ID: o.CarrierTurvoID, Name: o.CarrierName}
+	}
+	for _, id := range o.ShipmentIDs {
+		order.Shipments = append(order.Shipments, OrderShipment{ID: id})
+	}
+	return order, nil
+}
+
+// FromTurvoOrder converts a Turvo Order into a Drumkit Order.
+func (m *Mapper) FromTurvoOrder(o Order) *domain.Order {
+	out := &domain.Order{
+		TurvoID:     o.ID,
+		Customer:    domain.Party{TurvoID: o.Customer.ID, Name: o.Customer.Name},
+		Origin:      fromOrderLocation(o.Origin),
+		Destination: fromOrderLocation(o.Destination),
+	}
+	for _, ext := range o.ExternalIDs {
+		if ext.Type.Key == externalIDCustomerRef.Key || out.ExternalID == "" {
+			out.ExternalID = ext.Value
+		}
+	}
+	if o.Status != nil {
+		out.Status = o.Status.Code.Value
+	}
+	if o.PlannedPickup != nil {
+		out.Origin.ReadyTime = o.PlannedPickup
+	} else if !o.StartDate.IsZero() {
+		t := o.StartDate
+		out.Origin.ReadyTime = &t
+	}
+	if o.PlannedDelivery != nil {
+		out.Destination.MustDeliver = o.PlannedDelivery
+	} else if !o.EndDate.IsZero() {
+		t := o.EndDate
+		out.Destination.MustDeliver = &t
+	}
+	for _, it := range o.Items {
+		item := domain.OrderItem{Name: it.Name, Notes: it.Notes, Quantity: it.Quantity.Value}
+		if it.Quantity.Units != nil {
+			item.Units = strings.ToLower(it.Quantity.Units.Value)
+		}
+		if it.UnitGrossWeight != nil {
+			item.Weight = it.UnitGrossWeight.Value
+		}
+		out.Items = append(out.Items, item)
+	}
+	if o.Carrier != nil {
+		out.CarrierTurvoID, out.CarrierName = o.Carrier.ID, o.Carrier.Name
+	}
+	for _, s := range o.Shipments {
+		out.ShipmentIDs = append(out.ShipmentIDs, s.ID)
+	}
+	return out
+}
+
+func toOrderLocation(st domain.Stop) *OrderLocation {
+	return &OrderLocation{
+		Location: Location{ID: st.TurvoLocationID},
+		Name:     st.Name,
+		Address:  strings.TrimSpace(strings.Join([]string{st.AddressLine1, st.AddressLine2}, " ")),
+		City:     st.City,
+		State:    st.State,
+		Zip:      st.Zipcode,
+		Country:  st.Country,
+	}
+}
+
+func fromOrderLocation(l *OrderLocation) domain.Stop {
+	if l == nil {
+		return domain.Stop{}
+	}
+	return domain.Stop{
+		TurvoLocationID: l.Location.ID,
+		Name:            l.Name,
+		AddressLine1:    l.Address,
+		City:            l.City,
+		State:           l.State,
+		Zipcode:         l.Zip,
+		Country:         l.Country,
+	}
+}
@@ -0,0 +1,310 @@
+package turvo
+
+import (
+	"math"
+	"slices"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// Turvo cost line item codes used for Drumkit rates.
+var (
+	costCodeLinehaul = KeyValuePair{Key: "1600", Value: "Freight - flat"}
+	costCodeFuel     = KeyValuePair{Key: "1601", Value: "Fuel surcharge"}
+)
+
+// Turvo line item units; the linehaul unit records the rate type so it can
+// be restored when reading the shipment back.
+var (
+	rateUnits = map[string]KeyValuePair{
+		domain.RateTypeFlat:    {Key: "1700", Value: "Flat"},
+		domain.RateTypePerMile: {Key: "1701", Value: "Mile"},
+		domain.RateTypeHourly:  {Key: "1702", Value: "Hour"},
+	}
+	unitsPercent = KeyValuePair{Key: "1703", Value: "Percent"}
+)
+
+// rateQty returns the quantity a linehaul rate is multiplied by.
+func rateQty(rateType string, hours, miles float64) float64 {
+	switch rateType {
+	case domain.RateTypePerMile:
+		return miles
+	case domain.RateTypeHourly:
+		return hours
+	default:
+		return 1
+	}
+}
+
+// linehaulItem builds a linehaul line item, or nil when there is no rate.
+func linehaulItem(rateType string, rate, hours, miles float64) *CostLineItem {
+	if rate == 0 {
+		return nil
+	}
+	if _, ok := rateUnits[rateType]; !ok {
+		rateType = domain.RateTypeFlat
+	}
+	qty := rateQty(rateType, hours, miles)
+	units := rateUnits[rateType]
+	return &CostLineItem{Code: costCodeLinehaul, Qty: qty, Price: rate, Amount: round2(qty * rate), Units: &units, Billable: true}
+}
+
+// customerItems builds the receivable line items for a customer order, or
+// nil when there is no customer rate.
+func customerItems(r *domain.RateData, miles float64) []CostLineItem {
+	lh := linehaulItem(r.CustomerRateType, r.CustomerLhRateUsd, r.CustomerNumHours, miles)
+	if lh == nil {
+		return nil
+	}
+	items := []CostLineItem{*lh}
+	switch {
+	case r.FscPercent > 0:
+		units := unitsPercent
+		price := lh.Amount / 100
+		items = append(items, CostLineItem{Code: costCodeFuel, Qty: r.FscPercent, Price: price, Amount: round2(r.FscPercent * price), Units: &units, Billable: true})
+	case r.FscPerMile > 0:
+		units := rateUnits[domain.RateTypePerMile]
+		items = append(items, CostLineItem{Code: costCodeFuel, Qty: miles, Price: r.FscPerMile, Amount: round2(miles * r.FscPerMile), Units: &units, Billable: true})
+	}
+	return items
+}
+
+// carrierItems builds the payable line items for a carrier order, or nil
+// when there is no carrier rate.
+func carrierItems(r *domain.RateData, miles float64) []CostLineItem {
+	lh := linehaulItem(r.CarrierRateType, r.CarrierLhRateUsd, r.CarrierNumHours, miles)
+	if lh == nil {
+		return nil
+	}
+	return []CostLineItem{*lh}
+}
+
+// mergeCosts replaces the line items with the owned codes in existing by
+// items and keeps every other line item, such as accessorials entered in
+// Turvo. The total is recomputed over all of them.
+func mergeCosts(existing *Costs, items []CostLineItem, owned ...KeyValuePair) *Costs {
+	merged := &Costs{LineItem: append([]CostLineItem{}, items...)}
+	if existing != nil {
+		for _, li := range existing.LineItem {
+			if !slices.ContainsFunc(owned, func(c KeyValuePair) bool { return c.Key == li.Code.Key }) {
+				merged.LineItem = append(merged.LineItem, li)
+			}
+		}
+	}
+	for _, li := range merged.LineItem {
+		merged.TotalAmount += li.Amount
+	}
+	merged.TotalAmount = round2(merged.TotalAmount)
+	return merged
+}
+
+// ComputeProfit sets NetProfitUsd and ProfitPercent from the receivable and
+// payable totals. Profit percent is relative to the customer total and 0
+// when there is none.
+func ComputeProfit(r *domain.RateData, receivable, payable float64) {
+	r.NetProfitUsd = round2(receivable - payable)
+	r.ProfitPercent = 0
+	if receivable != 0 {
+		r.ProfitPercent = round2(r.NetProfitUsd / receivable * 100)
+	}
+}
+
+// loadMiles returns the route miles used for per-mile rates.
+func loadMiles(load *domain.Load) float64 {
+	if load.Specifications != nil && load.Specifications.RouteMiles > 0 {
+		return load.Specifications.RouteMiles
+	}
+	if load.CustomerTotalMiles != nil {
+		return *load.CustomerTotalMiles
+	}
+	return 0
+}
+
+// applyRates merges customer and carrier rates into the shipment's cost line
+// items and recomputes the margin from the resulting totals. Only the
+// linehaul and fuel line items are Drumkit's; others are kept. Carrier costs
+// need a carrier order; without one only the max pay is kept and nothing is
+// payable. Profit is always computed here, never taken from the caller, and
+// written back to load.RateData.
+func (m *Mapper) applyRates(shipment *Shipment, load *domain.Load) {
+	r := load.RateData
+	if r == nil {
+		return
+	}
+	miles := loadMiles(load)
+	if items := customerItems(r, miles); items != nil {
+		orders := make([]CustomerOrder, len(shipment.CustomerOrder))
+		copy(orders, shipment.CustomerOrder)
+		if len(orders) == 0 {
+			orders = append(orders, CustomerOrder{CustomerOrderSourceID: 1})
+		}
+		orders[0].Costs = mergeCosts(orders[0].Costs, items, costCodeLinehaul, costCodeFuel)
+		shipment.CustomerOrder = orders
+	}
+	carrier := activeCarrierOrder(shipment.CarrierOrder)
+	if items := carrierItems(r, miles); items != nil && carrier >= 0 {
+		orders := make([]CarrierOrder, len(shipment.CarrierOrder))
+		copy(orders, shipment.CarrierOrder)
+		orders[carrier].Costs = mergeCosts(orders[carrier].Costs, items, costCodeLinehaul)
+		shipment.CarrierOrder = orders
+	}
+
+	var receivable, payable float64
+	if len(shipment.CustomerOrder) > 0 && shipment.CustomerOrder[0].Costs != nil {
+		receivable = shipment.CustomerOrder[0].Costs.TotalAmount
+	}
+	if carrier >= 0 && shipment.CarrierOrder[carrier].Costs != nil {
+		payable = shipment.CarrierOrder[carrier].Costs.TotalAmount
+	}
+	ComputeProfit(r, receivable, payable)
+	margin := Margin{}
+	if shipment.Margin != nil {
+		margin = *shipment.Margin
+	}
+	margin.MaxPay = r.CarrierMaxRate
+	margin.TotalReceivableAmount = receivable
+	margin.TotalPayableAmount = payable
+	margin.Amount = r.NetProfitUsd
+	margin.Value = r.ProfitPercent
+	shipment.Margin = &margin
+}
+
+// AssignCarrier replaces the active carrier order on a copy of existing with
+// one for carrier and prices it. The carrier linehaul and max pay come from
+// rates when set there, or else from the shipment; customer costs are kept
+// as they are. The margin is recomputed from the resulting totals.
+func (m *Mapper) AssignCarrier(existing Shipment, carrier *domain.Carrier, rates *domain.RateData) (Shipment, error) {
+	co, err := m.ToTurvoCarrierOrder(carrier)
+	if err != nil {
+		return Shipment{}, err
+	}
+	updated := existing
+	updated.CarrierOrder = AssignCarrierOrder(existing.CarrierOrder, co)
+
+	r := m.fromTurvoRates(existing)
+	if r == nil && rates == nil {
+		return updated, nil
+	}
+	if r == nil {
+		r = &domain.RateData{}
+	}
+	if rates != nil && rates.CarrierLhRateUsd != 0 {
+		r.CarrierRateType, r.CarrierNumHours, r.CarrierLhRateUsd = rates.CarrierRateType, rates.CarrierNumHours, rates.CarrierLhRateUsd
+	}
+	if rates != nil && rates.CarrierMaxRate != 0 {
+		r.CarrierMaxRate = rates.CarrierMaxRate
+	}
+	// without customer rates applyRates leaves the customer order alone
+	r.CustomerLhRateUsd, r.FscPercent, r.FscPerMile = 0, 0, 0
+	miles := shipmentMiles(existing)
+	m.applyRates(&updated, &domain.Load{CustomerTotalMiles: &miles, RateData: r})
+	return updated, nil
+}
+
+// shipmentMiles returns the miles s was priced with: the quantity of a
+// per-mile linehaul, or else the customer order's total miles.
+func shipmentMiles(s Shipment) float64 {
+	costs := []*Costs{}
+	if len(s.CustomerOrder) > 0 {
+		costs = append(costs, s.CustomerOrder[0].Costs)
+	}
+	if i := activeCarrierOrder(s.CarrierOrder); i >= 0 {
+		costs = append(costs, s.CarrierOrder[i].Costs)
+	}
+	for _, c := range costs {
+		if c == nil {
+			continue
+		}
+		for _, li := range c.LineItem {
+			if li.Code.Key == costCodeLinehaul.Key && rateTypeFromUnits(li.Units) == domain.RateTypePerMile {
+				return li.Qty
+			}
+		}
+	}
+	if len(s.CustomerOrder) > 0 {
+		return s.CustomerOrder[0].TotalMiles
+	}
+	return 0
+}
+
+// activeCarrierOrder returns the index of the last carrier order that is not
+// deleted, or -1.
+func activeCarrierOrder(orders []CarrierOrder) int {
+	for i := len(orders) - 1; i >= 0; i-- {
+		if !orders[i].Deleted {
+			return i
+		}
+	}
+	return -1
+}
+
+// fromTurvoRates rebuilds RateData from the cost line items and margin. It
+// returns nil when the shipment carries no pricing.
+func (m *Mapper) fromTurvoRates(s Shipment) *domain.RateData {
+	r := &domain.RateData{}
+	found := false
+	var receivable, payable float64
+	if len(s.CustomerOrder) > 0 && s.CustomerOrder[0].Costs != nil {
+		costs := s.CustomerOrder[0].Costs
+		found = true
+		receivable = costs.TotalAmount
+		for _, li := range costs.LineItem {
+			switch li.Code.Key {
+			case costCodeLinehaul.Key:
+				r.CustomerRateType, r.CustomerLhRateUsd = rateTypeFromUnits(li.Units), li.Price
+				if r.CustomerRateType == domain.RateTypeHourly {
+					r.CustomerNumHours = li.Qty
+				}
+			case costCodeFuel.Key:
+				if li.Units != nil && li.Units.Key == unitsPercent.Key {
+					r.FscPercent = li.Qty
+				} else {
+					r.FscPerMile = li.Price
+				}
+			}
+		}
+	}
+	if i := activeCarrierOrder(s.CarrierOrder); i >= 0 && s.CarrierOrder[i].Costs != nil {
+		co := s.CarrierOrder[i]
+		found = true
+		payable = co.Costs.TotalAmount
+		for _, li := range co.Costs.LineItem {
+			if li.Code.Key == costCodeLinehaul.Key {
+				r.CarrierRateType, r.CarrierLhRateUsd = rateTypeFromUnits(li.Units), li.Price
+				if r.CarrierRateType == domain.RateTypeHourly {
+					r.CarrierNumHours = li.Qty
+				}
+			}
+		}
+	}
+	if s.Margin != nil {
+		found = found || s.Margin.MaxPay != 0 || s.Margin.Amount != 0
+		r.CarrierMaxRate = s.Margin.MaxPay
+		if receivable == 0 {
+			receivable = s.Margin.TotalReceivableAmount
+		}
+		if payable == 0 {
+			payable = s.Margin.TotalPayableAmount
+		}
+	}
+	if !found {
+		return nil
+	}
+	ComputeProfit(r, receivable, payable)
+	return r
+}
+
+func rateTypeFromUnits(units *KeyValuePair) string {
+	if units != nil {
+		for t, u := range rateUnits {
+			if u.Key == units.Key {
+				return t
+			}
+		}
+	}
+	return domain.RateTypeFlat
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,181 @@
+package turvo_test
+
+import (
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+func ratedLoad(r domain.RateData) *domain.Load {
+	miles := 500.0
+	return &domain.Load{
+		ExternalTMSLoadID:  "RATE-1",
+		Pickup:             domain.Stop{City: "Chicago", State: "IL"},
+		Consignee:          domain.Stop{City: "Dallas", State: "TX"},
+		CustomerTotalMiles: &miles,
+		RateData:           &r,
+	}
+}
+
+func lineItems(c *turvo.Costs) map[string]float64 {
+	items := make(map[string]float64)
+	if c != nil {
+		for _, li := range c.LineItem {
+			items[li.Code.Key] = li.Amount
+		}
+	}
+	return items
+}
+
+func TestRatesRoundTrip(t *testing.T) {
+	load := ratedLoad(domain.RateData{
+		CustomerRateType: domain.RateTypePerMile, CustomerLhRateUsd: 2.5, FscPercent: 10,
+		CarrierRateType: domain.RateTypeFlat, CarrierLhRateUsd: 1000, CarrierMaxRate: 1100,
+	})
+	load.Carrier = &domain.Carrier{TurvoID: 77, Name: "Fast Freight"}
+	s, back := roundTrip(t, load)
+
+	if got := lineItems(s.CustomerOrder[0].Costs); got["1600"] != 1250 || got["1601"] != 125 || s.CustomerOrder[0].Costs.TotalAmount != 1375 {
+		t.Errorf("customer costs = %+v", s.CustomerOrder[0].Costs)
+	}
+	if got := s.CarrierOrder[0].Costs; got == nil || got.TotalAmount != 1000 {
+		t.Errorf("carrier costs = %+v", got)
+	}
+	if m := s.Margin; m.Amount != 375 || m.Value != 27.27 || m.MaxPay != 1100 || m.TotalReceivableAmount != 1375 || m.TotalPayableAmount != 1000 {
+		t.Errorf("margin = %+v", m)
+	}
+	r := back.RateData
+	if r == nil || r.CustomerRateType != domain.RateTypePerMile || r.CustomerLhRateUsd != 2.5 || r.FscPercent != 10 ||
+		r.CarrierLhRateUsd != 1000 || r.CarrierMaxRate != 1100 || r.NetProfitUsd != 375 || r.ProfitPercent != 27.27 {
+		t.Errorf("rate data = %+v", r)
+	}
+}
+
+func TestRatesIgnoreClientProfit(t *testing.T) {
+	// no carrier order: the carrier rate is not payable yet, and the
+	// client's own profit figures are replaced
+	load := ratedLoad(domain.RateData{
+		CustomerLhRateUsd: 2000, CarrierLhRateUsd: 1500, CarrierMaxRate: 1600,
+		NetProfitUsd: 999, ProfitPercent: 99,
+	})
+	s, _ := roundTrip(t, load)
+	if len(s.CarrierOrder) != 0 {
+		t.Fatalf("carrier orders = %+v", s.CarrierOrder)
+	}
+	if m := s.Margin; m.Amount != 2000 || m.Value != 100 || m.TotalPayableAmount != 0 || m.MaxPay != 1600 {
+		t.Errorf("margin = %+v", m)
+	}
+	if load.RateData.NetProfitUsd != 2000 || load.RateData.ProfitPercent != 100 {
+		t.Errorf("rate data = %+v", load.RateData)
+	}
+
+	// nothing priced yet: profit is zero, not the client's numbers
+	load = ratedLoad(domain.RateData{CarrierMaxRate: 1600, NetProfitUsd: 999, ProfitPercent: 99})
+	s, _ = roundTrip(t, load)
+	if m := s.Margin; m.Amount != 0 || m.Value != 0 {
+		t.Errorf("unpriced margin = %+v", m)
+	}
+}
+
+func TestRatesUpdateKeepsOtherLineItems(t *testing.T) {
+	m := turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500})
+	detention := turvo.CostLineItem{Code: turvo.KeyValuePair{Key: "1620", Value: "Detention"}, Qty: 1, Price: 150, Amount: 150}
+	lumper := turvo.CostLineItem{Code: turvo.KeyValuePair{Key: "1630", Value: "Lumper"}, Qty: 1, Price: 80, Amount: 80}
+	oldFuel := turvo.CostLineItem{Code: turvo.KeyValuePair{Key: "1601", Value: "Fuel surcharge"}, Amount: 90}
+	existing := turvo.Shipment{
+		ID: 9,
+		CustomerOrder: []turvo.CustomerOrder{{ID: 1, Costs: &turvo.Costs{TotalAmount: 1240, LineItem: []turvo.CostLineItem{
+			{Code: turvo.KeyValuePair{Key: "1600"}, Qty: 1, Price: 1000, Amount: 1000}, oldFuel, detention,
+		}}}},
+		CarrierOrder: []turvo.CarrierOrder{{ID: 2, Costs: &turvo.Costs{TotalAmount: 880, LineItem: []turvo.CostLineItem{
+			{Code: turvo.KeyValuePair{Key: "1600"}, Qty: 1, Price: 800, Amount: 800}, lumper,
+		}}}},
+	}
+	load := ratedLoad(domain.RateData{CustomerLhRateUsd: 1200, CarrierLhRateUsd: 900})
+	s, err := m.ApplyLoadUpdate(existing, load, map[string]bool{"rateData": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fuel surcharge is Drumkit's and was dropped from the rates, so it
+	// goes; detention and lumper were entered in Turvo and stay
+	cust := lineItems(s.CustomerOrder[0].Costs)
+	if len(cust) != 2 || cust["1600"] != 1200 || cust["1620"] != 150 || s.CustomerOrder[0].Costs.TotalAmount != 1350 {
+		t.Errorf("customer costs = %+v", s.CustomerOrder[0].Costs)
+	}
+	carr := lineItems(s.CarrierOrder[0].Costs)
+	if len(carr) != 2 || carr["1600"] != 900 || carr["1630"] != 80 || s.CarrierOrder[0].Costs.TotalAmount != 980 {
+		t.Errorf("carrier costs = %+v", s.CarrierOrder[0].Costs)
+	}
+	if mg := s.Margin; mg.TotalReceivableAmount != 1350 || mg.TotalPayableAmount != 980 || mg.Amount != 370 || mg.Value != 27.41 {
+		t.Errorf("margin = %+v", mg)
+	}
+	// the existing shipment is not modified
+	if existing.CustomerOrder[0].Costs.TotalAmount != 1240 || len(existing.CarrierOrder[0].Costs.LineItem) != 2 {
+		t.Errorf("existing changed: %+v", existing)
+	}
+}
+
+func TestAssignCarrierReprices(t *testing.T) {
+	m := turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500})
+	mile := turvo.KeyValuePair{Key: "1701", Value: "Mile"}
+	lumper := turvo.CostLineItem{Code: turvo.KeyValuePair{Key: "1630", Value: "Lumper"}, Qty: 1, Price: 80, Amount: 80}
+	existing := turvo.Shipment{
+		ID: 9,
+		CustomerOrder: []turvo.CustomerOrder{{ID: 1, Costs: &turvo.Costs{TotalAmount: 1250, LineItem: []turvo.CostLineItem{
+			{Code: turvo.KeyValuePair{Key: "1600"}, Qty: 500, Price: 2.5, Amount: 1250, Units: &mile},
+		}}}},
+		CarrierOrder: []turvo.CarrierOrder{{ID: 2, Costs: &turvo.Costs{TotalAmount: 880, LineItem: []turvo.CostLineItem{
+			{Code: turvo.KeyValuePair{Key: "1600"}, Qty: 1, Price: 800, Amount: 800}, lumper,
+		}}}},
+		Margin: &turvo.Margin{MaxPay: 900},
+	}
+
+	// the replaced order's linehaul moves over; its lumper was that carrier's
+	s, err := m.AssignCarrier(existing, &domain.Carrier{TurvoID: 88}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.CarrierOrder) != 2 || !s.CarrierOrder[0].Deleted {
+		t.Fatalf("carrier orders = %+v", s.CarrierOrder)
+	}
+	if c := s.CarrierOrder[1].Costs; c == nil || c.TotalAmount != 800 || len(c.LineItem) != 1 {
+		t.Errorf("new carrier costs = %+v", c)
+	}
+	if s.CustomerOrder[0].Costs != existing.CustomerOrder[0].Costs {
+		t.Errorf("customer costs rewritten: %+v", s.CustomerOrder[0].Costs)
+	}
+	if mg := s.Margin; mg.TotalReceivableAmount != 1250 || mg.TotalPayableAmount != 800 || mg.Amount != 450 || mg.Value != 36 || mg.MaxPay != 900 {
+		t.Errorf("margin = %+v", mg)
+	}
+
+	// a per-mile rate for the new carrier uses the shipment's miles
+	s, err = m.AssignCarrier(existing, &domain.Carrier{TurvoID: 88}, &domain.RateData{CarrierRateType: domain.RateTypePerMile, CarrierLhRateUsd: 1.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.CarrierOrder[1].Costs; c == nil || c.TotalAmount != 900 {
+		t.Errorf("per-mile carrier costs = %+v", c)
+	}
+	if mg := s.Margin; mg.TotalPayableAmount != 900 || mg.Amount != 350 || mg.Value != 28 {
+		t.Errorf("per-mile margin = %+v", mg)
+	}
+}
+
+func TestComputeProfit(t *testing.T) {
+	r := domain.RateData{NetProfitUsd: 5, ProfitPercent: 50}
+	turvo.ComputeProfit(&r, 0, 0)
+	if r.NetProfitUsd != 0 || r.ProfitPercent != 0 {
+		t.Errorf("zero totals: %+v", r)
+	}
+	turvo.ComputeProfit(&r, 0, 250)
+	if r.NetProfitUsd != -250 || r.ProfitPercent != 0 {
+		t.Errorf("no receivable: %+v", r)
+	}
+	turvo.ComputeProfit(&r, 1000, 1333.33)
+	if r.NetProfitUsd != -333.33 || r.ProfitPercent != -33.33 {
+		t.Errorf("loss: %+v", r)
+	}
+}
@@ -0,0 +1,215 @@
+package turvo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the Client retries Turvo data calls.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// BaseDelay is the first backoff delay; it doubles on each retry.
+	BaseDelay time.Duration
+	// MaxDelay caps a single backoff delay.
+	MaxDelay time.Duration
+	// MaxRetryAfter is the longest Retry-After the client will wait out; longer
+	// waits are returned to the caller as RateLimitedError.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy is used by NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:   4,
+	BaseDelay:     250 * time.Millisecond,
+	MaxDelay:      5 * time.Second,
+	MaxRetryAfter: 30 * time.Second,
+}
+
+type retryCheckKey struct{}
+
+// retryCheck runs before a create is repeated and reports whether it may
+// be; a nil check always allows it.
+type retryCheck func(context.Context) bool
+
+// WithSafeRetry marks creates made with ctx as safe to repeat, allowing the
+// client to retry them on 5xx and network errors. Use it only when a
+// repeated create cannot duplicate: CreateShipment looks up a shipment's
+// customId before each retry, and other creates need a key the caller
+// dedupes on.
+func WithSafeRetry(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(retryCheckKey{}).(retryCheck); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, retryCheckKey{}, retryCheck(nil))
+}
+
+// withRetryCheck narrows WithSafeRetry on ctx to repeats check allows. It
+// returns ctx unchanged when ctx is not marked safe.
+func withRetryCheck(ctx context.Context, check retryCheck) context.Context {
+	if _, ok := ctx.Value(retryCheckKey{}).(retryCheck); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, retryCheckKey{}, check)
+}
+
+// mayRepeat reports whether a request that failed with a 5xx or network
+// error, and so may have been applied, can be sent again.
+func mayRepeat(ctx context.Context, method string) bool {
+	if method != http.MethodPost {
+		return true
+	}
+	check, ok := ctx.Value(retryCheckKey{}).(retryCheck)
+	return ok && (check == nil || check(ctx))
+}
+
+// SetRetryPolicy replaces the client's retry policy.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	c.retry = p
+}
+
+// do executes a Turvo data request with the client's retry policy and returns
+// the final response (body already read and closed) with its body bytes.
+//
+//   - 401 refreshes the token and retries exactly once.
+//   - 429 waits for Retry-After (up to MaxRetryAfter) and retries.
+//   - 5xx and network errors back off exponentially with jitter, but only for
+//     idempotent methods or when ctx is marked with WithSafeRetry. Turvo has
+//     no idempotency key for creates, so an unmarked POST that may have been
+//     applied is never repeated.
+//
+// Non-2xx responses that are not retried are returned without error so the
+// caller can build its own error message.
+func (c *Client) do(ctx context.Context, method, path string, payload []byte) (*http.Response, []byte, error) {
+	refreshed := false
+	var lastErr error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+		req, err := c.newRequest(ctx, method, path, body)
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt == c.retry.MaxAttempts-1 || !mayRepeat(ctx, method) {
+				return nil, nil, err
+			}
+			lastErr = err
+			slog.WarnContext(ctx, "Turvo request failed; retrying", "method", method, "path", path, "attempt", attempt+1, "error", err)
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		bodyBytes, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, nil, readErr
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && !refreshed && c.usesOAuth():
+			refreshed = true
+			c.invalidateToken()
+			if err := c.fetchToken(ctx, true); err != nil {
+				// the refresh token may itself have expired; fall back to a password grant
+				if err := c.fetchToken(ctx, false); err != nil {
+					return nil, nil, err
+				}
+			}
+			// the failed request was rejected before processing; retry at once
+			attempt--
+			continue
+		case resp.StatusCode == http.StatusTooManyRequests:
+			wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if wait <= 0 {
+				wait = c.backoff(attempt)
+			}
+			if wait > c.retry.MaxRetryAfter || attempt == c.retry.MaxAttempts-1 {
+				return nil, nil, RateLimitedError{RetryAfter: wait, Message: string(bodyBytes)}
+			}
+			slog.WarnContext(ctx, "Turvo rate limited; retrying", "method", method, "path", path, "wait", wait)
+			if err := c.sleep(ctx, wait); err != nil {
+				return nil, nil, err
+			}
+			continue
+		case resp.StatusCode >= 500 && attempt < c.retry.MaxAttempts-1 && mayRepeat(ctx, method):
+			slog.WarnContext(ctx, "Turvo server error; retrying", "method", method, "path", path, "status", resp.Status, "attempt", attempt+1)
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			// still rejected after a fresh token, or api_key mode
+			c.authRejected()
+		}
+		return resp, bodyBytes, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("turvo request failed")
+	}
+	return nil, nil, lastErr
+}
+
+// backoff returns a full-jitter exponential delay for the given attempt.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retry.BaseDelay << attempt
+	if d <= 0 || d > c.retry.MaxDelay {
+		d = c.retry.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// sleep waits for d or until ctx is done.
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// invalidateToken drops the cached access token so the next fetch renews it.
+func (c *Client) invalidateToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	c.tokenExp = time.Time{}
+}
+
+// parseRetryAfter accepts delay-seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package turvo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// Turvo stop type codes used in globalRoute.
+var stopTypes = map[string]KeyValuePair{
+	domain.StopTypePickup:   {Key: "1500", Value: "Pickup"},
+	domain.StopTypeDelivery: {Key: "1501", Value: "Delivery"},
+}
+
+// routeEndpoints validates an ordered stop list and returns the first pickup
+// and the last delivery, which define the shipment lane and date window.
+func routeEndpoints(stops []domain.Stop) (domain.Stop, domain.Stop, error) {
+	var first, last *domain.Stop
+	for i := range stops {
+		st := &stops[i]
+		if _, ok := stopTypes[st.StopType]; !ok {
+			return domain.Stop{}, domain.Stop{}, fmt.Errorf("stop %d: unknown stop type %q", i+1, st.StopType)
+		}
+		if st.StopType == domain.StopTypePickup && first == nil {
+			first = st
+		}
+		if st.StopType == domain.StopTypeDelivery {
+			last = st
+		}
+	}
+	if first == nil || last == nil {
+		return domain.Stop{}, domain.Stop{}, fmt.Errorf("stops must include at least one pickup and one delivery")
+	}
+	if stops[0].StopType != domain.StopTypePickup {
+		return domain.Stop{}, domain.Stop{}, fmt.Errorf("first stop must be a pickup")
+	}
+	return *first, *last, nil
+}
+
+// stopTime returns the appointment time of a stop, falling back to the
+// pickup ready time or delivery deadline.
+func stopTime(st domain.Stop) *time.Time {
+	switch {
+	case st.ApptTime != nil && !st.ApptTime.IsZero():
+		return st.ApptTime
+	case st.StopType == domain.StopTypePickup && st.ReadyTime != nil && !st.ReadyTime.IsZero():
+		return st.ReadyTime
+	case st.StopType == domain.StopTypeDelivery && st.MustDeliver != nil && !st.MustDeliver.IsZero():
+		return st.MustDeliver
+	}
+	return nil
+}
+
+// toGlobalRoute converts ordered domain stops into Turvo globalRoute entries.
+// Stops without their own time fall back to the shipment start (pickups) or
+// end (deliveries) so Turvo always has a date.
+func (m *Mapper) toGlobalRoute(stops []domain.Stop, start, end time.Time) []GlobalRoute {
+	route := make([]GlobalRoute, 0, len(stops))
+	for i, st := range stops {
+		loc := st.Location()
+		tz := loc.String()
+		appt := Appointment{Date: start.In(loc), Timezone: tz}
+		if st.StopType == domain.StopTypeDelivery {
+			appt.Date = end.In(loc)
+		}
+		if t := stopTime(st); t != nil {
+			appt.Date = t.In(loc)
+			appt.HasTime = true
+		}
+		route = append(route, GlobalRoute{
+			Name:          st.Name,
+			AppointmentNo: st.RefNumber,
+			StopType:      stopTypes[st.StopType],
+			Timezone:      tz,
+			Location:      Location{ID: st.TurvoLocationID},
+			Sequence:      i + 1,
+			Appointment:   appt,
+			PoNumbers:     st.PoNumbers,
+			Notes:         st.Notes,
+		})
+	}
+	return route
+}
+
+// inZone expresses t in the named IANA zone, or leaves it unchanged when the
+// zone is empty or unknown.
+func inZone(t time.Time, tz string) time.Time {
+	if tz == "" {
+		return t
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// fromGlobalRoute converts Turvo globalRoute entries into domain stops ordered
+// by sequence.
+func (m *Mapper) fromGlobalRoute(route []GlobalRoute) []domain.Stop {
+	sorted := make([]GlobalRoute, len(route))
+	copy(sorted, route)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Sequence < sorted[j].Sequence })
+	stops := make([]domain.Stop, 0, len(sorted))
+	for _, gr := range sorted {
+		st := domain.Stop{
+			Name:            gr.Name,
+			RefNumber:       gr.AppointmentNo,
+			Timezone:        gr.Timezone,
+			TurvoLocationID: gr.Location.ID,
+			PoNumbers:       gr.PoNumbers,
+			Notes:           gr.Notes,
+		}
+		for t, kv := range stopTypes {
+			if kv.Key == gr.StopType.Key || strings.EqualFold(kv.Value, gr.StopType.Value) {
+				st.StopType = t
+				break
+			}
+		}
+		if st.Timezone == "" {
+			st.Timezone = gr.Appointment.Timezone
+		}
+		if !gr.Appointment.Date.IsZero() {
+			appt := inZone(gr.Appointment.Date, st.Timezone)
+			st.ApptTime = &appt
+			switch st.StopType {
+			case domain.StopTypePickup:
+				st.ReadyTime = &appt
+			case domain.StopTypeDelivery:
+				st.MustDeliver = &appt
+			}
+		}
+		stops = append(stops, st)
+	}
+	return stops
+}
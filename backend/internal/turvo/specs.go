@@ -0,0 +1,250 @@
+package turvo
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+)
+
+// Mapping tables between Drumkit Specifications and Turvo equipment,
+// services and flex attributes. Keep every code used for specifications here.
+var (
+	equipmentVan     = KeyValuePair{Key: "1200", Value: "Van"}
+	equipmentReefer  = KeyValuePair{Key: "1201", Value: "Reefer"}
+	equipmentFlatbed = KeyValuePair{Key: "1202", Value: "Flatbed"}
+
+	tempUnitsFahrenheit = KeyValuePair{Key: "1510", Value: "°F"}
+	weightUnitsPounds   = KeyValuePair{Key: "1520", Value: "lb"}
+)
+
+// specServices maps boolean Specifications flags to Turvo shipment services.
+var specServices = []struct {
+	code KeyValuePair
+	flag func(*domain.Specifications) *bool
+}{
+	{KeyValuePair{Key: "21000", Value: "Liftgate pickup"}, func(s *domain.Specifications) *bool { return &s.LiftgatePickup }},
+	{KeyValuePair{Key: "21001", Value: "Liftgate delivery"}, func(s *domain.Specifications) *bool { return &s.LiftgateDelivery }},
+	{KeyValuePair{Key: "21002", Value: "Inside pickup"}, func(s *domain.Specifications) *bool { return &s.InsidePickup }},
+	{KeyValuePair{Key: "21003", Value: "Inside delivery"}, func(s *domain.Specifications) *bool { return &s.InsideDelivery }},
+	{KeyValuePair{Key: "21004", Value: "Tarps"}, func(s *domain.Specifications) *bool { return &s.Tarps }},
+	{KeyValuePair{Key: "21005", Value: "Oversized"}, func(s *domain.Specifications) *bool { return &s.Oversized }},
+	{KeyValuePair{Key: "21006", Value: "Hazmat"}, func(s *domain.Specifications) *bool { return &s.Hazmat }},
+	{KeyValuePair{Key: "21007", Value: "Straps"}, func(s *domain.Specifications) *bool { return &s.Straps }},
+	{KeyValuePair{Key: "21008", Value: "Permits"}, func(s *domain.Specifications) *bool { return &s.Permits }},
+	{KeyValuePair{Key: "21009", Value: "Escorts"}, func(s *domain.Specifications) *bool { return &s.Escorts }},
+	{KeyValuePair{Key: "21010", Value: "Seal"}, func(s *domain.Specifications) *bool { return &s.Seal }},
+	{KeyValuePair{Key: "21011", Value: "Customs bonded"}, func(s *domain.Specifications) *bool { return &s.CustomBonded }},
+	{KeyValuePair{Key: "21012", Value: "Labor"}, func(s *domain.Specifications) *bool { return &s.Labor }},
+}
+
+// specAttributes maps numeric and text Specifications values to Turvo flex
+// attributes, which have no dedicated shipment field.
+var specAttributes = []struct {
+	name string
+	get  func(*domain.Specifications) string
+	set  func(*domain.Specifications, string)
+}{
+	{"inPalletCount", func(s *domain.Specifications) string { return itoaNonZero(s.InPalletCount) }, func(s *domain.Specifications, v string) { s.InPalletCount = atoiOrZero(v) }},
+	{"outPalletCount", func(s *domain.Specifications) string { return itoaNonZero(s.OutPalletCount) }, func(s *domain.Specifications, v string) { s.OutPalletCount = atoiOrZero(v) }},
+	{"numCommodities", func(s *domain.Specifications) string { return itoaNonZero(s.NumCommodities) }, func(s *domain.Specifications, v string) { s.NumCommodities = atoiOrZero(v) }},
+	{"billableWeight", func(s *domain.Specifications) string { return ftoaNonZero(s.BillableWeight) }, func(s *domain.Specifications, v string) { s.BillableWeight, _ = strconv.ParseFloat(v, 64) }},
+	{"poNums", func(s *domain.Specifications) string { return s.PoNums }, func(s *domain.Specifications, v string) { s.PoNums = v }},
+	{"operator", func(s *domain.Specifications) string { return s.Operator }, func(s *domain.Specifications, v string) { s.Operator = v }},
+}
+
+// specAttributeType is the flex attribute type used for specification values.
+var specAttributeType = KeyValuePair{Key: "2600", Value: "Text"}
+
+// Flex attributes holding a reefer load's temperature range. The equipment
+// only has room for one set point, the minimum.
+const (
+	attrMinTemp = "minTempFahrenheit"
+	attrMaxTemp = "maxTempFahrenheit"
+)
+
+// equipmentTypes are the equipment types Drumkit picks. Equipment entries
+// of other types are left as they are in Turvo.
+var equipmentTypes = []KeyValuePair{equipmentVan, equipmentReefer, equipmentFlatbed}
+
+// equipmentType picks the Turvo equipment type: reefer for reefer or
+// temperature loads, flatbed for tarps or oversized, then an explicit entry
+// in load.Equipment, and van otherwise. The specifications come first
+// because an update starts from the load read back from Turvo, whose
+// equipment names the type the load had before.
+func equipmentType(load *domain.Load) KeyValuePair {
+	spec := load.Specifications
+	switch {
+	case spec.Reefer || spec.MinTempFahrenheit != 0 || spec.MaxTempFahrenheit != 0:
+		return equipmentReefer
+	case spec.Tarps || spec.Oversized:
+		return equipmentFlatbed
+	}
+	for _, name := range load.Equipment {
+		for _, eq := range equipmentTypes {
+			if strings.EqualFold(name, eq.Value) {
+				return eq
+			}
+		}
+	}
+	return equipmentVan
+}
+
+// applySpecifications merges equipment, services and flex attributes for
+// load.Specifications into shipment. Only the entries Drumkit maps are
+// replaced; services, equipment and attributes added in Turvo are kept.
+// Reefer loads carry the minimum as the equipment set point and the range
+// in flex attributes.
+func (m *Mapper) applySpecifications(shipment *Shipment, load *domain.Load) {
+	spec := load.Specifications
+	if spec == nil {
+		return
+	}
+	shipment.Equipment = mergeEquipment(shipment.Equipment, spec, equipmentType(load))
+
+	var services []KeyValuePair
+	for _, sv := range specServices {
+		if *sv.flag(spec) {
+			services = append(services, sv.code)
+		}
+	}
+	for _, kv := range shipment.Services {
+		if !isSpecService(kv.Key) {
+			services = append(services, kv)
+		}
+	}
+	shipment.Services = services
+
+	attrs := make([]FlexAttribute, 0, len(shipment.FlexAttributes))
+	for _, fa := range shipment.FlexAttributes {
+		if !isSpecAttribute(fa.Name) && fa.Name != attrMinTemp && fa.Name != attrMaxTemp {
+			attrs = append(attrs, fa)
+		}
+	}
+	for _, sa := range specAttributes {
+		if v := sa.get(spec); v != "" {
+			attrs = append(attrs, FlexAttribute{Type: specAttributeType, Name: sa.name, Value: v})
+		}
+	}
+	if equipmentType(load) == equipmentReefer {
+		attrs = append(attrs,
+			FlexAttribute{Type: specAttributeType, Name: attrMinTemp, Value: strconv.FormatFloat(spec.MinTempFahrenheit, 'f', -1, 64)},
+			FlexAttribute{Type: specAttributeType, Name: attrMaxTemp, Value: strconv.FormatFloat(spec.MaxTempFahrenheit, 'f', -1, 64)})
+	}
+	shipment.FlexAttributes = attrs
+}
+
+// mergeEquipment puts the equipment for spec first and keeps entries of
+// types Drumkit does not pick. The new entry starts from the replaced one,
+// preferring the same type, so fields Drumkit does not set survive.
+func mergeEquipment(existing []Equipment, spec *domain.Specifications, typ KeyValuePair) []Equipment {
+	var (
+		eq     Equipment
+		others []Equipment
+		found  bool
+	)
+	for _, e := range existing {
+		if !isEquipmentType(e.Type.Key) {
+			others = append(others, e)
+			continue
+		}
+		if !found || e.Type.Key == typ.Key && eq.Type.Key != typ.Key {
+			eq, found = e, true
+		}
+	}
+	eq.Type = typ
+	eq.Weight, eq.WeightUnits = nil, nil
+	if spec.TotalWeight > 0 {
+		w := spec.TotalWeight
+		units := weightUnitsPounds
+		eq.Weight, eq.WeightUnits = &w, &units
+	}
+	eq.Temp, eq.TempUnits = nil, nil
+	if typ == equipmentReefer {
+		t := spec.MinTempFahrenheit
+		units := tempUnitsFahrenheit
+		eq.Temp, eq.TempUnits = &t, &units
+	}
+	return append([]Equipment{eq}, others...)
+}
+
+// fromTurvoSpecifications rebuilds Specifications from equipment, services
+// and flex attributes.
+func (m *Mapper) fromTurvoSpecifications(s Shipment) *domain.Specifications {
+	spec := &domain.Specifications{}
+	if i := slices.IndexFunc(s.Equipment, func(e Equipment) bool { return isEquipmentType(e.Type.Key) }); i >= 0 {
+		eq := s.Equipment[i]
+		if eq.Weight != nil {
+			spec.TotalWeight = *eq.Weight
+		}
+		if eq.Type.Key == equipmentReefer.Key {
+			spec.Reefer = true
+			if eq.Temp != nil {
+				spec.MinTempFahrenheit, spec.MaxTempFahrenheit = *eq.Temp, *eq.Temp
+			}
+		}
+	}
+	for _, kv := range s.Services {
+		for _, sv := range specServices {
+			if kv.Key == sv.code.Key {
+				*sv.flag(spec) = true
+			}
+		}
+	}
+	for _, fa := range s.FlexAttributes {
+		for _, sa := range specAttributes {
+			if fa.Name == sa.name {
+				sa.set(spec, fa.Value)
+			}
+		}
+		if !spec.Reefer {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fa.Value, 64); err == nil {
+			switch fa.Name {
+			case attrMinTemp:
+				spec.MinTempFahrenheit = v
+			case attrMaxTemp:
+				spec.MaxTempFahrenheit = v
+			}
+		}
+	}
+	return spec
+}
+
+func isEquipmentType(key string) bool {
+	return slices.ContainsFunc(equipmentTypes, func(t KeyValuePair) bool { return t.Key == key })
+}
+
+func isSpecService(key string) bool {
+	for _, sv := range specServices {
+		if sv.code.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpecAttribute(name string) bool {
+	for _, sa := range specAttributes {
+		if sa.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func itoaNonZero(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func ftoaNonZero(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
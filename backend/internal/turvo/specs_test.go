@@ -0,0 +1,124 @@
+package turvo_test
+
+import (
+	"testing"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/domain"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+func specLoad(spec domain.Specifications) *domain.Load {
+	return &domain.Load{
+		ExternalTMSLoadID: "SPEC-1",
+		Pickup:            domain.Stop{City: "Chicago", State: "IL"},
+		Consignee:         domain.Stop{City: "Dallas", State: "TX"},
+		Specifications:    &spec,
+	}
+}
+
+func serviceKeys(s turvo.Shipment) []string {
+	var keys []string
+	for _, kv := range s.Services {
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestSpecificationsRoundTrip(t *testing.T) {
+	s, back := roundTrip(t, specLoad(domain.Specifications{
+		Reefer: true, MinTempFahrenheit: -10, MaxTempFahrenheit: 5,
+		LiftgateDelivery: true, Hazmat: true, InPalletCount: 12, TotalWeight: 38000, PoNums: "PO-1;PO-2",
+	}))
+	if len(s.Equipment) != 1 {
+		t.Fatalf("equipment = %+v", s.Equipment)
+	}
+	eq := s.Equipment[0]
+	if eq.Type.Value != "Reefer" || eq.Temp == nil || *eq.Temp != -10 || eq.TempUnits == nil || eq.Weight == nil || *eq.Weight != 38000 {
+		t.Errorf("equipment = %+v", eq)
+	}
+	if got := serviceKeys(s); len(got) != 2 || got[0] != "21001" || got[1] != "21006" {
+		t.Errorf("services = %v", got)
+	}
+	spec := back.Specifications
+	if !spec.Reefer || spec.MinTempFahrenheit != -10 || spec.MaxTempFahrenheit != 5 || !spec.LiftgateDelivery || !spec.Hazmat ||
+		spec.LiftgatePickup || spec.InPalletCount != 12 || spec.TotalWeight != 38000 || spec.PoNums != "PO-1;PO-2" {
+		t.Errorf("specifications = %+v", spec)
+	}
+}
+
+func TestSpecificationsZeroDegreeReefer(t *testing.T) {
+	// frozen at 0°F is still a reefer load with a set point
+	s, back := roundTrip(t, specLoad(domain.Specifications{Reefer: true}))
+	eq := s.Equipment[0]
+	if eq.Type.Value != "Reefer" || eq.Temp == nil || *eq.Temp != 0 {
+		t.Errorf("equipment = %+v", eq)
+	}
+	if spec := back.Specifications; !spec.Reefer || spec.MinTempFahrenheit != 0 || spec.MaxTempFahrenheit != 0 {
+		t.Errorf("specifications = %+v", spec)
+	}
+
+	// without the flag, a temperature still implies a reefer, and no
+	// temperature means a van with no set point
+	if s, _ := roundTrip(t, specLoad(domain.Specifications{MaxTempFahrenheit: 34})); s.Equipment[0].Type.Value != "Reefer" {
+		t.Errorf("temperature load equipment = %+v", s.Equipment[0])
+	}
+	s, back = roundTrip(t, specLoad(domain.Specifications{}))
+	if s.Equipment[0].Type.Value != "Van" || s.Equipment[0].Temp != nil || back.Specifications.Reefer {
+		t.Errorf("van equipment = %+v, specifications %+v", s.Equipment[0], back.Specifications)
+	}
+}
+
+func TestSpecificationsUpdateMergesByKey(t *testing.T) {
+	m := turvo.NewMapper(&config.Config{TurvoDefaultCustomerID: 500})
+	length := 53.0
+	desc := "food grade"
+	temp := 40.0
+	existing := turvo.Shipment{
+		ID: 9,
+		Equipment: []turvo.Equipment{
+			{Type: turvo.KeyValuePair{Key: "1200", Value: "Van"}, ShipmentLength: &length, Description: &desc, Temp: &temp},
+			{Type: turvo.KeyValuePair{Key: "1299", Value: "Pallet jack"}},
+		},
+		Services: []turvo.KeyValuePair{
+			{Key: "21000", Value: "Liftgate pickup"},
+			{Key: "29999", Value: "Driver assist"},
+		},
+		FlexAttributes: []turvo.FlexAttribute{
+			{Name: "inPalletCount", Value: "4"},
+			{Name: "dock", Value: "7"},
+		},
+	}
+	load := specLoad(domain.Specifications{Reefer: true, MinTempFahrenheit: 0, MaxTempFahrenheit: 10, Hazmat: true})
+	s, err := m.ApplyLoadUpdate(existing, load, map[string]bool{"specifications": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Equipment) != 2 {
+		t.Fatalf("equipment = %+v", s.Equipment)
+	}
+	eq := s.Equipment[0]
+	if eq.Type.Value != "Reefer" || eq.Temp == nil || *eq.Temp != 0 || eq.ShipmentLength == nil || *eq.ShipmentLength != 53 ||
+		eq.Description == nil || *eq.Description != "food grade" {
+		t.Errorf("reefer equipment = %+v", eq)
+	}
+	if s.Equipment[1].Type.Key != "1299" {
+		t.Errorf("other equipment = %+v", s.Equipment[1])
+	}
+	// liftgate pickup is Drumkit's and now off; driver assist came from Turvo
+	if got := serviceKeys(s); len(got) != 2 || got[0] != "21006" || got[1] != "29999" {
+		t.Errorf("services = %v", got)
+	}
+	attrs := map[string]string{}
+	for _, fa := range s.FlexAttributes {
+		attrs[fa.Name] = fa.Value
+	}
+	if _, ok := attrs["inPalletCount"]; ok || attrs["dock"] != "7" || attrs["minTempFahrenheit"] != "0" || attrs["maxTempFahrenheit"] != "10" {
+		t.Errorf("flex attributes = %v", attrs)
+	}
+	// the existing shipment is not modified
+	if len(existing.Services) != 2 || existing.Equipment[0].Type.Value != "Van" {
+		t.Errorf("existing changed: %+v", existing)
+	}
+}
@@ -0,0 +1,490 @@
+// Package turvotest provides an in-process fake of the Turvo public API for
+// tests and local development. It keeps shipments, customers and locations
+// in memory, issues OAuth tokens, and lets callers inject faults.
+package turvotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maceo-kwik/drumkit/backend/internal/config"
+	"github.com/maceo-kwik/drumkit/backend/internal/turvo"
+)
+
+// Credentials accepted by the fake's OAuth grants and, for APIKey, by data
+// requests in api_key mode.
+const (
+	ClientID     = "fake-client"
+	ClientSecret = "fake-secret"
+	Username     = "fake-user"
+	Password     = "fake-pass"
+	APIKey       = "fake-api-key"
+)
+
+// Fault makes the next Times matching requests fail. A zero Status closes the
+// connection without a response to simulate a network error.
+type Fault struct {
+	Status int
+	// Body defaults to a Turvo error envelope for Status.
+	Body       string
+	RetryAfter string
+	// Times defaults to 1.
+	Times int
+	// Applied handles the request before failing, as when Turvo applies a
+	// create but the response is lost.
+	Applied bool
+}
+
+type fault struct {
+	method, path string
+	Fault
+}
+
+// Server is a fake Turvo API served over HTTP.
+type Server struct {
+	*httptest.Server
+
+	// BareLists makes shipments/list return a bare JSON array instead of the
+	// wrapped {"Status","details"} form.
+	BareLists bool
+	// TokenTTL is the expires_in reported for access tokens, in seconds.
+	TokenTTL int
+
+	mu        sync.Mutex
+	shipments map[int]turvo.Shipment
+	customers []turvo.MinimalCustomer
+	locations map[int]turvo.LocationRecord
+	nextID    int
+	tokens    map[string]bool
+	refresh   map[string]bool
+	tokenSeq  int
+	faults    []*fault
+	calls     map[string]int
+}
+
+// NewServer starts a fake Turvo server. Call Close when done.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a fake that is not yet listening, so callers can
+// replace its Listener (for example to bind a fixed port) before Start.
+func NewUnstartedServer() *Server {
+	s := &Server{
+		TokenTTL:  3600,
+		shipments: make(map[int]turvo.Shipment),
+		locations: make(map[int]turvo.LocationRecord),
+		nextID:    1000,
+		tokens:    make(map[string]bool),
+		refresh:   make(map[string]bool),
+		calls:     make(map[string]int),
+	}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Config returns a configuration that points a turvo.Client at the fake.
+func (s *Server) Config() *config.Config {
+	return &config.Config{
+		AppEnv:             "test",
+		TurvoBaseURL:       s.URL,
+		TurvoAPIPrefix:     "/v1",
+		TurvoAuthMode:      config.AuthOAuthPassword,
+		TurvoClientID:      ClientID,
+		TurvoClientSecret:  ClientSecret,
+		TurvoOAuthUsername: Username,
+		TurvoOAuthPassword: Password,
+		TurvoOAuthScope:    "read+trust+write",
+		TurvoOAuthUserType: "business",
+	}
+}
+
+// AddShipment stores s, assigning an id when it has none, and returns the id.
+func (s *Server) AddShipment(sh turvo.Shipment) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store(sh)
+}
+
+// Shipment returns the stored shipment with id.
+func (s *Server) Shipment(id int) (turvo.Shipment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh, ok := s.shipments[id]
+	return sh, ok
+}
+
+// Shipments returns all stored shipments ordered by id.
+func (s *Server) Shipments() []turvo.Shipment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedShipments()
+}
+
+// AddCustomer stores a customer returned by customers/list.
+func (s *Server) AddCustomer(c turvo.MinimalCustomer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.customers = append(s.customers, c)
+}
+
+// InjectFault fails requests whose method matches and whose path, without
+// the /v1/ prefix, starts with path (for example "oauth/token" or "shipments/").
+// An empty method matches any method. Faults are consumed in order.
+func (s *Server) InjectFault(method, path string, f Fault) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{method: method, path: strings.TrimLeft(path, "/"), Fault: f})
+}
+
+// ExpireTokens revokes every access token; refresh tokens stay valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// Calls returns how many requests reached method and path, where path has no
+// /v1/ prefix and no query (for example "shipments/list"). Faulted requests
+// are counted.
+func (s *Server) Calls(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method+" "+strings.TrimLeft(path, "/")]
+}
+
+// GrantCalls returns how many token requests used grant type g.
+func (s *Server) GrantCalls(g string) int {
+	return s.Calls(http.MethodPost, "oauth/token#"+g)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(strings.TrimLeft(r.URL.Path, "/"), "v1/")
+	s.mu.Lock()
+	s.calls[r.Method+" "+path]++
+	if path == "oauth/token" {
+		r.ParseForm()
+		s.calls[r.Method+" "+path+"#"+r.PostForm.Get("grant_type")]++
+	}
+	f := s.takeFault(r.Method, path)
+	s.mu.Unlock()
+
+	if f != nil {
+		if f.Applied {
+			s.handle(httptest.NewRecorder(), r, path)
+		}
+		s.writeFault(w, f)
+		return
+	}
+	s.handle(w, r, path)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request, path string) {
+	if path == "oauth/token" && r.Method == http.MethodPost {
+		s.token(w, r)
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
+		return
+	}
+	switch {
+	case path == "shipments/list" && r.Method == http.MethodGet:
+		s.listShipments(w, r)
+	case path == "shipments" && r.Method == http.MethodPost:
+		s.createShipment(w, r)
+	case strings.HasPrefix(path, "shipments/") && r.Method == http.MethodGet:
+		s.getShipment(w, strings.TrimPrefix(path, "shipments/"))
+	case strings.HasPrefix(path, "shipments/") && r.Method == http.MethodPut:
+		s.updateShipment(w, r, strings.TrimPrefix(path, "shipments/"))
+	case path == "customers/list" && r.Method == http.MethodGet:
+		s.listCustomers(w)
+	case path == "locations/list" && r.Method == http.MethodGet:
+		s.listLocations(w, r)
+	case path == "locations" && r.Method == http.MethodPost:
+		s.createLocation(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "no route for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *Server) takeFault(method, path string) *Fault {
+	for i, f := range s.faults {
+		if (f.method == "" || f.method == method) && strings.HasPrefix(path, f.path) {
+			f.Times--
+			if f.Times <= 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+			out := f.Fault
+			return &out
+		}
+	}
+	return nil
+}
+
+func (s *Server) writeFault(w http.ResponseWriter, f *Fault) {
+	if f.Status == 0 {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		f.Status = http.StatusBadGateway
+	}
+	if f.RetryAfter != "" {
+		w.Header().Set("Retry-After", f.RetryAfter)
+	}
+	if f.Body == "" {
+		writeError(w, f.Status, "FAULT", http.StatusText(f.Status))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.Status)
+	w.Write([]byte(f.Body))
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != ClientID || q.Get("client_secret") != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		if r.PostForm.Get("username") != Username || r.PostForm.Get("password") != Password {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	case "client_credentials":
+	case "refresh_token":
+		rt := r.PostForm.Get("refresh_token")
+		if !s.refresh[rt] {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(s.refresh, rt)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	s.tokenSeq++
+	access := fmt.Sprintf("access-%d", s.tokenSeq)
+	refresh := fmt.Sprintf("refresh-%d", s.tokenSeq)
+	s.tokens[access] = true
+	s.refresh[refresh] = true
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  access,
+		"token_type":    "bearer",
+		"expires_in":    s.TokenTTL,
+		"refresh_token": refresh,
+		"scope":         r.PostForm.Get("scope"),
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if r.Header.Get("Authorization") == "" && r.Header.Get("x-api-key") == APIKey {
+		return true
+	}
+	tok := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[tok]
+}
+
+func (s *Server) listShipments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, _ := strconv.Atoi(q.Get("start"))
+	pageSize, _ := strconv.Atoi(q.Get("pageSize"))
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	// lastObjectKey continues after that shipment id
+	after, _ := strconv.Atoi(q.Get("lastObjectKey"))
+	s.mu.Lock()
+	var matched []turvo.Shipment
+	for _, sh := range s.sortedShipments() {
+		if v := q.Get("customId[eq]"); v != "" && sh.CustomID != v {
+			continue
+		}
+		if after > 0 && sh.ID <= after {
+			continue
+		}
+		matched = append(matched, sh)
+	}
+	bare := s.BareLists
+	s.mu.Unlock()
+
+	page := []turvo.Shipment{}
+	if start < len(matched) {
+		page = matched[start:min(start+pageSize, len(matched))]
+	}
+	if bare {
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+	more := start+len(page) < len(matched)
+	var lastKey any
+	if len(page) > 0 {
+		lastKey = strconv.Itoa(page[len(page)-1].ID)
+	}
+	writeWrapped(w, http.StatusOK, map[string]any{
+		"shipments": page,
+		"pagination": map[string]any{
+			"start":              start,
+			"pageSize":           pageSize,
+			"totalRecordsInPage": len(page),
+			"moreAvailable":      more,
+			"lastObjectKey":      lastKey,
+		},
+	})
+}
+
+func (s *Server) getShipment(w http.ResponseWriter, id string) {
+	n, _ := strconv.Atoi(id)
+	sh, ok := s.Shipment(n)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "shipment "+id+" not found")
+		return
+	}
+	writeWrapped(w, http.StatusOK, sh)
+}
+
+func (s *Server) createShipment(w http.ResponseWriter, r *http.Request) {
+	var sh turvo.Shipment
+	if err := json.NewDecoder(r.Body).Decode(&sh); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_JSON", err.Error())
+		return
+	}
+	if fields := validateShipment(sh); len(fields) > 0 {
+		writeValidation(w, fields)
+		return
+	}
+	sh.ID = 0
+	id := s.AddShipment(sh)
+	sh, _ = s.Shipment(id)
+	writeWrapped(w, http.StatusOK, sh)
+}
+
+func (s *Server) updateShipment(w http.ResponseWriter, r *http.Request, id string) {
+	n, _ := strconv.Atoi(id)
+	if _, ok := s.Shipment(n); !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "shipment "+id+" not found")
+		return
+	}
+	var sh turvo.Shipment
+	if err := json.NewDecoder(r.Body).Decode(&sh); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_JSON", err.Error())
+		return
+	}
+	sh.ID = n
+	s.AddShipment(sh)
+	writeWrapped(w, http.StatusOK, sh)
+}
+
+// validateShipment applies the few checks Turvo makes that tests rely on.
+func validateShipment(sh turvo.Shipment) []turvo.FieldError {
+	var fields []turvo.FieldError
+	if len(sh.CustomerOrder) == 0 || sh.CustomerOrder[0].Customer == nil || sh.CustomerOrder[0].Customer.ID <= 0 {
+		fields = append(fields, turvo.FieldError{Field: "customerOrder.customer.id", Message: "customer is required"})
+	}
+	if sh.StartDate.Date.IsZero() {
+		fields = append(fields, turvo.FieldError{Field: "startDate", Message: "start date is required"})
+	}
+	return fields
+}
+
+func (s *Server) listCustomers(w http.ResponseWriter) {
+	s.mu.Lock()
+	customers := append([]turvo.MinimalCustomer{}, s.customers...)
+	s.mu.Unlock()
+	writeWrapped(w, http.StatusOK, map[string]any{"customers": customers})
+}
+
+func (s *Server) listLocations(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name[eq]")
+	s.mu.Lock()
+	out := []turvo.LocationRecord{}
+	for _, loc := range s.locations {
+		if name == "" || loc.Name == name {
+			out = append(out, loc)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	writeWrapped(w, http.StatusOK, map[string]any{"locations": out})
+}
+
+func (s *Server) createLocation(w http.ResponseWriter, r *http.Request) {
+	var loc turvo.LocationRecord
+	if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_JSON", err.Error())
+		return
+	}
+	s.mu.Lock()
+	s.nextID++
+	loc.ID = s.nextID
+	s.locations[loc.ID] = loc
+	s.mu.Unlock()
+	writeWrapped(w, http.StatusOK, loc)
+}
+
+// store saves sh under its id, assigning one when zero. Callers hold s.mu.
+func (s *Server) store(sh turvo.Shipment) int {
+	if sh.ID == 0 {
+		s.nextID++
+		sh.ID = s.nextID
+	}
+	s.shipments[sh.ID] = sh
+	return sh.ID
+}
+
+func (s *Server) sortedShipments() []turvo.Shipment {
+	out := make([]turvo.Shipment, 0, len(s.shipments))
+	for _, sh := range s.shipments {
+		out = append(out, sh)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeWrapped(w http.ResponseWriter, status int, details any) {
+	writeJSON(w, status, map[string]any{"Status": "SUCCESS", "details": details})
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]any{
+		"Status":  "ERROR",
+		"details": map[string]any{"errorCode": code, "errorMessage": msg},
+	})
+}
+
+func writeValidation(w http.ResponseWriter, fields []turvo.FieldError) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"Status": "ERROR",
+		"details": map[string]any{
+			"errorCode":    "VALIDATION_FAILED",
+			"errorMessage": "shipment is invalid",
+			"errors":       fields,
+		},
+	})
+}
@@ -0,0 +1,157 @@
+package turvo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook event types sent by Turvo for shipment changes.
+const (
+	EventShipmentCreated       = "SHIPMENT_CREATED"
+	EventShipmentUpdated       = "SHIPMENT_UPDATED"
+	EventShipmentStatusChanged = "SHIPMENT_STATUS_CHANGED"
+)
+
+// Webhook headers carrying the signature and the signing timestamp.
+const (
+	WebhookSignatureHeader = "X-Turvo-Signature"
+	WebhookTimestampHeader = "X-Turvo-Timestamp"
+)
+
+// ErrInvalidSignature is returned when a webhook payload fails verification.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookEvent is the envelope shared by all Turvo webhook payloads. Data is
+// decoded into a typed event by ParseWebhookEvent.
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Event is implemented by all typed webhook events.
+type Event interface {
+	EventID() string
+	EventType() string
+}
+
+// ShipmentCreatedEvent is emitted when a shipment is created in Turvo.
+type ShipmentCreatedEvent struct {
+	ID        string
+	CreatedAt time.Time
+	Shipment  Shipment
+}
+
+// ShipmentUpdatedEvent is emitted when any shipment field changes in Turvo.
+type ShipmentUpdatedEvent struct {
+	ID        string
+	CreatedAt time.Time
+	Shipment  Shipment
+}
+
+// ShipmentStatusChangedEvent is emitted when a shipment moves to a new status.
+type ShipmentStatusChangedEvent struct {
+	ID             string
+	CreatedAt      time.Time
+	ShipmentID     int
+	CustomID       string
+	PreviousStatus Status
+	Status         Status
+}
+
+func (e ShipmentCreatedEvent) EventID() string         { return e.ID }
+func (e ShipmentCreatedEvent) EventType() string       { return EventShipmentCreated }
+func (e ShipmentUpdatedEvent) EventID() string         { return e.ID }
+func (e ShipmentUpdatedEvent) EventType() string       { return EventShipmentUpdated }
+func (e ShipmentStatusChangedEvent) EventID() string   { return e.ID }
+func (e ShipmentStatusChangedEvent) EventType() string { return EventShipmentStatusChanged }
+
+// ParseWebhookEvent decodes a verified webhook body into a typed event.
+func ParseWebhookEvent(body []byte) (Event, error) {
+	var env WebhookEvent
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("decode webhook: %w", err)
+	}
+	if env.ID == "" {
+		return nil, fmt.Errorf("webhook event has no id")
+	}
+	switch env.Type {
+	case EventShipmentCreated, EventShipmentUpdated:
+		var data struct {
+			Shipment Shipment `json:"shipment"`
+		}
+		if err := json.Unmarshal(env.Data, &data); err != nil {
+			return nil, fmt.Errorf("decode %s data: %w", env.Type, err)
+		}
+		if env.Type == EventShipmentCreated {
+			return ShipmentCreatedEvent{ID: env.ID, CreatedAt: env.CreatedAt, Shipment: data.Shipment}, nil
+		}
+		return ShipmentUpdatedEvent{ID: env.ID, CreatedAt: env.CreatedAt, Shipment: data.Shipment}, nil
+	case EventShipmentStatusChanged:
+		var data struct {
+			ShipmentID     int    `json:"shipmentId"`
+			CustomID       string `json:"customId"`
+			PreviousStatus Status `json:"previousStatus"`
+			Status         Status `json:"status"`
+		}
+		if err := json.Unmarshal(env.Data, &data); err != nil {
+			return nil, fmt.Errorf("decode %s data: %w", env.Type, err)
+		}
+		return ShipmentStatusChangedEvent{
+			ID:             env.ID,
+			CreatedAt:      env.CreatedAt,
+			ShipmentID:     data.ShipmentID,
+			CustomID:       data.CustomID,
+			PreviousStatus: data.PreviousStatus,
+			Status:         data.Status,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported webhook event type %q", env.Type)
+	}
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "timestamp.body" under secret.
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature header ("sha256=<hex>" or bare
+// hex) against the body and timestamp, and rejects timestamps further than
+// tolerance from now so captured payloads cannot be replayed later.
+func VerifyWebhookSignature(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	if secret == "" || signature == "" || timestamp == "" {
+		return ErrInvalidSignature
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	skew := now.Sub(time.Unix(secs, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if tolerance > 0 && skew > tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(SignWebhook(secret, timestamp, body))
+	if !hmac.Equal(got, want) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
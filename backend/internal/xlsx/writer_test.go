@@ -0,0 +1,70 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterProducesWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Loads & more")
+	if err != nil {
+		t.Fatal(err)
+	}
+	miles := 812.5
+	var none *float64
+	when := time.Date(2026, 3, 2, 12, 0, 0, 0, time.FixedZone("CST", -6*3600))
+	w.WriteRow([]any{"Name", "Miles", "Pickup"})
+	w.WriteRow([]any{"<A&B>", &miles, &when, none, 3})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="Loads &amp; more"`) {
+		t.Errorf("workbook = %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;A&amp;B&gt;</t></is></c>`,
+		`<c r="B2"><v>812.5</v></c>`,
+		// noon on 2026-03-02 in the pickup's own zone
+		`<c r="C2" s="1"><v>46083.5</v></c>`,
+		`<c r="E2"><v>3</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet lacks %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="D2"`) {
+		t.Errorf("nil cell was written: %s", sheet)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
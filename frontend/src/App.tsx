@@ -1,4 +1,4 @@
-import { useEffect, useMemo, useState } from 'react'
+import { useEffect, useMemo, useRef, useState } from 'react'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Input } from '@/components/ui/input'
@@ -11,8 +11,10 @@ import {
   getFilteredRowModel,
   useReactTable,
 } from '@tanstack/react-table'
-import { ArrowUpDown, ChevronDown, ChevronUp, Plus } from "lucide-react"
+import { ArrowUpDown, ChevronDown, ChevronUp, Download, Plus } from "lucide-react"
 import CreateLoadModal from '@/components/CreateLoadModal'
+import { apiFetch, downloadFile, streamEvents } from '@/lib/api'
+import { apiErrorMessage } from '@/lib/utils'
 
 import './App.css'
 
@@ -39,9 +41,10 @@ function App() {
   const [loads, setLoads] = useState<Load[]>([])
   const [sorting, setSorting] = useState<SortingState>([])
   const [error, setError] = useState<string | null>(null)
-  const [start, setStart] = useState(0)
+  // cursors[i] fetches page i; the first page has none
+  const [cursors, setCursors] = useState<string[]>([''])
+  const [nextCursor, setNextCursor] = useState('')
   const [pageSize] = useState(24)
-  const [moreAvailable, setMoreAvailable] = useState(false)
   const [showCreate, setShowCreate] = useState(false)
   // Server-side filters
   const [filterStatus] = useState('') // Turvo status code (2101/2102) or empty
@@ -64,9 +67,8 @@ function App() {
     return `${field}:${dir}`
   }
 
-  function buildQuery(nextStart = start) {
+  function buildQuery() {
     const params = new URLSearchParams()
-    params.set('start', String(nextStart))
     params.set('pageSize', String(pageSize))
     if (filterStatus) params.set('status[eq]', filterStatus)
     if (filterExternalId) params.set('customId[eq]', filterExternalId)
@@ -77,25 +79,44 @@ function App() {
     return params.toString()
   }
 
-  const API_BASE = import.meta.env.VITE_API_BASE?.replace(/\/$/, '') || ''
-
-  async function fetchLoads(nextStart = 0) {
+  // fetchLoads loads the page at the end of pageCursors. Later pages follow
+  // the server's nextCursor, which keeps the first page's filters and does
+  // not shift when loads are created meanwhile.
+  async function fetchLoads(pageCursors: string[] = ['']) {
     try {
       setError(null)
-      const qs = buildQuery(nextStart)
-      const r = await fetch(`${API_BASE}/api/loads?${qs}`)
+      const cursor = pageCursors[pageCursors.length - 1]
+      const qs = cursor
+        ? new URLSearchParams({ cursor, pageSize: String(pageSize) }).toString()
+        : buildQuery()
+      const r = await apiFetch(`/api/loads?${qs}`)
       if (!r.ok) throw new Error('Failed to fetch loads')
       const data = await r.json()
       const items: Load[] = Array.isArray(data) ? data : (data?.items ?? [])
       setLoads(items)
-      const more = !!(data?.pagination?.moreAvailable)
-      setMoreAvailable(more)
-      setStart(nextStart)
+      setNextCursor(data?.pagination?.nextCursor ?? '')
+      setCursors(pageCursors)
     } catch (e: any) {
       setError(e?.message ?? 'Failed to fetch loads')
     }
   }
 
+  // exportLoads downloads every load matching the current filters; the
+  // server pages through Turvo and streams the file. It is fetched rather
+  // than navigated to so the request carries credentials.
+  async function exportLoads(format: 'csv' | 'xlsx') {
+    const params = new URLSearchParams(buildQuery())
+    params.delete('pageSize')
+    params.set('format', format)
+    try {
+      setError(null)
+      const res = await downloadFile(`/api/loads/export?${params.toString()}`, `loads.${format}`)
+      if (!res.ok) throw new Error(await apiErrorMessage(res, 'Failed to export loads'))
+    } catch (e: any) {
+      setError(e?.message ?? 'Failed to export loads')
+    }
+  }
+
   const columns: ColumnDef<Load>[] = useMemo(() => [
     {
       header: ({ column }) => {
@@ -516,16 +537,35 @@ function App() {
   })
 
   useEffect(() => {
-    fetchLoads(0)
+    fetchLoads()
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [])
 
   // Refetch when sorting changes so server applies ordering
   useEffect(() => {
-    fetchLoads(0)
+    fetchLoads()
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [JSON.stringify(sorting)])
 
+  // Refetch the current page when the backend reports a load created or
+  // changed in Turvo; a burst of events causes one refetch.
+  const refreshPage = useRef(() => {})
+  refreshPage.current = () => { fetchLoads(cursors) }
+  useEffect(() => {
+    const stream = new AbortController()
+    let timer: ReturnType<typeof setTimeout> | undefined
+    streamEvents('/api/loads/events', (event) => {
+      if (event !== 'load') return
+      clearTimeout(timer)
+      timer = setTimeout(() => refreshPage.current(), 1000)
+    }, stream.signal)
+    return () => {
+      clearTimeout(timer)
+      stream.abort()
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [])
+
   function StatusBadge({ value }: { value: string }) {
     const v = (value || '').toUpperCase()
     const cls = v === 'COVERED' ? 'bg-green-100 text-green-800' : v === 'NEW' || v === 'TENDERED' ? 'bg-yellow-100 text-yellow-800' : 'bg-gray-100 text-gray-800'
@@ -537,16 +577,24 @@ function App() {
       <CreateLoadModal
         open={showCreate}
         onClose={() => setShowCreate(false)}
-        onSuccess={async () => { await fetchLoads(0) }}
+        onSuccess={async () => { await fetchLoads() }}
       />
 
       <Card>
         <CardHeader>
           <div className="flex items-center justify-between">
             <CardTitle>Loads</CardTitle>
-            <Button size="sm" onClick={() => setShowCreate(true)} aria-label="Create Load">
-              <Plus className="h-4 w-4" />
-            </Button>
+            <div className="flex items-center gap-2">
+              <Button variant="outline" size="sm" onClick={() => exportLoads('csv')} aria-label="Export CSV">
+                <Download className="h-4 w-4 mr-1" />CSV
+              </Button>
+              <Button variant="outline" size="sm" onClick={() => exportLoads('xlsx')} aria-label="Export Excel">
+                <Download className="h-4 w-4 mr-1" />Excel
+              </Button>
+              <Button size="sm" onClick={() => setShowCreate(true)} aria-label="Create Load">
+                <Plus className="h-4 w-4" />
+              </Button>
+            </div>
           </div>
         </CardHeader>
         <CardContent className="space-y-3">
@@ -590,9 +638,9 @@ function App() {
             </Table>
           </div>
           <div className="flex items-center justify-end gap-3">
-            <div className="text-sm">Start {start}</div>
-            <Button variant="outline" size="sm" onClick={() => fetchLoads(Math.max(0, start - pageSize))} disabled={start === 0}>Prev</Button>
-            <Button variant="outline" size="sm" onClick={() => fetchLoads(start + pageSize)} disabled={!moreAvailable}>Next</Button>
+            <div className="text-sm">Page {cursors.length}</div>
+            <Button variant="outline" size="sm" onClick={() => fetchLoads(cursors.slice(0, -1))} disabled={cursors.length === 1}>Prev</Button>
+            <Button variant="outline" size="sm" onClick={() => fetchLoads([...cursors, nextCursor])} disabled={!nextCursor}>Next</Button>
           </div>
         </CardContent>
       </Card>
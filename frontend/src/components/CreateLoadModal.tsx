@@ -5,8 +5,8 @@ import { Label } from '@/components/ui/label'
 import { useForm, FormProvider } from 'react-hook-form'
 import { z } from 'zod'
 import { zodResolver } from '@hookform/resolvers/zod'
-
-const API_BASE = import.meta.env.VITE_API_BASE?.replace(/\/$/, '') || ''
+import { apiErrorMessage, similarLoadsPrompt, type ApiErrorBody } from '@/lib/utils'
+import { apiFetch } from '@/lib/api'
 
 type CreateLoadModalProps = {
   open: boolean
@@ -84,6 +84,7 @@ const rateDataSchema = z.object({
 })
 
 const specificationsSchema = z.object({
+  reefer: z.boolean().optional(),
   minTempFahrenheit: optStr,
   maxTempFahrenheit: optStr,
   liftgatePickup: z.boolean().optional(),
@@ -184,7 +185,7 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
     ;(async () => {
       try {
         setLoadingCustomers(true)
-        const r = await fetch(`${API_BASE}/api/customers?pageSize=50`)
+        const r = await apiFetch('/api/customers?pageSize=50')
         if (!r.ok) throw new Error('Failed customers')
         const data = await r.json()
         const items = Array.isArray(data) ? data : (data?.items ?? [])
@@ -260,6 +261,14 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
     setStep((s) => Math.max(s - 1, 0))
   }
 
+  // toLocal passes a datetime-local value through unchanged. Stop times are
+  // the wall-clock time at the facility, and the server places them in the
+  // stop's zone, so the browser's own zone must not be applied.
+  function toLocal(value?: string) {
+    const v = (value || '').trim()
+    return v || undefined
+  }
+
   function toISO(value?: string) {
     const v = (value || '').trim()
     if (!v) return undefined
@@ -282,8 +291,8 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
         externalTMSLoadID: values.externalTMSLoadID,
         status: values.status,
         customer: values.customer,
-        pickup: { ...values.pickup, readyTime: toISO(values.scheduling.readyTime), apptTime: toISO(values.scheduling.pickupApptTime), apptNote: values.scheduling.pickupApptNote, timezone: values.scheduling.timezone },
-        consignee: { ...values.consignee, mustDeliver: toISO(values.scheduling.mustDeliver), apptTime: toISO(values.scheduling.consigneeApptTime), apptNote: values.scheduling.consigneeApptNote, timezone: values.scheduling.timezone },
+        pickup: { ...values.pickup, readyTime: toLocal(values.scheduling.readyTime), apptTime: toLocal(values.scheduling.pickupApptTime), apptNote: values.scheduling.pickupApptNote, timezone: values.scheduling.timezone || values.pickup.timezone },
+        consignee: { ...values.consignee, mustDeliver: toLocal(values.scheduling.mustDeliver), apptTime: toLocal(values.scheduling.consigneeApptTime), apptNote: values.scheduling.consigneeApptNote, timezone: values.scheduling.timezone || values.consignee.timezone },
       }
       console.log('[CreateLoadModal] submit -> payload:', payload)
       if (values.freightLoadID?.trim()) payload.freightLoadID = values.freightLoadID.trim()
@@ -319,6 +328,8 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
       }
       if (values.specsEnabled && values.specifications) {
         payload.specifications = {
+          // a temperature makes the load a reefer, including 0°F
+          reefer: !!values.specifications.reefer || !!values.specifications.minTempFahrenheit || !!values.specifications.maxTempFahrenheit,
           minTempFahrenheit: toNum(values.specifications.minTempFahrenheit),
           maxTempFahrenheit: toNum(values.specifications.maxTempFahrenheit),
           liftgatePickup: !!values.specifications.liftgatePickup,
@@ -346,13 +357,20 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
       }
 
       console.log('[CreateLoadModal] submit -> POST /api/loads')
-      const res = await fetch(`${API_BASE}/api/loads`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(payload) })
+      const post = (query = '') => apiFetch(`/api/loads${query}`, { method: 'POST', headers: { 'Content-Type': 'application/json' }, body: JSON.stringify(payload) })
+      let res = await post()
       console.log('[CreateLoadModal] submit -> response status:', res.status)
+      if (res.status === 409) {
+        const body = (await res.clone().json().catch(() => null)) as ApiErrorBody | null
+        if (body?.error?.code === 'possible_duplicate' && window.confirm(similarLoadsPrompt(body.error.similar ?? []))) {
+          console.log('[CreateLoadModal] submit -> confirmed possible duplicate, resending')
+          res = await post('?allowSimilar=true')
+        }
+      }
       if (!res.ok) {
-        let bodyText = ''
-        try { bodyText = await res.text() } catch {}
-        console.error('[CreateLoadModal] submit -> response not ok:', res.status, bodyText)
-        throw new Error(bodyText || 'Failed to create load')
+        const message = await apiErrorMessage(res, 'Failed to create load')
+        console.error('[CreateLoadModal] submit -> response not ok:', res.status, message)
+        throw new Error(message)
       }
       methods.reset()
       console.log('[CreateLoadModal] submit -> success, reset and invoking onSuccess')
@@ -641,6 +659,7 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
                   <details>
                     <summary className="cursor-pointer text-sm text-gray-700">Specifications details</summary>
                     <div className="mt-2 grid gap-3 sm:grid-cols-2">
+                      <label className="flex items-center gap-2 text-sm sm:col-span-2"><input type="checkbox" {...methods.register('specifications.reefer')} />Reefer</label>
                       <Field name="specifications.minTempFahrenheit" label="Min Temp (F)" type="number" />
                       <Field name="specifications.maxTempFahrenheit" label="Max Temp (F)" type="number" />
                       <label className="flex items-center gap-2 text-sm"><input type="checkbox" {...methods.register('specifications.liftgatePickup')} />Liftgate Pickup</label>
@@ -672,7 +691,7 @@ export default function CreateLoadModal({ open, onClose, onSuccess }: CreateLoad
             )}
 
             <div className="mt-2 flex items-center justify-between">
-              <div className="text-sm text-red-600">{error}</div>
+              <div className="text-sm text-red-600 whitespace-pre-line">{error}</div>
               <div className="flex gap-2">
                 {step > 0 && <Button type="button" variant="outline" onClick={prevStep}>Back</Button>}
                 {step < 5 && <Button type="button" onClick={nextStep}>Next</Button>}
@@ -0,0 +1,98 @@
+const API_BASE = import.meta.env.VITE_API_BASE?.replace(/\/$/, '') || ''
+
+// The credential entered at the sign-in prompt is kept for the browser
+// session. VITE_API_KEY is a build-time fallback for internal deployments.
+const CREDENTIAL_KEY = 'drumkit.credential'
+
+function credential(): string {
+  return sessionStorage.getItem(CREDENTIAL_KEY) || import.meta.env.VITE_API_KEY || ''
+}
+
+// authHeaders returns the headers that authenticate an API request: a JWT
+// is sent as a bearer token, anything else as an API key.
+export function authHeaders(): Record<string, string> {
+  const c = credential()
+  if (!c) return {}
+  return c.split('.').length === 3 ? { Authorization: `Bearer ${c}` } : { 'X-API-Key': c }
+}
+
+// signIn asks for an access token or API key and keeps it for the session.
+// It returns false when the prompt is cancelled.
+export function signIn(): boolean {
+  const c = window.prompt('Sign in to Drumkit: paste your access token or API key')?.trim()
+  if (!c) return false
+  sessionStorage.setItem(CREDENTIAL_KEY, c)
+  return true
+}
+
+// apiFetch requests path from the API with the session's credentials. A 401
+// asks the user to sign in and retries once; when another request signed in
+// meanwhile, it just retries.
+export async function apiFetch(path: string, init: RequestInit = {}): Promise<Response> {
+  const send = () => fetch(`${API_BASE}${path}`, { ...init, headers: { ...(init.headers as Record<string, string>), ...authHeaders() } })
+  const used = credential()
+  const res = await send()
+  if (res.status !== 401) return res
+  if (credential() === used) {
+    sessionStorage.removeItem(CREDENTIAL_KEY)
+    if (!signIn()) return res
+  }
+  return send()
+}
+
+// downloadFile fetches path with credentials and saves the response under
+// the name from its Content-Disposition header.
+export async function downloadFile(path: string, fallbackName: string): Promise<Response> {
+  const res = await apiFetch(path)
+  if (!res.ok) return res
+  const name = /filename="?([^";]+)"?/.exec(res.headers.get('Content-Disposition') ?? '')?.[1] ?? fallbackName
+  const url = URL.createObjectURL(await res.blob())
+  const a = document.createElement('a')
+  a.href = url
+  a.download = name
+  document.body.appendChild(a)
+  a.click()
+  a.remove()
+  URL.revokeObjectURL(url)
+  return res
+}
+
+// streamEvents reads the server-sent events at path with credentials,
+// which EventSource cannot send, and calls onEvent with each event's name
+// and data. It reconnects after retryMs until signal is aborted.
+export async function streamEvents(path: string, onEvent: (event: string, data: string) => void, signal: AbortSignal, retryMs = 5000) {
+  while (!signal.aborted) {
+    try {
+      const res = await fetch(`${API_BASE}${path}`, { headers: { Accept: 'text/event-stream', ...authHeaders() }, signal })
+      if (res.ok && res.body) {
+        const reader = res.body.pipeThrough(new TextDecoderStream()).getReader()
+        let buf = ''
+        let event = 'message'
+        let data: string[] = []
+        for (;;) {
+          const { value, done } = await reader.read()
+          if (done) break
+          buf += value
+          let nl: number
+          while ((nl = buf.indexOf('\n')) >= 0) {
+            const line = buf.slice(0, nl).replace(/\r$/, '')
+            buf = buf.slice(nl + 1)
+            if (line === '') {
+              if (data.length > 0) onEvent(event, data.join('\n'))
+              event = 'message'
+              data = []
+            } else if (line.startsWith('event:')) {
+              event = line.slice(6).trim()
+            } else if (line.startsWith('data:')) {
+              data.push(line.slice(5).replace(/^ /, ''))
+            }
+          }
+        }
+      }
+    } catch {
+      // aborted or disconnected; reconnect below unless aborted
+    }
+    if (signal.aborted) return
+    await new Promise((resolve) => setTimeout(resolve, retryMs))
+  }
+}
@@ -4,3 +4,50 @@ import { twMerge } from "tailwind-merge"
 export function cn(...inputs: ClassValue[]) {
   return twMerge(clsx(inputs))
 }
+
+type LoadRef = {
+  id: number
+  externalTMSLoadID?: string
+  status?: string
+  pickupDate?: string
+  link: string
+}
+
+export type ApiErrorBody = {
+  error?: {
+    code?: string
+    message?: string
+    fields?: { field: string; message: string }[]
+    requestId?: string
+    existing?: LoadRef
+    similar?: LoadRef[]
+  }
+}
+
+// similarLoadsPrompt builds the confirmation shown when the backend reports
+// possible duplicates (409 possible_duplicate).
+export function similarLoadsPrompt(similar: LoadRef[]): string {
+  const lines = similar.map((l) => {
+    const date = l.pickupDate ? new Date(l.pickupDate).toLocaleDateString() : "no date"
+    return `- ${l.externalTMSLoadID || l.id} (${l.status || "unknown status"}, pickup ${date})`
+  })
+  return ["Similar loads already exist for this customer, lane and pickup date:", ...lines, "", "Create this load anyway?"].join("\n")
+}
+
+// apiErrorMessage reads the backend's JSON error envelope and returns a
+// readable message with one line per field error.
+export async function apiErrorMessage(res: Response, fallback: string): Promise<string> {
+  let text = ""
+  try { text = await res.text() } catch {}
+  try {
+    const body = JSON.parse(text) as ApiErrorBody
+    if (body.error) {
+      const lines = [body.error.message || fallback]
+      for (const f of body.error.fields ?? []) {
+        lines.push(f.field ? `${f.field}: ${f.message}` : f.message)
+      }
+      return lines.join("\n")
+    }
+  } catch {}
+  return text || fallback
+}